@@ -23,6 +23,7 @@ import (
 	"github.com/kubermatic-labs/registryman/pkg/config"
 	"github.com/kubermatic-labs/registryman/pkg/operator"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"k8s.io/client-go/rest"
 )
 
@@ -70,4 +71,13 @@ func init() {
 	options = &cliOptions{}
 	applyCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "if specified, no operation will be performed")
 	applyCmd.PersistentFlags().BoolVar(&options.forceDelete, "force-delete", false, "if specified, projects will be deleted, even with repositories")
+	applyCmd.PersistentFlags().StringSliceVar(&options.pausedRegistries, "pause-registry", []string{}, "name of a registry that is inspected but not modified; can be repeated")
+	applyCmd.PersistentFlags().StringSliceVar(&options.pausedProjects, "pause-project", []string{}, "name of a project that is inspected but not modified; can be repeated")
+	applyCmd.PersistentFlags().IntVar(&operator.ResyncWorkers, "workers", operator.ResyncWorkers, "number of registries reconciled concurrently")
+	applyCmd.PersistentFlags().String("output-dir", "", "directory where the generated credentials are written (default is the configuration directory)")
+	applyCmd.PersistentFlags().String("encrypt", "", "encrypt the generated credentials; supported values: age, sops")
+	applyCmd.PersistentFlags().StringSlice("recipient", []string{}, "age recipient of the encrypted credentials")
+	cobra.CheckErr(viper.BindPFlag("output-dir", applyCmd.PersistentFlags().Lookup("output-dir")))
+	cobra.CheckErr(viper.BindPFlag("encrypt", applyCmd.PersistentFlags().Lookup("encrypt")))
+	cobra.CheckErr(viper.BindPFlag("recipient", applyCmd.PersistentFlags().Lookup("recipient")))
 }
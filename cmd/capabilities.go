@@ -0,0 +1,115 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var capabilitiesOutput string
+
+// featureSupportString returns the table cell representation of the feature
+// support, e.g. "rw (>=2.0)".
+func featureSupportString(fs globalregistry.FeatureSupport) string {
+	if !fs.Read && !fs.Write {
+		return "-"
+	}
+	var sb strings.Builder
+	if fs.Read {
+		sb.WriteString("r")
+	}
+	if fs.Write {
+		sb.WriteString("w")
+	}
+	switch {
+	case fs.MinVersion != "" && fs.MaxVersion != "":
+		fmt.Fprintf(&sb, " (%s-%s)", fs.MinVersion, fs.MaxVersion)
+	case fs.MinVersion != "":
+		fmt.Fprintf(&sb, " (>=%s)", fs.MinVersion)
+	case fs.MaxVersion != "":
+		fmt.Fprintf(&sb, " (<=%s)", fs.MaxVersion)
+	}
+	return sb.String()
+}
+
+// writeCapabilityMatrix prints the features (rows) supported by the registered
+// providers (columns) as a table.
+func writeCapabilityMatrix(w io.Writer, providers []string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "FEATURE\t%s\n", strings.ToUpper(strings.Join(providers, "\t")))
+	for _, feature := range globalregistry.Features {
+		cells := make([]string, len(providers))
+		for i, provider := range providers {
+			capabilities, err := globalregistry.GetProviderCapabilities(provider)
+			if err != nil {
+				return err
+			}
+			cells[i] = featureSupportString(capabilities[feature])
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", feature, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// capabilitiesCmd represents the capabilities command
+var capabilitiesCmd = &cobra.Command{
+	Use:   "capabilities",
+	Short: "Show the capabilities of the registry providers",
+	Long: `Show the features supported by the registered registry providers.
+
+In table format, r means that the state of the feature can be read, w means that
+it can be manipulated. The version constraints of the features are shown in
+parentheses.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providers := globalregistry.RegisteredProviders()
+		capabilities := make(map[string]globalregistry.ProviderCapabilities, len(providers))
+		for _, provider := range providers {
+			providerCapabilities, err := globalregistry.GetProviderCapabilities(provider)
+			if err != nil {
+				return err
+			}
+			capabilities[provider] = providerCapabilities
+		}
+		switch capabilitiesOutput {
+		case "table":
+			return writeCapabilityMatrix(os.Stdout, providers)
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(capabilities)
+		case "yaml":
+			return yaml.NewEncoder(os.Stdout).Encode(capabilities)
+		default:
+			return fmt.Errorf("invalid output format: %s", capabilitiesOutput)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(capabilitiesCmd)
+	capabilitiesCmd.Flags().StringVarP(&capabilitiesOutput, "output", "o", "table", "Output format. Supported values are table, json or yaml.")
+}
@@ -0,0 +1,60 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+func TestWriteCapabilityMatrix(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := writeCapabilityMatrix(buf, []string{"acr", "harbor"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(globalregistry.Features)+1 {
+		t.Fatalf("unexpected number of lines: %d\n%s", len(lines), buf.String())
+	}
+	if fields := strings.Fields(lines[0]); len(fields) != 3 || fields[1] != "ACR" || fields[2] != "HARBOR" {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, string(globalregistry.FeatureProjectMembers)+" ") {
+			if fields := strings.Fields(line); fields[1] != "-" || fields[2] != "rw" {
+				t.Errorf("unexpected projectMembers row: %q", line)
+			}
+		}
+	}
+}
+
+func TestFeatureSupportString(t *testing.T) {
+	for expected, fs := range map[string]globalregistry.FeatureSupport{
+		"-":            {},
+		"r":            {Read: true},
+		"rw (>=2.0)":   {Read: true, Write: true, MinVersion: "2.0"},
+		"w (<=1.10)":   {Write: true, MaxVersion: "1.10"},
+		"rw (2.0-2.4)": {Read: true, Write: true, MinVersion: "2.0", MaxVersion: "2.4"},
+	} {
+		if s := featureSupportString(fs); s != expected {
+			t.Errorf("expected %q, got %q", expected, s)
+		}
+	}
+}
@@ -60,7 +60,11 @@ path/filename of the generated tar file can also be overwritten with the '-o' fl
 		if err != nil {
 			return err
 		}
-		transfer := skopeo.New(project.Registry.GetUsername(), project.Registry.GetPassword())
+		transfer, err := skopeo.New(project.Config)
+		if err != nil {
+			return err
+		}
+		defer transfer.Close()
 
 		projectWithRepositories, ok := project.Project.(globalregistry.ProjectWithRepositories)
 		if !ok {
@@ -17,29 +17,57 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/kubermatic-labs/registryman/pkg/config"
+	"github.com/kubermatic-labs/registryman/pkg/health"
+	"github.com/kubermatic-labs/registryman/pkg/metrics"
 	"github.com/kubermatic-labs/registryman/pkg/operator"
 	"github.com/spf13/cobra"
 )
 
+var resyncPeriod time.Duration
+var leaderElect bool
+var metricsAddress string
+var healthProbeAddress string
+var leaderElectionID string
+
+// serve starts an HTTP server in the background.
+func serve(name, address string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:    address,
+		Handler: handler,
+	}
+	go func() {
+		logger.Info("starting "+name+" server", "address", address)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error(err, name+" server failed")
+		}
+	}()
+	return server
+}
+
 // operatorCmd represents the operator command
 var operatorCmd = &cobra.Command{
 	Use:   "operator",
 	Short: "Start in operator mode",
 	Long:  `Start in operator mode`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// the errors are not caused by the command line arguments
+		cmd.SilenceUsage = true
 		config.SetLogger(logger)
 		operator.SetLogger(logger)
 		fmt.Println("operator called")
 		aos, clientConfig, err := config.ConnectToKube(options, "")
 		if err != nil {
 			logger.Error(err, "error connecting to Kubernetes API server")
-			return
+			return err
 		}
 		logger.Info("connecting to Kubernetes for resources",
 			"host", clientConfig.Host)
@@ -48,15 +76,78 @@ var operatorCmd = &cobra.Command{
 			aos.(operator.RegistryStore),
 		)
 		reconciler := operator.NewReconciler(
-			aos.(operator.AOSWithSharedInformerFactory))
-		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			aos.(operator.AOSWithSharedInformerFactory), resyncPeriod)
+		var election *operator.LeaderElection
+		if leaderElect {
+			identity, err := os.Hostname()
+			if err != nil {
+				logger.Error(err, "cannot get the identity for leader election")
+				return fmt.Errorf("cannot get the identity for leader election: %w", err)
+			}
+			election = operator.NewLeaderElection(
+				aos.(operator.LeaseLockProvider).LeaseLock(leaderElectionID, identity))
+			logger.Info("waiting for the leader lease",
+				"lease", leaderElectionID,
+				"identity", identity,
+			)
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer cancel()
-		statusUpdater.Start(ctx)
-		reconciler.Start(ctx)
-		<-ctx.Done()
+		if metricsAddress != "" {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			defer serve("metrics", metricsAddress, mux).Close()
+		}
+		if healthProbeAddress != "" {
+			probes := &health.Probes{}
+			probes.Healthz.Add("reconciler", reconciler.Healthy)
+			probes.Healthz.Add("statusupdater", statusUpdater.Healthy)
+			if election != nil {
+				probes.Readyz.Add("leader-election", election.Ready)
+			}
+			probes.Readyz.Add("informers", func() error {
+				if election != nil && !election.IsLeader() {
+					// the informers are started by the leader only
+					return nil
+				}
+				return reconciler.Synced()
+			})
+			mux := http.NewServeMux()
+			probes.Register(mux)
+			defer serve("health probe", healthProbeAddress, mux).Close()
+		}
+		run := func(ctx context.Context) {
+			statusUpdater.Start(ctx)
+			reconciler.Start(ctx)
+			<-ctx.Done()
+			logger.Info("shutting down, waiting for the running operations")
+			<-reconciler.Done()
+			<-statusUpdater.Done()
+		}
+		if election == nil {
+			run(ctx)
+			return nil
+		}
+		election.Run(ctx, run)
+		if ctx.Err() == nil {
+			// the servers are closed by the deferred calls and the
+			// process exits with a failure, so that it is restarted
+			cancel()
+			err = errors.New("leader lease lost")
+			logger.Error(err, "stopping operator")
+			return err
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(operatorCmd)
+	operatorCmd.PersistentFlags().DurationVar(&resyncPeriod, "resync-period", operator.DefaultResyncPeriod, "period of the full resynchronization of the registries")
+	operatorCmd.PersistentFlags().StringVar(&metricsAddress, "metrics-address", ":8080", "address where the Prometheus metrics are served; empty disables the metrics server")
+	operatorCmd.PersistentFlags().StringVar(&healthProbeAddress, "health-probe-address", ":8081", "address where the /healthz and /readyz probes are served; empty disables the probes")
+	operatorCmd.PersistentFlags().BoolVar(&leaderElect, "leader-elect", false, "use a Lease based leader election, so that only one operator instance is active")
+	operatorCmd.PersistentFlags().StringVar(&leaderElectionID, "leader-election-id", "registryman-operator", "name of the Lease resource used for leader election")
+	operatorCmd.PersistentFlags().DurationVar(&operator.ShutdownTimeout, "shutdown-timeout", operator.ShutdownTimeout, "time given to the running operations to finish on shutdown")
+	operatorCmd.PersistentFlags().IntVar(&operator.ResyncWorkers, "workers", operator.ResyncWorkers, "number of registries reconciled concurrently")
 }
@@ -16,15 +16,51 @@
 
 package cmd
 
-import "github.com/kubermatic-labs/registryman/pkg/globalregistry"
+import (
+	"github.com/kubermatic-labs/registryman/pkg/config"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/spf13/viper"
+)
 
 type cliOptions struct {
-	forceDelete bool
+	forceDelete      bool
+	pausedRegistries []string
+	pausedProjects   []string
 }
 
 var _ globalregistry.CanForceDelete = &cliOptions{}
+var _ globalregistry.CanPause = &cliOptions{}
+var _ config.LocalFileStoreOptions = &cliOptions{}
 
 // ForceDeleteProjects returns with the value of the force-delete CLI option.
 func (o *cliOptions) ForceDeleteProjects() bool {
 	return o.forceDelete
 }
+
+// PausedRegistries returns with the value of the pause-registry CLI option.
+func (o *cliOptions) PausedRegistries() []string {
+	return o.pausedRegistries
+}
+
+// PausedProjects returns with the value of the pause-project CLI option.
+func (o *cliOptions) PausedProjects() []string {
+	return o.pausedProjects
+}
+
+// OutputDir returns with the value of the output-dir CLI option or the
+// corresponding config file setting.
+func (o *cliOptions) OutputDir() string {
+	return viper.GetString("output-dir")
+}
+
+// CredentialEncryption returns with the value of the encrypt CLI option or the
+// corresponding config file setting.
+func (o *cliOptions) CredentialEncryption() string {
+	return viper.GetString("encrypt")
+}
+
+// CredentialRecipients returns with the value of the recipient CLI option or
+// the corresponding config file setting.
+func (o *cliOptions) CredentialRecipients() []string {
+	return viper.GetStringSlice("recipient")
+}
@@ -25,6 +25,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/plugin"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
@@ -43,11 +44,13 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	err := rootCmd.Execute()
+	plugin.Shutdown()
+	cobra.CheckErr(err)
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initLogger)
+	cobra.OnInitialize(initConfig, initLogger, initPlugins)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
@@ -56,6 +59,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kubermatic-harbor.yaml)")
 
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "sets the logging verbosity")
+	rootCmd.PersistentFlags().String("plugin-dir", plugin.DefaultDirectory(), "directory of the provider plugins")
+	cobra.CheckErr(viper.BindPFlag("plugin-dir", rootCmd.PersistentFlags().Lookup("plugin-dir")))
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
@@ -101,3 +106,14 @@ func initLogger() {
 	}
 	logger = zapr.NewLogger(zapLogger)
 }
+
+// initPlugins registers the providers implemented by the plugins of the plugin
+// directory.
+func initPlugins() {
+	plugin.SetLogger(logger)
+	providers, err := plugin.Discover(viper.GetString("plugin-dir"))
+	cobra.CheckErr(err)
+	if len(providers) > 0 {
+		logger.V(1).Info("provider plugins loaded", "providers", providers)
+	}
+}
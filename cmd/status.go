@@ -119,4 +119,5 @@ func init() {
 		[]string{}, "Select which registries shall be checked. When not set all registries will be checked.")
 	statusCmd.PersistentFlags().StringVarP(&outputEncoder, "output", "o", "json", "Output format. Supported values are json or yaml.")
 	statusCmd.PersistentFlags().BoolVarP(&showExpected, "expected", "e", false, "Show the expected state rather than the actual state.")
+	statusCmd.PersistentFlags().IntVar(&reconciler.StatusWorkers, "workers", reconciler.StatusWorkers, "Number of projects whose status is fetched concurrently.")
 }
@@ -17,10 +17,14 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"fmt"
+	"time"
 
 	"net/http"
 
+	"github.com/kubermatic-labs/registryman/pkg/health"
+	"github.com/kubermatic-labs/registryman/pkg/metrics"
 	"github.com/kubermatic-labs/registryman/pkg/webhook"
 	"github.com/spf13/cobra"
 )
@@ -40,12 +44,26 @@ var webhookCmd = &cobra.Command{
 		webhook.SetLogger(logger)
 		logger.V(1).Info("startup configuration",
 			"verbose", verbose)
+		certificate := webhook.NewCertificateLoader(*certFilePath, *keyFilePath)
+		if err := certificate.Load(); err != nil {
+			logger.Error(err, "cannot load TLS certificate, retrying in the background")
+		}
+		go certificate.Run(10*time.Second, nil)
+		probes := &health.Probes{}
+		probes.Readyz.Add("tls", certificate.Ready)
+		probes.Register(http.DefaultServeMux)
 		http.HandleFunc("/", webhook.AdmissionRequestHandler)
+		http.Handle("/metrics", metrics.Handler())
 		logger.Info("starting validating webhook server",
 			"port", *webhookListenPort,
 		)
-		panic(http.ListenAndServeTLS(fmt.Sprintf(":%d", *webhookListenPort),
-			*certFilePath, *keyFilePath, nil))
+		server := &http.Server{
+			Addr: fmt.Sprintf(":%d", *webhookListenPort),
+			TLSConfig: &tls.Config{
+				GetCertificate: certificate.GetCertificate,
+			},
+		}
+		panic(server.ListenAndServeTLS("", ""))
 	},
 }
 
@@ -0,0 +1,248 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// The memory plugin is an example registry provider plugin. It stores the
+// projects in memory, so its state is lost when the plugin exits.
+//
+// Build it into the plugin directory:
+//
+//	go build -o ~/.registryman/plugins/registryman-provider-memory ./examples/plugins/memory
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/plugin"
+)
+
+var capabilities = globalregistry.ProviderCapabilities{
+	globalregistry.FeatureProjects:                {Read: true, Write: true},
+	globalregistry.FeatureProjectDeletion:         {Write: true},
+	globalregistry.FeatureProjectMembers:          {Read: true, Write: true},
+	globalregistry.FeatureProjectScanners:         {Read: true, Write: true},
+	globalregistry.FeatureProjectReplicationRules: {Read: true, Write: true},
+	globalregistry.FeatureProjectStorageReport:    {Read: true},
+	globalregistry.FeaturePullReplication:         {Read: true, Write: true},
+	globalregistry.FeaturePushReplication:         {Read: true, Write: true},
+}
+
+// store contains the projects of the registries, keyed by the registry name.
+var (
+	storeMu sync.Mutex
+	store   = map[string]map[string]*project{}
+)
+
+type registry struct {
+	globalregistry.Registry
+	projects map[string]*project
+}
+
+func newRegistry(logger logr.Logger, config globalregistry.Registry) (globalregistry.Registry, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	projects, ok := store[config.GetName()]
+	if !ok {
+		projects = map[string]*project{}
+		store[config.GetName()] = projects
+	}
+	return &registry{
+		Registry: config,
+		projects: projects,
+	}, nil
+}
+
+func (r *registry) ListProjects(ctx context.Context) ([]globalregistry.Project, error) {
+	names := make([]string, 0, len(r.projects))
+	for name := range r.projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	projects := make([]globalregistry.Project, len(names))
+	for i, name := range names {
+		projects[i] = r.projects[name]
+	}
+	return projects, nil
+}
+
+func (r *registry) GetProjectByName(ctx context.Context, name string) (globalregistry.Project, error) {
+	p, ok := r.projects[name]
+	if !ok {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (r *registry) CreateProject(ctx context.Context, name string) (globalregistry.Project, error) {
+	if _, ok := r.projects[name]; ok {
+		return nil, fmt.Errorf("project %s: %w", name, globalregistry.ErrAlreadyExists)
+	}
+	p := &project{
+		name:     name,
+		registry: r,
+	}
+	r.projects[name] = p
+	return p, nil
+}
+
+type project struct {
+	name     string
+	registry *registry
+	members  []globalregistry.ProjectMember
+	scanner  *plugin.Scanner
+	rules    []*replicationRule
+}
+
+func (p *project) GetName() string {
+	return p.name
+}
+
+func (p *project) Delete(ctx context.Context) error {
+	delete(p.registry.projects, p.name)
+	return nil
+}
+
+func (p *project) GetMembers(ctx context.Context) ([]globalregistry.ProjectMember, error) {
+	return p.members, nil
+}
+
+func (p *project) AssignMember(ctx context.Context, member globalregistry.ProjectMember) (*globalregistry.ProjectMemberCredentials, error) {
+	for _, m := range p.members {
+		if m.GetName() == member.GetName() {
+			return nil, fmt.Errorf("member %s: %w", member.GetName(), globalregistry.ErrAlreadyExists)
+		}
+	}
+	p.members = append(p.members, member)
+	if member.GetType() == "Robot" {
+		return &globalregistry.ProjectMemberCredentials{
+			Username: fmt.Sprintf("robot$%s+%s", p.name, member.GetName()),
+			Password: "secret",
+		}, nil
+	}
+	return nil, nil
+}
+
+func (p *project) UnassignMember(ctx context.Context, member globalregistry.ProjectMember) error {
+	for i, m := range p.members {
+		if m.GetName() == member.GetName() {
+			p.members = append(p.members[:i], p.members[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("member %s: %w", member.GetName(), globalregistry.ErrNotFound)
+}
+
+func (p *project) GetScanner(ctx context.Context) (globalregistry.Scanner, error) {
+	if p.scanner == nil {
+		return nil, nil
+	}
+	return p.scanner, nil
+}
+
+func (p *project) AssignScanner(ctx context.Context, s globalregistry.Scanner) error {
+	p.scanner = &plugin.Scanner{
+		Name: s.GetName(),
+		URL:  s.GetURL(),
+	}
+	return nil
+}
+
+func (p *project) UnassignScanner(ctx context.Context, s globalregistry.Scanner) error {
+	p.scanner = nil
+	return nil
+}
+
+func (p *project) GetReplicationRules(ctx context.Context, trigger globalregistry.ReplicationTrigger, direction string) ([]globalregistry.ReplicationRule, error) {
+	rules := make([]globalregistry.ReplicationRule, len(p.rules))
+	for i, rule := range p.rules {
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+func (p *project) AssignReplicationRule(ctx context.Context, remote globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	rule := &replicationRule{
+		name:      fmt.Sprintf("%s-%s-%s", p.name, remote.GetName(), direction),
+		project:   p,
+		direction: direction,
+		remote: &plugin.RegistryConfig{
+			Name:        remote.GetName(),
+			Provider:    remote.GetProvider(),
+			APIEndpoint: remote.GetAPIEndpoint(),
+		},
+	}
+	if trigger != nil {
+		rule.triggerType = trigger.TriggerType()
+		rule.triggerSchedule = trigger.TriggerSchedule()
+	}
+	p.rules = append(p.rules, rule)
+	return rule, nil
+}
+
+func (p *project) UpdateReplicationRule(ctx context.Context, remote globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	for _, rule := range p.rules {
+		if rule.remote.GetName() == remote.GetName() && rule.direction == direction {
+			rule.triggerType = api.UndefinedRepliationTriggerType
+			rule.triggerSchedule = ""
+			if trigger != nil {
+				rule.triggerType = trigger.TriggerType()
+				rule.triggerSchedule = trigger.TriggerSchedule()
+			}
+			return rule, nil
+		}
+	}
+	return nil, fmt.Errorf("replication rule of %s with %s: %w", p.name, remote.GetName(), globalregistry.ErrNotFound)
+}
+
+func (p *project) GetUsedStorage(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+type replicationRule struct {
+	name            string
+	project         *project
+	remote          *plugin.RegistryConfig
+	triggerType     api.ReplicationTriggerType
+	triggerSchedule string
+	direction       string
+}
+
+func (rule *replicationRule) GetProjectName() string                     { return rule.project.name }
+func (rule *replicationRule) GetName() string                            { return rule.name }
+func (rule *replicationRule) Trigger() globalregistry.ReplicationTrigger { return rule }
+func (rule *replicationRule) TriggerType() api.ReplicationTriggerType    { return rule.triggerType }
+func (rule *replicationRule) TriggerSchedule() string                    { return rule.triggerSchedule }
+func (rule *replicationRule) Direction() string                          { return rule.direction }
+func (rule *replicationRule) RemoteRegistry() globalregistry.Registry    { return rule.remote }
+
+func (rule *replicationRule) Delete(ctx context.Context) error {
+	for i, r := range rule.project.rules {
+		if r == rule {
+			rule.project.rules = append(rule.project.rules[:i], rule.project.rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("replication rule %s: %w", rule.name, globalregistry.ErrNotFound)
+}
+
+func main() {
+	plugin.Serve("memory", capabilities, newRegistry)
+}
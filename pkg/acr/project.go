@@ -49,7 +49,7 @@ func (p *project) Delete(ctx context.Context) error {
 		switch opt := p.registry.GetOptions().(type) {
 		case globalregistry.CanForceDelete:
 			if !opt.ForceDeleteProjects() {
-				return fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.GetName(), globalregistry.ErrRecoverableError)
+				return wrapError("delete project", 0, fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.GetName(), globalregistry.ErrConflict))
 			}
 			for _, repo := range repoNames {
 				p.registry.logger.V(1).Info("deleting repository",
@@ -61,7 +61,7 @@ func (p *project) Delete(ctx context.Context) error {
 				}
 			}
 		default:
-			return globalregistry.ErrNotImplemented
+			return wrapError("delete project", 0, globalregistry.ErrNotImplemented)
 		}
 	}
 	return nil
@@ -43,53 +43,22 @@ func (r *registry) GetProjectByName(ctx context.Context, name string) (globalreg
 			return project, nil
 		}
 	}
-	return nil, fmt.Errorf("no project found: %w", globalregistry.ErrRecoverableError)
+	return nil, wrapError("get project", 0, fmt.Errorf("no project found: %w", globalregistry.ErrNotFound))
 }
 
-type bytesBody struct {
-	*bytes.Buffer
+// wrapError returns a *globalregistry.ProviderError of the acr provider.
+func wrapError(action string, statusCode int, err error) error {
+	return globalregistry.WrapError("acr", action, statusCode, err)
 }
 
-func (bb bytesBody) Close() error { return nil }
-
 func (s *registry) do(req *http.Request) (*http.Response, error) {
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		s.logger.Error(err, "http.Client cannot Do",
-			"req-url", req.URL,
-		)
-		return nil, err
-	}
-
-	buf := bytesBody{
-		Buffer: new(bytes.Buffer),
-	}
-	n, err := buf.ReadFrom(resp.Body)
-	if err != nil {
-		s.logger.Error(err, "cannot read HTTP response body")
-		return nil, err
-	}
-	resp.Body = buf
-
-	switch {
-	case resp.StatusCode == 401:
-		// Unauthorized
-		return nil, globalregistry.ErrUnauthorized
-	case resp.StatusCode < 200 || resp.StatusCode >= 300:
-		// Any other error code
-		s.logger.V(-1).Info("HTTP response status code is not OK",
-			"status-code", resp.StatusCode,
-			"resp-body-size", n,
-			"req-url", req.URL,
-		)
-		s.logger.V(1).Info(buf.String())
-	}
-	return resp, nil
+	return s.client.Do(req.Context(), req)
 }
 
 func (r *registry) getRepositories(ctx context.Context) ([]string, error) {
-	r.parsedUrl.Path = path
-	req, err := http.NewRequest(http.MethodGet, r.parsedUrl.String(), nil)
+	url := *r.parsedUrl
+	url.Path = path
+	req, err := http.NewRequest(http.MethodGet, url.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -19,11 +19,11 @@
 package acr
 
 import (
-	"net/http"
 	"net/url"
 
 	"github.com/go-logr/logr"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
 )
 
 const path = "/v2/_catalog"
@@ -32,7 +32,7 @@ type registry struct {
 	logger    logr.Logger
 	parsedUrl *url.URL
 	globalregistry.Registry
-	*http.Client
+	client *transport.Client
 }
 
 type repositories struct {
@@ -45,15 +45,18 @@ func init() {
 	globalregistry.RegisterProviderImplementation(
 		"acr",
 		newRegistry,
-		acrRegistryCapabilities{},
+		acrRegistryCapabilities,
 	)
 }
 
 func newRegistry(logger logr.Logger, config globalregistry.Registry) (globalregistry.Registry, error) {
-	var err error
+	client, err := transport.New(logger, config)
+	if err != nil {
+		return nil, err
+	}
 	r := &registry{
 		Registry: config,
-		Client:   http.DefaultClient,
+		client:   client,
 		logger:   logger,
 	}
 
@@ -64,14 +67,10 @@ func newRegistry(logger logr.Logger, config globalregistry.Registry) (globalregi
 	return r, nil
 }
 
-type acrRegistryCapabilities struct{}
-
-var _ globalregistry.ReplicationCapabilities = acrRegistryCapabilities{}
-
-func (cap acrRegistryCapabilities) CanPull() bool {
-	return false
-}
-
-func (cap acrRegistryCapabilities) CanPush() bool {
-	return false
+// acrRegistryCapabilities is the capability descriptor of the acr provider.
+// ACR projects are derived from the repository names, so they can be listed
+// and deleted, but not created.
+var acrRegistryCapabilities = globalregistry.ProviderCapabilities{
+	globalregistry.FeatureProjects:        {Read: true},
+	globalregistry.FeatureProjectDeletion: {Write: true},
 }
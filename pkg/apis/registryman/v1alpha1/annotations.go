@@ -0,0 +1,55 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CleanupFinalizer is the finalizer of the Registry and Project resources. The
+// operator removes it once the remote resources of the deleted resource are
+// cleaned up.
+const CleanupFinalizer = "registryman.kubermatic.com/cleanup"
+
+// OrphanAnnotation is the annotation of the Registry and Project resources
+// which, if set to "true", leaves the remote resources in place when the
+// resource is deleted.
+const OrphanAnnotation = "registryman.kubermatic.com/orphan"
+
+// IsOrphaned returns true if the remote resources of the Registry or Project
+// resource shall be left in place when the resource is deleted.
+func IsOrphaned(obj metav1.Object) bool {
+	return obj.GetAnnotations()[OrphanAnnotation] == "true"
+}
+
+// IsBeingCleanedUp returns true if the Registry or Project resource is being
+// deleted and its remote resources shall be removed. Such resources are not
+// part of the expected state.
+func IsBeingCleanedUp(obj metav1.Object) bool {
+	return obj.GetDeletionTimestamp() != nil && !IsOrphaned(obj)
+}
+
+// PausedAnnotation is the annotation of the Registry and Project resources
+// which, if set to "true", pauses their reconciliation. The status of the
+// paused resources is still collected, but no actions are performed on them.
+const PausedAnnotation = "registryman.kubermatic.com/paused"
+
+// IsPaused returns true if the reconciliation of the Registry or Project
+// resource is paused by the PausedAnnotation.
+func IsPaused(obj metav1.Object) bool {
+	return obj.GetAnnotations()[PausedAnnotation] == "true"
+}
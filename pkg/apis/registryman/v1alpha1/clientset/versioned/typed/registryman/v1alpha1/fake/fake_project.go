@@ -102,6 +102,18 @@ func (c *FakeProjects) Update(ctx context.Context, project *v1alpha1.Project, op
 	return obj.(*v1alpha1.Project), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeProjects) UpdateStatus(ctx context.Context, project *v1alpha1.Project, opts v1.UpdateOptions) (*v1alpha1.Project, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(projectsResource, "status", c.ns, project), &v1alpha1.Project{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Project), err
+}
+
 // Delete takes name of the project and deletes it. Returns an error if one occurs.
 func (c *FakeProjects) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -102,6 +102,18 @@ func (c *FakeScanners) Update(ctx context.Context, scanner *v1alpha1.Scanner, op
 	return obj.(*v1alpha1.Scanner), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeScanners) UpdateStatus(ctx context.Context, scanner *v1alpha1.Scanner, opts v1.UpdateOptions) (*v1alpha1.Scanner, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(scannersResource, "status", c.ns, scanner), &v1alpha1.Scanner{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Scanner), err
+}
+
 // Delete takes name of the scanner and deletes it. Returns an error if one occurs.
 func (c *FakeScanners) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -40,6 +40,7 @@ type ProjectsGetter interface {
 type ProjectInterface interface {
 	Create(ctx context.Context, project *v1alpha1.Project, opts v1.CreateOptions) (*v1alpha1.Project, error)
 	Update(ctx context.Context, project *v1alpha1.Project, opts v1.UpdateOptions) (*v1alpha1.Project, error)
+	UpdateStatus(ctx context.Context, project *v1alpha1.Project, opts v1.UpdateOptions) (*v1alpha1.Project, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.Project, error)
@@ -135,6 +136,22 @@ func (c *projects) Update(ctx context.Context, project *v1alpha1.Project, opts v
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *projects) UpdateStatus(ctx context.Context, project *v1alpha1.Project, opts v1.UpdateOptions) (result *v1alpha1.Project, err error) {
+	result = &v1alpha1.Project{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("projects").
+		Name(project.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(project).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the project and deletes it. Returns an error if one occurs.
 func (c *projects) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
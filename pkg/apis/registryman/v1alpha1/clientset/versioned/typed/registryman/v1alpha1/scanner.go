@@ -40,6 +40,7 @@ type ScannersGetter interface {
 type ScannerInterface interface {
 	Create(ctx context.Context, scanner *v1alpha1.Scanner, opts v1.CreateOptions) (*v1alpha1.Scanner, error)
 	Update(ctx context.Context, scanner *v1alpha1.Scanner, opts v1.UpdateOptions) (*v1alpha1.Scanner, error)
+	UpdateStatus(ctx context.Context, scanner *v1alpha1.Scanner, opts v1.UpdateOptions) (*v1alpha1.Scanner, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.Scanner, error)
@@ -135,6 +136,22 @@ func (c *scanners) Update(ctx context.Context, scanner *v1alpha1.Scanner, opts v
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *scanners) UpdateStatus(ctx context.Context, scanner *v1alpha1.Scanner, opts v1.UpdateOptions) (result *v1alpha1.Scanner, err error) {
+	result = &v1alpha1.Scanner{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("scanners").
+		Name(scanner.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(scanner).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the scanner and deletes it. Returns an error if one occurs.
 func (c *scanners) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -30,23 +30,135 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.MemberStatus":          schema_pkg_apis_registryman_v1alpha1_MemberStatus(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.Project":               schema_pkg_apis_registryman_v1alpha1_Project(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectList":           schema_pkg_apis_registryman_v1alpha1_ProjectList(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectMember":         schema_pkg_apis_registryman_v1alpha1_ProjectMember(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectSpec":           schema_pkg_apis_registryman_v1alpha1_ProjectSpec(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectStatus":         schema_pkg_apis_registryman_v1alpha1_ProjectStatus(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.Registry":              schema_pkg_apis_registryman_v1alpha1_Registry(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryCapabilities":  schema_pkg_apis_registryman_v1alpha1_RegistryCapabilities(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryList":          schema_pkg_apis_registryman_v1alpha1_RegistryList(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistrySpec":          schema_pkg_apis_registryman_v1alpha1_RegistrySpec(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryStatus":        schema_pkg_apis_registryman_v1alpha1_RegistryStatus(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ReplicationRuleStatus": schema_pkg_apis_registryman_v1alpha1_ReplicationRuleStatus(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ReplicationTrigger":    schema_pkg_apis_registryman_v1alpha1_ReplicationTrigger(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.Scanner":               schema_pkg_apis_registryman_v1alpha1_Scanner(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerList":           schema_pkg_apis_registryman_v1alpha1_ScannerList(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerSpec":           schema_pkg_apis_registryman_v1alpha1_ScannerSpec(ref),
-		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerStatus":         schema_pkg_apis_registryman_v1alpha1_ScannerStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.CredentialsReference":       schema_pkg_apis_registryman_v1alpha1_CredentialsReference(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.EnvCredentialsReference":    schema_pkg_apis_registryman_v1alpha1_EnvCredentialsReference(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.FileCredentialsReference":   schema_pkg_apis_registryman_v1alpha1_FileCredentialsReference(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.MemberStatus":               schema_pkg_apis_registryman_v1alpha1_MemberStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.Project":                    schema_pkg_apis_registryman_v1alpha1_Project(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectDeletionPolicy":      schema_pkg_apis_registryman_v1alpha1_ProjectDeletionPolicy(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectList":                schema_pkg_apis_registryman_v1alpha1_ProjectList(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectMember":              schema_pkg_apis_registryman_v1alpha1_ProjectMember(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectProvisioningStatus":  schema_pkg_apis_registryman_v1alpha1_ProjectProvisioningStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectRegistryStatus":      schema_pkg_apis_registryman_v1alpha1_ProjectRegistryStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectSpec":                schema_pkg_apis_registryman_v1alpha1_ProjectSpec(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectStatus":              schema_pkg_apis_registryman_v1alpha1_ProjectStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.Registry":                   schema_pkg_apis_registryman_v1alpha1_Registry(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryCapabilities":       schema_pkg_apis_registryman_v1alpha1_RegistryCapabilities(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryList":               schema_pkg_apis_registryman_v1alpha1_RegistryList(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistrySpec":               schema_pkg_apis_registryman_v1alpha1_RegistrySpec(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryStatus":             schema_pkg_apis_registryman_v1alpha1_RegistryStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryTLS":                schema_pkg_apis_registryman_v1alpha1_RegistryTLS(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ReplicationRuleStatus":      schema_pkg_apis_registryman_v1alpha1_ReplicationRuleStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ReplicationTrigger":         schema_pkg_apis_registryman_v1alpha1_ReplicationTrigger(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.Scanner":                    schema_pkg_apis_registryman_v1alpha1_Scanner(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerList":                schema_pkg_apis_registryman_v1alpha1_ScannerList(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerProvisioningStatus":  schema_pkg_apis_registryman_v1alpha1_ScannerProvisioningStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerRegistryStatus":      schema_pkg_apis_registryman_v1alpha1_ScannerRegistryStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerSpec":                schema_pkg_apis_registryman_v1alpha1_ScannerSpec(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerStatus":              schema_pkg_apis_registryman_v1alpha1_ScannerStatus(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretCredentialsReference": schema_pkg_apis_registryman_v1alpha1_SecretCredentialsReference(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretKeyReference":         schema_pkg_apis_registryman_v1alpha1_SecretKeyReference(ref),
+		"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretReference":            schema_pkg_apis_registryman_v1alpha1_SecretReference(ref),
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_CredentialsReference(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "CredentialsReference describes where the registry credentials are stored. Exactly one of the references shall be set.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"secretRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SecretRef references a Kubernetes Secret containing the credentials.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretCredentialsReference"),
+						},
+					},
+					"fileRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FileRef references local files containing the credentials. It is supported only when the resources are read from the local filesystem.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.FileCredentialsReference"),
+						},
+					},
+					"envRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "EnvRef references environment variables containing the credentials.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.EnvCredentialsReference"),
+						},
+					},
+					"uri": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URI references the credentials in a URI-style format. The scheme selects the credential backend:\n  - secret://[<namespace>/]<name>: Kubernetes Secret\n  - vault://<mount>/<path>: HashiCorp Vault KV secret\n  - file:///<dir>: directory containing username and password files\n  - env://<PREFIX>: <PREFIX>_USERNAME and <PREFIX>_PASSWORD variables\nThe usernameKey and passwordKey query parameters select the keys (or file names) of the user name and password.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.EnvCredentialsReference", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.FileCredentialsReference", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretCredentialsReference"},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_EnvCredentialsReference(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "EnvCredentialsReference describes the environment variables that store the registry credentials.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"usernameVar": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UsernameVar is the name of the environment variable containing the user name.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"passwordVar": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PasswordVar is the name of the environment variable containing the password.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"usernameVar", "passwordVar"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_FileCredentialsReference(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "FileCredentialsReference describes the files that store the registry credentials. Relative paths are resolved from the directory of the configuration files.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"usernameFile": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UsernameFile is the path of the file containing the user name.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"passwordFile": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PasswordFile is the path of the file containing the password.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"usernameFile", "passwordFile"},
+			},
+		},
 	}
 }
 
@@ -127,12 +239,68 @@ func schema_pkg_apis_registryman_v1alpha1_Project(ref common.ReferenceCallback)
 							Ref: ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectSpec"),
 						},
 					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Status describes the state of the project at the target registries.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectProvisioningStatus"),
+						},
+					},
 				},
 				Required: []string{"spec"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectProvisioningStatus", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_ProjectDeletionPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ProjectDeletionPolicy describes which of the undeclared projects can be removed from a registry.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"policy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Policy selects the projects that can be removed.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"include": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Include lists the name patterns of the projects the policy applies to. The patterns use the syntax of path.Match. If it is empty, the policy applies to every project.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"exclude": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Exclude lists the name patterns of the projects that are never removed.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -229,6 +397,103 @@ func schema_pkg_apis_registryman_v1alpha1_ProjectMember(ref common.ReferenceCall
 	}
 }
 
+func schema_pkg_apis_registryman_v1alpha1_ProjectProvisioningStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ProjectProvisioningStatus describes the state of a Project resource at its target registries.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"registries": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Registries lists the state of the project at each target registry.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectRegistryStatus"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectRegistryStatus"},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_ProjectRegistryStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ProjectRegistryStatus describes the state of a project at a target registry.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"registry": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Registry is the name of the target registry.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"exists": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Exists shows whether the project exists at the registry.",
+							Default:     false,
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"members": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Members shows whether the project members are synchronized.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"replicationRules": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReplicationRules shows whether the replication rules of the project are synchronized.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"scanner": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Scanner shows whether the scanner of the project is synchronized.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"paused": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Paused shows whether the reconciliation of the project at the registry is paused, either by the project or by the registry.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"lastError": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastError is the last error that occurred while the project was inspected or reconciled at the registry.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"registry", "exists", "members", "replicationRules", "scanner"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_registryman_v1alpha1_ProjectSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -285,6 +550,13 @@ func schema_pkg_apis_registryman_v1alpha1_ProjectSpec(ref common.ReferenceCallba
 							},
 						},
 					},
+					"memberManagement": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MemberManagement selects how the members of the project are managed. If it is not set, the mode of the registry is used.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 					"scanner": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Scanner specifies the name of the assigned scanner.",
@@ -594,7 +866,7 @@ func schema_pkg_apis_registryman_v1alpha1_RegistrySpec(ref common.ReferenceCallb
 				Properties: map[string]spec.Schema{
 					"provider": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Provider identifies the actual registry type, e.g. Harbor, Docker Hub, etc.",
+							Description: "Provider identifies the actual registry type, e.g. Harbor, Docker Hub, etc. Besides the built-in harbor, acr and artifactory providers, the providers implemented by plugins can be used.",
 							Default:     "",
 							Type:        []string{"string"},
 							Format:      "",
@@ -610,20 +882,24 @@ func schema_pkg_apis_registryman_v1alpha1_RegistrySpec(ref common.ReferenceCallb
 					},
 					"username": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Username is the user name to be used during the authentication at the APIEndpoint interface.",
-							Default:     "",
+							Description: "Username is the user name to be used during the authentication at the APIEndpoint interface. It is ignored if CredentialsRef is set.",
 							Type:        []string{"string"},
 							Format:      "",
 						},
 					},
 					"password": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Password is the password to be used during the authentication at the APIEndpoint interface.",
-							Default:     "",
+							Description: "Password is the password to be used during the authentication at the APIEndpoint interface. It is ignored if CredentialsRef is set.",
 							Type:        []string{"string"},
 							Format:      "",
 						},
 					},
+					"credentialsRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CredentialsRef references the user name and password stored outside of the Registry resource.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.CredentialsReference"),
+						},
+					},
 					"role": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Role specifies whether the registry is a Global Hub or a Local registry.",
@@ -640,10 +916,31 @@ func schema_pkg_apis_registryman_v1alpha1_RegistrySpec(ref common.ReferenceCallb
 							Format:      "",
 						},
 					},
+					"tls": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TLS configures the CA bundle used to validate the certificate of the registry endpoint and the client certificate used for mutual TLS authentication.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryTLS"),
+						},
+					},
+					"projectDeletion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ProjectDeletion controls which of the projects not described by a Project resource are removed from the registry. By default only the projects created by registryman are removed.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectDeletionPolicy"),
+						},
+					},
+					"memberManagement": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MemberManagement selects how the members of the projects are managed at the registry, unless the project specifies it. The default is Authoritative.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
-				Required: []string{"provider", "apiEndpoint", "username", "password", "role", "insecureSkipTlsVerify"},
+				Required: []string{"provider", "apiEndpoint", "role", "insecureSkipTlsVerify"},
 			},
 		},
+		Dependencies: []string{
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.CredentialsReference", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectDeletionPolicy", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryTLS"},
 	}
 }
 
@@ -681,12 +978,87 @@ func schema_pkg_apis_registryman_v1alpha1_RegistryStatus(ref common.ReferenceCal
 							Ref:     ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryCapabilities"),
 						},
 					},
+					"version": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Version is the version of the registry if the provider can detect it.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"conditions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Conditions describe the connection to the registry and the result of the last reconciliation. The condition types are Reachable, Authenticated, Synced, Drifted and Paused.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.Condition"),
+									},
+								},
+							},
+						},
+					},
+					"lastSyncTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastSyncTime is the time of the last successful reconciliation.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"observedGeneration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ObservedGeneration is the generation of the Registry resource which was reconciled last time successfully.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"lastError": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastError is the error of the last failed reconciliation. It is cleared by a successful reconciliation.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
 				Required: []string{"projects", "capabilities"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectStatus", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryCapabilities"},
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ProjectStatus", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.RegistryCapabilities", "k8s.io/apimachinery/pkg/apis/meta/v1.Condition", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_RegistryTLS(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RegistryTLS describes the TLS settings of the registry endpoint.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"caBundle": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CABundle is the PEM encoded CA bundle used to validate the certificate of the registry endpoint.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"caBundleSecretRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CABundleSecretRef references a Secret key containing the PEM encoded CA bundle. It is ignored if CABundle is set.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretKeyReference"),
+						},
+					},
+					"clientCertificateSecretRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClientCertificateSecretRef references a kubernetes.io/tls Secret containing the client certificate (tls.crt) and key (tls.key) used for mutual TLS authentication.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretReference"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretKeyReference", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.SecretReference"},
 	}
 }
 
@@ -789,12 +1161,18 @@ func schema_pkg_apis_registryman_v1alpha1_Scanner(ref common.ReferenceCallback)
 							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerSpec"),
 						},
 					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Status describes the state of the scanner at the registries.",
+							Ref:         ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerProvisioningStatus"),
+						},
+					},
 				},
 				Required: []string{"spec"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerProvisioningStatus", "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
 	}
 }
 
@@ -847,6 +1225,95 @@ func schema_pkg_apis_registryman_v1alpha1_ScannerList(ref common.ReferenceCallba
 	}
 }
 
+func schema_pkg_apis_registryman_v1alpha1_ScannerProvisioningStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ScannerProvisioningStatus describes the state of a Scanner resource at the registries.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"registries": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Registries lists the state of the scanner at each registry that manages the scanners at the registry level.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerRegistryStatus"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1.ScannerRegistryStatus"},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_ScannerRegistryStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ScannerRegistryStatus describes the state of a scanner at a registry.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"registry": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Registry is the name of the registry.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"registered": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Registered shows whether the scanner is registered at the registry.",
+							Default:     false,
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"health": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Health is the health of the scanner as reported by the registry.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"projects": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Projects lists the projects of the registry that use the scanner.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"lastError": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastError is the last error that occurred while the registration of the scanner was inspected.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"registry", "registered", "health"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_registryman_v1alpha1_ScannerSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -902,3 +1369,111 @@ func schema_pkg_apis_registryman_v1alpha1_ScannerStatus(ref common.ReferenceCall
 		},
 	}
 }
+
+func schema_pkg_apis_registryman_v1alpha1_SecretCredentialsReference(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "SecretCredentialsReference describes the Kubernetes Secret and its keys that store the registry credentials.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name is the name of the Secret.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace is the namespace of the Secret. If omitted, the namespace of the Registry resource is used.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"usernameKey": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UsernameKey is the key of the user name in the Secret data.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"passwordKey": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PasswordKey is the key of the password in the Secret data.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_SecretKeyReference(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "SecretKeyReference identifies a key of a Kubernetes Secret.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name is the name of the Secret.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace is the namespace of the Secret. If omitted, the namespace of the Registry resource is used.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"key": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Key is the key in the Secret data.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_registryman_v1alpha1_SecretReference(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "SecretReference identifies a Kubernetes Secret.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name is the name of the Secret.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace is the namespace of the Secret. If omitted, the namespace of the Registry resource is used.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+	}
+}
@@ -39,6 +39,16 @@ import (
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=registries,scope=Namespaced,singular=registry
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.provider`
+// +kubebuilder:printcolumn:name="Role",type=string,JSONPath=`.spec.role`
+// +kubebuilder:printcolumn:name="Reachable",type=string,JSONPath=`.status.conditions[?(@.type=="Reachable")].status`
+// +kubebuilder:printcolumn:name="Authenticated",type=string,JSONPath=`.status.conditions[?(@.type=="Authenticated")].status`,priority=1
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+// +kubebuilder:printcolumn:name="Drifted",type=string,JSONPath=`.status.conditions[?(@.type=="Drifted")].status`
+// +kubebuilder:printcolumn:name="Paused",type=string,JSONPath=`.status.conditions[?(@.type=="Paused")].status`,priority=1
+// +kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+// +kubebuilder:printcolumn:name="Error",type=string,JSONPath=`.status.lastError`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Registry describes the expected state of a registry Object
 type Registry struct {
@@ -55,10 +65,11 @@ var _ runtime.Object = &Registry{}
 // RegistrySpec describes the specification of a Registry.
 type RegistrySpec struct {
 
-	// +kubebuilder:validation:Enum=harbor;acr;artifactory
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9-]*$`
 
 	// Provider identifies the actual registry type, e.g. Harbor, Docker Hub,
-	// etc.
+	// etc. Besides the built-in harbor, acr and artifactory providers, the
+	// providers implemented by plugins can be used.
 	Provider string `json:"provider"`
 
 	// +kubebuilder:validation:Pattern=`^(https?|ftp)://[^\s/$.?#].[^\s]*$`
@@ -68,13 +79,23 @@ type RegistrySpec struct {
 	// like "http://harbor.example.com:8080".
 	APIEndpoint string `json:"apiEndpoint"`
 
+	// +kubebuilder:validation:Optional
+
 	// Username is the user name to be used during the authentication at the
-	// APIEndpoint interface.
-	Username string `json:"username"`
+	// APIEndpoint interface. It is ignored if CredentialsRef is set.
+	Username string `json:"username,omitempty"`
+
+	// +kubebuilder:validation:Optional
 
 	// Password is the password to be used during the authentication at the
-	// APIEndpoint interface.
-	Password string `json:"password"`
+	// APIEndpoint interface. It is ignored if CredentialsRef is set.
+	Password string `json:"password,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// CredentialsRef references the user name and password stored outside
+	// of the Registry resource.
+	CredentialsRef *CredentialsReference `json:"credentialsRef,omitempty"`
 
 	// +kubebuilder:default=Local
 	// +kubebuilder:validation:Optional
@@ -90,6 +111,228 @@ type RegistrySpec struct {
 	// InsecureSkipTlsVerify shows whether the TLS validation of the
 	// registry endpoint can be skipped or not.
 	InsecureSkipTlsVerify bool `json:"insecureSkipTlsVerify"`
+
+	// +kubebuilder:validation:Optional
+
+	// TLS configures the CA bundle used to validate the certificate of the
+	// registry endpoint and the client certificate used for mutual TLS
+	// authentication.
+	TLS *RegistryTLS `json:"tls,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// ProjectDeletion controls which of the projects not described by a
+	// Project resource are removed from the registry. By default only the
+	// projects created by registryman are removed.
+	ProjectDeletion *ProjectDeletionPolicy `json:"projectDeletion,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// MemberManagement selects how the members of the projects are managed
+	// at the registry, unless the project specifies it. The default is
+	// Authoritative.
+	MemberManagement MemberManagementMode `json:"memberManagement,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Authoritative;Additive;Owned
+
+// MemberManagementMode selects which of the project members not declared in
+// the Project resource are removed.
+type MemberManagementMode string
+
+const (
+	// AuthoritativeMembers removes every member which is not declared.
+	AuthoritativeMembers MemberManagementMode = "Authoritative"
+
+	// AdditiveMembers only adds and updates the declared members, the
+	// other members are left in place.
+	AdditiveMembers MemberManagementMode = "Additive"
+
+	// OwnedMembers removes only the undeclared members which have been
+	// added by registryman.
+	OwnedMembers MemberManagementMode = "Owned"
+)
+
+// +kubebuilder:validation:Enum=DeleteOwned;DeleteAll;NeverDelete
+
+// ProjectDeletionPolicyType selects which undeclared projects are removed from
+// a registry.
+type ProjectDeletionPolicyType string
+
+const (
+	// DeleteOwned removes only the projects created by registryman.
+	DeleteOwned ProjectDeletionPolicyType = "DeleteOwned"
+
+	// DeleteAll removes every project which is not described by a Project
+	// resource.
+	DeleteAll ProjectDeletionPolicyType = "DeleteAll"
+
+	// NeverDelete leaves every project of the registry in place.
+	NeverDelete ProjectDeletionPolicyType = "NeverDelete"
+)
+
+// ProjectDeletionPolicy describes which of the undeclared projects can be
+// removed from a registry.
+type ProjectDeletionPolicy struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=DeleteOwned
+
+	// Policy selects the projects that can be removed.
+	Policy ProjectDeletionPolicyType `json:"policy,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=set
+
+	// Include lists the name patterns of the projects the policy applies
+	// to. The patterns use the syntax of path.Match. If it is empty, the
+	// policy applies to every project.
+	Include []string `json:"include,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=set
+
+	// Exclude lists the name patterns of the projects that are never
+	// removed.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// RegistryTLS describes the TLS settings of the registry endpoint.
+type RegistryTLS struct {
+	// +kubebuilder:validation:Optional
+
+	// CABundle is the PEM encoded CA bundle used to validate the
+	// certificate of the registry endpoint.
+	CABundle string `json:"caBundle,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// CABundleSecretRef references a Secret key containing the PEM encoded
+	// CA bundle. It is ignored if CABundle is set.
+	CABundleSecretRef *SecretKeyReference `json:"caBundleSecretRef,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// ClientCertificateSecretRef references a kubernetes.io/tls Secret
+	// containing the client certificate (tls.crt) and key (tls.key) used
+	// for mutual TLS authentication.
+	ClientCertificateSecretRef *SecretReference `json:"clientCertificateSecretRef,omitempty"`
+}
+
+// SecretReference identifies a Kubernetes Secret.
+type SecretReference struct {
+	// +kubebuilder:validation:MinLength=1
+
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Optional
+
+	// Namespace is the namespace of the Secret. If omitted, the namespace of
+	// the Registry resource is used.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// SecretKeyReference identifies a key of a Kubernetes Secret.
+type SecretKeyReference struct {
+	SecretReference `json:",inline"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=ca.crt
+
+	// Key is the key in the Secret data.
+	Key string `json:"key,omitempty"`
+}
+
+// CredentialsReference describes where the registry credentials are stored.
+// Exactly one of the references shall be set.
+type CredentialsReference struct {
+	// +kubebuilder:validation:Optional
+
+	// SecretRef references a Kubernetes Secret containing the credentials.
+	SecretRef *SecretCredentialsReference `json:"secretRef,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// FileRef references local files containing the credentials. It is
+	// supported only when the resources are read from the local
+	// filesystem.
+	FileRef *FileCredentialsReference `json:"fileRef,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// EnvRef references environment variables containing the credentials.
+	EnvRef *EnvCredentialsReference `json:"envRef,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9+.-]*:`
+
+	// URI references the credentials in a URI-style format. The scheme
+	// selects the credential backend:
+	//   - secret://[<namespace>/]<name>: Kubernetes Secret
+	//   - vault://<mount>/<path>: HashiCorp Vault KV secret
+	//   - file:///<dir>: directory containing username and password files
+	//   - env://<PREFIX>: <PREFIX>_USERNAME and <PREFIX>_PASSWORD variables
+	// The usernameKey and passwordKey query parameters select the keys (or
+	// file names) of the user name and password.
+	URI string `json:"uri,omitempty"`
+}
+
+// SecretCredentialsReference describes the Kubernetes Secret and its keys that
+// store the registry credentials.
+type SecretCredentialsReference struct {
+	// +kubebuilder:validation:MinLength=1
+
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Optional
+
+	// Namespace is the namespace of the Secret. If omitted, the namespace of
+	// the Registry resource is used.
+	Namespace string `json:"namespace,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=username
+
+	// UsernameKey is the key of the user name in the Secret data.
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=password
+
+	// PasswordKey is the key of the password in the Secret data.
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+// FileCredentialsReference describes the files that store the registry
+// credentials. Relative paths are resolved from the directory of the
+// configuration files.
+type FileCredentialsReference struct {
+	// +kubebuilder:validation:MinLength=1
+
+	// UsernameFile is the path of the file containing the user name.
+	UsernameFile string `json:"usernameFile"`
+
+	// +kubebuilder:validation:MinLength=1
+
+	// PasswordFile is the path of the file containing the password.
+	PasswordFile string `json:"passwordFile"`
+}
+
+// EnvCredentialsReference describes the environment variables that store the
+// registry credentials.
+type EnvCredentialsReference struct {
+	// +kubebuilder:validation:MinLength=1
+
+	// UsernameVar is the name of the environment variable containing the
+	// user name.
+	UsernameVar string `json:"usernameVar"`
+
+	// +kubebuilder:validation:MinLength=1
+
+	// PasswordVar is the name of the environment variable containing the
+	// password.
+	PasswordVar string `json:"passwordVar"`
 }
 
 // RegistryStatus specifies the status of a registry.
@@ -98,8 +341,64 @@ type RegistryStatus struct {
 	// +listMapKey=name
 	Projects     []ProjectStatus      `json:"projects"`
 	Capabilities RegistryCapabilities `json:"capabilities"`
+
+	// +kubebuilder:validation:Optional
+
+	// Version is the version of the registry if the provider can detect
+	// it.
+	Version string `json:"version,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+
+	// Conditions describe the connection to the registry and the result of
+	// the last reconciliation. The condition types are Reachable,
+	// Authenticated, Synced, Drifted and Paused.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// LastSyncTime is the time of the last successful reconciliation.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// ObservedGeneration is the generation of the Registry resource which
+	// was reconciled last time successfully.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// LastError is the error of the last failed reconciliation. It is
+	// cleared by a successful reconciliation.
+	LastError string `json:"lastError,omitempty"`
 }
 
+// The condition types of the RegistryStatus.
+const (
+	// RegistryReachable shows whether the API of the registry can be
+	// accessed.
+	RegistryReachable = "Reachable"
+
+	// RegistryAuthenticated shows whether the registry accepts the
+	// configured credentials.
+	RegistryAuthenticated = "Authenticated"
+
+	// RegistrySynced shows whether the last reconciliation of the registry
+	// succeeded.
+	RegistrySynced = "Synced"
+
+	// RegistryDrifted shows whether the actual state of the registry
+	// differs from the expected one, i.e. some actions could not be
+	// performed during the last reconciliation.
+	RegistryDrifted = "Drifted"
+
+	// RegistryPaused shows whether the reconciliation of the registry is
+	// paused, i.e. its status is collected but no actions are performed.
+	RegistryPaused = "Paused"
+)
+
 type RegistryCapabilities struct {
 	// CanCreateProject shows whether the registry can create projects.
 	CanCreateProject bool `json:"canCreateProject"`
@@ -232,12 +531,16 @@ type RegistryList struct {
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:categories="registryman"
 // +kubebuilder:resource:path=projects,scope=Namespaced,singular=project
+// +kubebuilder:subresource:status
 
 // Project describes the expected state of a globalregistry Project
 type Project struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
 	Spec              *ProjectSpec `json:"spec"`
+
+	// Status describes the state of the project at the target registries.
+	Status *ProjectProvisioningStatus `json:"status,omitempty"`
 }
 
 // Project implements the runtime.Object interface
@@ -266,6 +569,12 @@ type ProjectSpec struct {
 
 	// +kubebuilder:validation:Optional
 
+	// MemberManagement selects how the members of the project are managed.
+	// If it is not set, the mode of the registry is used.
+	MemberManagement MemberManagementMode `json:"memberManagement,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
 	// Scanner specifies the name of the assigned scanner.
 	Scanner string `json:"scanner,omitempty"`
 
@@ -277,6 +586,65 @@ type ProjectSpec struct {
 	Trigger ReplicationTrigger `json:"trigger,omitempty"`
 }
 
+// ProjectProvisioningStatus describes the state of a Project resource at its
+// target registries.
+type ProjectProvisioningStatus struct {
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=registry
+
+	// Registries lists the state of the project at each target registry.
+	Registries []ProjectRegistryStatus `json:"registries,omitempty"`
+}
+
+// ProjectRegistryStatus describes the state of a project at a target registry.
+type ProjectRegistryStatus struct {
+	// Registry is the name of the target registry.
+	Registry string `json:"registry"`
+
+	// Exists shows whether the project exists at the registry.
+	Exists bool `json:"exists"`
+
+	// Members shows whether the project members are synchronized.
+	Members SyncState `json:"members"`
+
+	// ReplicationRules shows whether the replication rules of the project
+	// are synchronized.
+	ReplicationRules SyncState `json:"replicationRules"`
+
+	// Scanner shows whether the scanner of the project is synchronized.
+	Scanner SyncState `json:"scanner"`
+
+	// +kubebuilder:validation:Optional
+
+	// Paused shows whether the reconciliation of the project at the
+	// registry is paused, either by the project or by the registry.
+	Paused bool `json:"paused,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// LastError is the last error that occurred while the project was
+	// inspected or reconciled at the registry.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Synced;OutOfSync;Unknown
+
+// SyncState describes whether a part of a project is synchronized.
+type SyncState string
+
+const (
+	// Synced means that the actual state matches the expected state.
+	Synced SyncState = "Synced"
+
+	// OutOfSync means that the actual state differs from the expected
+	// state.
+	OutOfSync SyncState = "OutOfSync"
+
+	// SyncUnknown means that the actual state could not be inspected.
+	SyncUnknown SyncState = "Unknown"
+)
+
 //------------------------------------------------
 
 // +kubebuilder:validation:Type=string
@@ -584,6 +952,7 @@ type ProjectList struct {
 // +kubebuilder:resource:path=scanners,scope=Namespaced,singular=scanner
 // +kubebuilder:resource:categories="registryman"
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
 
 // Scanner resource describes the configuration of an external vulnerability
 // scanner.
@@ -592,6 +961,9 @@ type Scanner struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	// Spec describes the Scanner Specification.
 	Spec *ScannerSpec `json:"spec"`
+
+	// Status describes the state of the scanner at the registries.
+	Status *ScannerProvisioningStatus `json:"status,omitempty"`
 }
 
 type ScannerSpec struct {
@@ -605,6 +977,59 @@ type ScannerSpec struct {
 	AccessCredential string `json:"accessCredential,omitempty"`
 }
 
+// ScannerProvisioningStatus describes the state of a Scanner resource at the
+// registries.
+type ScannerProvisioningStatus struct {
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=registry
+
+	// Registries lists the state of the scanner at each registry that
+	// manages the scanners at the registry level.
+	Registries []ScannerRegistryStatus `json:"registries,omitempty"`
+}
+
+// ScannerRegistryStatus describes the state of a scanner at a registry.
+type ScannerRegistryStatus struct {
+	// Registry is the name of the registry.
+	Registry string `json:"registry"`
+
+	// Registered shows whether the scanner is registered at the registry.
+	Registered bool `json:"registered"`
+
+	// Health is the health of the scanner as reported by the registry.
+	Health ScannerHealth `json:"health"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=set
+
+	// Projects lists the projects of the registry that use the scanner.
+	Projects []string `json:"projects,omitempty"`
+
+	// +kubebuilder:validation:Optional
+
+	// LastError is the last error that occurred while the registration of
+	// the scanner was inspected.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Healthy;Unhealthy;Unknown
+
+// ScannerHealth describes the health of a scanner.
+type ScannerHealth string
+
+const (
+	// ScannerHealthy means that the registry can reach the scanner.
+	ScannerHealthy ScannerHealth = "Healthy"
+
+	// ScannerUnhealthy means that the registry cannot reach the scanner.
+	ScannerUnhealthy ScannerHealth = "Unhealthy"
+
+	// ScannerHealthUnknown means that the health of the scanner could not
+	// be checked, e.g. because it is not registered.
+	ScannerHealthUnknown ScannerHealth = "Unknown"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ScannerList collects Registry resources.
@@ -22,9 +22,73 @@ limitations under the License.
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsReference) DeepCopyInto(out *CredentialsReference) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretCredentialsReference)
+		**out = **in
+	}
+	if in.FileRef != nil {
+		in, out := &in.FileRef, &out.FileRef
+		*out = new(FileCredentialsReference)
+		**out = **in
+	}
+	if in.EnvRef != nil {
+		in, out := &in.EnvRef, &out.EnvRef
+		*out = new(EnvCredentialsReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsReference.
+func (in *CredentialsReference) DeepCopy() *CredentialsReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvCredentialsReference) DeepCopyInto(out *EnvCredentialsReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvCredentialsReference.
+func (in *EnvCredentialsReference) DeepCopy() *EnvCredentialsReference {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvCredentialsReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileCredentialsReference) DeepCopyInto(out *FileCredentialsReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileCredentialsReference.
+func (in *FileCredentialsReference) DeepCopy() *FileCredentialsReference {
+	if in == nil {
+		return nil
+	}
+	out := new(FileCredentialsReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MemberStatus) DeepCopyInto(out *MemberStatus) {
 	*out = *in
@@ -51,6 +115,11 @@ func (in *Project) DeepCopyInto(out *Project) {
 		*out = new(ProjectSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(ProjectProvisioningStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -72,6 +141,32 @@ func (in *Project) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectDeletionPolicy) DeepCopyInto(out *ProjectDeletionPolicy) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectDeletionPolicy.
+func (in *ProjectDeletionPolicy) DeepCopy() *ProjectDeletionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectDeletionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectList) DeepCopyInto(out *ProjectList) {
 	*out = *in
@@ -121,6 +216,43 @@ func (in *ProjectMember) DeepCopy() *ProjectMember {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectProvisioningStatus) DeepCopyInto(out *ProjectProvisioningStatus) {
+	*out = *in
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]ProjectRegistryStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectProvisioningStatus.
+func (in *ProjectProvisioningStatus) DeepCopy() *ProjectProvisioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectProvisioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectRegistryStatus) DeepCopyInto(out *ProjectRegistryStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectRegistryStatus.
+func (in *ProjectRegistryStatus) DeepCopy() *ProjectRegistryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectRegistryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectSpec) DeepCopyInto(out *ProjectSpec) {
 	*out = *in
@@ -189,7 +321,7 @@ func (in *Registry) DeepCopyInto(out *Registry) {
 	if in.Spec != nil {
 		in, out := &in.Spec, &out.Spec
 		*out = new(RegistrySpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Status != nil {
 		in, out := &in.Status, &out.Status
@@ -269,6 +401,21 @@ func (in *RegistryList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegistrySpec) DeepCopyInto(out *RegistrySpec) {
 	*out = *in
+	if in.CredentialsRef != nil {
+		in, out := &in.CredentialsRef, &out.CredentialsRef
+		*out = new(CredentialsReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(RegistryTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProjectDeletion != nil {
+		in, out := &in.ProjectDeletion, &out.ProjectDeletion
+		*out = new(ProjectDeletionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -293,6 +440,17 @@ func (in *RegistryStatus) DeepCopyInto(out *RegistryStatus) {
 		}
 	}
 	out.Capabilities = in.Capabilities
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -306,6 +464,32 @@ func (in *RegistryStatus) DeepCopy() *RegistryStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryTLS) DeepCopyInto(out *RegistryTLS) {
+	*out = *in
+	if in.CABundleSecretRef != nil {
+		in, out := &in.CABundleSecretRef, &out.CABundleSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.ClientCertificateSecretRef != nil {
+		in, out := &in.ClientCertificateSecretRef, &out.ClientCertificateSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryTLS.
+func (in *RegistryTLS) DeepCopy() *RegistryTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplicationRuleStatus) DeepCopyInto(out *ReplicationRuleStatus) {
 	*out = *in
@@ -349,6 +533,11 @@ func (in *Scanner) DeepCopyInto(out *Scanner) {
 		*out = new(ScannerSpec)
 		**out = **in
 	}
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(ScannerProvisioningStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -403,6 +592,50 @@ func (in *ScannerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScannerProvisioningStatus) DeepCopyInto(out *ScannerProvisioningStatus) {
+	*out = *in
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]ScannerRegistryStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScannerProvisioningStatus.
+func (in *ScannerProvisioningStatus) DeepCopy() *ScannerProvisioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScannerProvisioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScannerRegistryStatus) DeepCopyInto(out *ScannerRegistryStatus) {
+	*out = *in
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScannerRegistryStatus.
+func (in *ScannerRegistryStatus) DeepCopy() *ScannerRegistryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScannerRegistryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScannerSpec) DeepCopyInto(out *ScannerSpec) {
 	*out = *in
@@ -434,3 +667,52 @@ func (in *ScannerStatus) DeepCopy() *ScannerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCredentialsReference) DeepCopyInto(out *SecretCredentialsReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCredentialsReference.
+func (in *SecretCredentialsReference) DeepCopy() *SecretCredentialsReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCredentialsReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+	out.SecretReference = in.SecretReference
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
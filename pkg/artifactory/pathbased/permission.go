@@ -20,10 +20,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
 )
 
 type permissionConfiguration struct {
@@ -53,8 +55,8 @@ func (r *pathRegistry) getPermission(ctx context.Context, permissionName string)
 
 	resp, err := r.do(ctx, req)
 	if err != nil {
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, fmt.Errorf("principal target %s not exists, %w", permissionName, globalregistry.ErrRecoverableError)
+		if errors.Is(err, globalregistry.ErrNotFound) {
+			return nil, wrapError("get permission target", http.StatusNotFound, fmt.Errorf("principal target %s not exists, %w", permissionName, globalregistry.ErrNotFound))
 		}
 		return nil, err
 	}
@@ -65,7 +67,7 @@ func (r *pathRegistry) getPermission(ctx context.Context, permissionName string)
 
 	err = json.NewDecoder(resp.Body).Decode(&permission)
 	if err != nil {
-		buf := resp.Body.(*bytesBody)
+		buf := resp.Body.(*transport.BytesBody)
 		r.logger.Error(err, "json decoding failed")
 		r.logger.Info(buf.String())
 	}
@@ -86,8 +88,8 @@ func (r *pathRegistry) deletePermission(ctx context.Context, projName string) er
 
 	resp, err := r.do(ctx, req)
 	if err != nil {
-		if resp.StatusCode == http.StatusNotFound {
-			return fmt.Errorf("principal target %s not exists, %w", permissionName, globalregistry.ErrRecoverableError)
+		if errors.Is(err, globalregistry.ErrNotFound) {
+			return wrapError("delete permission target", http.StatusNotFound, fmt.Errorf("principal target %s not exists, %w", permissionName, globalregistry.ErrNotFound))
 		}
 		return err
 	}
@@ -30,6 +30,7 @@ var _ globalregistry.ProjectWithRepositories = &project{}
 var _ globalregistry.ProjectWithMembers = &project{}
 
 var _ globalregistry.MemberManipulatorProject = &project{}
+var _ globalregistry.MemberUpdaterProject = &project{}
 var _ globalregistry.DestructibleProject = &project{}
 
 func (p *project) GetName() string {
@@ -47,7 +48,7 @@ func (p *project) Delete(ctx context.Context) error {
 		switch opt := p.registry.GetOptions().(type) {
 		case globalregistry.CanForceDelete:
 			if f := opt.ForceDeleteProjects(); !f {
-				return fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.GetName(), globalregistry.ErrRecoverableError)
+				return wrapError("delete project", 0, fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.GetName(), globalregistry.ErrConflict))
 			}
 		}
 
@@ -144,6 +145,37 @@ func (p *project) UnassignMember(ctx context.Context, member globalregistry.Proj
 	return err
 }
 
+// UpdateMember replaces the permissions of the member in the permission target
+// of the project.
+func (p *project) UpdateMember(ctx context.Context, member globalregistry.ProjectMember) error {
+	role, err := roleFromString(member.GetRole())
+	if err != nil {
+		return err
+	}
+	permissionReqBody, err := p.registry.getPermission(ctx, p.registry.GetDockerRegistryName()+"_"+p.GetName())
+	if err != nil {
+		return err
+	}
+
+	var principals map[string][]string
+	switch member.GetType() {
+	default:
+		return fmt.Errorf("unhandled ProjectMemberType: %s", member.GetType())
+	case userType:
+		principals = permissionReqBody.Principals.Users
+	case groupType:
+		principals = permissionReqBody.Principals.Groups
+	}
+	if _, found := principals[member.GetName()]; !found {
+		return wrapError("update project member", 0, fmt.Errorf(
+			"%s member %s of project %s, %w",
+			strings.ToLower(member.GetType()), member.GetName(), p.GetName(), globalregistry.ErrNotFound))
+	}
+	principals[member.GetName()] = strings.Split(role.String(), ",")
+
+	return p.registry.createPermission(ctx, p.GetName(), permissionReqBody)
+}
+
 func (p *project) GetRepositories(ctx context.Context) ([]string, error) {
 	repos, err := p.registry.listFolders(ctx, p.GetName())
 	if err != nil {
@@ -0,0 +1,66 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package pathbased
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+func TestUpdateMember(t *testing.T) {
+	var updated *permissionConfiguration
+	reg, _ := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(&permissionConfiguration{
+				Name:         "docker_app",
+				Repositories: []string{"docker"},
+				Principals: principals{
+					Users: map[string][]string{"alpha": {"r"}},
+				},
+			})
+		case http.MethodPut:
+			updated = &permissionConfiguration{}
+			if err := json.NewDecoder(r.Body).Decode(updated); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	p := &project{name: "app", registry: reg}
+	ctx := context.Background()
+	if err := p.UpdateMember(ctx, &projectMember{Name: "alpha", Roles: []string{"r", "d", "w", "n"}}); err != nil {
+		t.Fatal(err)
+	}
+	if updated == nil || !reflect.DeepEqual(updated.Principals.Users["alpha"], []string{"r", "d", "w", "n"}) {
+		t.Errorf("the role of the member is not updated: %+v", updated)
+	}
+
+	updated = nil
+	err := p.UpdateMember(ctx, &groupMember{Name: "devs", Roles: []string{"r", "d", "w", "n"}})
+	if !errors.Is(err, globalregistry.ErrNotFound) {
+		t.Errorf("unexpected error for a missing member: %v", err)
+	}
+	if updated != nil {
+		t.Errorf("the permission target is updated: %+v", updated)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -64,7 +65,7 @@ func (r *pathRegistry) GetProjectByName(ctx context.Context, name string) (globa
 			return project, nil
 		}
 	}
-	return nil, fmt.Errorf("no project found: %w", globalregistry.ErrRecoverableError)
+	return nil, wrapError("get project", 0, fmt.Errorf("no project found: %w", globalregistry.ErrNotFound))
 }
 
 func (r *pathRegistry) listFolders(ctx context.Context, optionalProjectName string) ([]string, error) {
@@ -195,17 +196,19 @@ func (r *pathRegistry) delete(ctx context.Context, project string) error {
 	req.SetBasicAuth(r.GetUsername(), r.GetPassword())
 
 	resp, err := r.do(ctx, req)
-	if err != nil {
+	// the permission target is removed even if the repository path is
+	// already gone, e.g. after a partially failed deletion
+	pathNotFound := errors.Is(err, globalregistry.ErrNotFound)
+	if err != nil && !pathNotFound {
 		return err
 	}
-
-	defer resp.Body.Close()
+	if resp != nil {
+		defer resp.Body.Close()
+	}
 
 	err = r.deletePermission(ctx, project)
-	if err != nil {
-		if resp.StatusCode == http.StatusNotFound {
-			return fmt.Errorf("principal target %s not exists, %w", r.GetDockerRegistryName()+"_"+project, globalregistry.ErrRecoverableError)
-		}
+	if pathNotFound && errors.Is(err, globalregistry.ErrNotFound) {
+		return wrapError("delete project", http.StatusNotFound, fmt.Errorf("project %s not exists, %w", project, globalregistry.ErrNotFound))
 	}
 
 	return err
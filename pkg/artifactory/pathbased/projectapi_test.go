@@ -0,0 +1,98 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package pathbased
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
+)
+
+type testConfig struct {
+	apiEndpoint string
+}
+
+var _ globalregistry.Registry = &testConfig{}
+
+func (c *testConfig) GetProvider() string                        { return "artifactory" }
+func (c *testConfig) GetUsername() string                        { return "admin" }
+func (c *testConfig) GetPassword() string                        { return "admin" }
+func (c *testConfig) GetAPIEndpoint() string                     { return c.apiEndpoint }
+func (c *testConfig) GetName() string                            { return "test" }
+func (c *testConfig) GetOptions() globalregistry.RegistryOptions { return nil }
+func (c *testConfig) GetAnnotations() map[string]string {
+	return map[string]string{transport.RateLimitAnnotation: "0"}
+}
+func (c *testConfig) GetInsecureSkipTLSVerify() bool { return false }
+
+// newTestRegistry creates a registry sending its requests to handler. The
+// paths of the requests are recorded in requests.
+func newTestRegistry(t *testing.T, handler http.HandlerFunc) (*pathRegistry, *[]string) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	config := &testConfig{apiEndpoint: server.URL}
+	client, err := transport.New(logr.Discard(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg, err := NewRegistry(logr.Discard(), client, config, "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg.(*pathRegistry), &requests
+}
+
+func TestDeleteProject(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		pathFound        bool
+		permissionFound  bool
+		expectedNotFound bool
+	}{
+		{"existing project", true, true, false},
+		{"missing repository path", false, true, false},
+		{"missing project", false, false, true},
+	} {
+		reg, requests := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+			found := tc.pathFound
+			if r.URL.Path == permissionPath+"/docker_app" {
+				found = tc.permissionFound
+			}
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		err := reg.delete(context.Background(), "app")
+		if errors.Is(err, globalregistry.ErrNotFound) != tc.expectedNotFound ||
+			err != nil && !tc.expectedNotFound {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if len(*requests) != 2 || (*requests)[1] != "DELETE "+permissionPath+"/docker_app" {
+			t.Errorf("%s: the permission target is not deleted: %v", tc.name, *requests)
+		}
+	}
+}
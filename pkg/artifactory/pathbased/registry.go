@@ -19,20 +19,20 @@
 package pathbased
 
 import (
-	"bytes"
 	"context"
 	"net/http"
 	"net/url"
 
 	"github.com/go-logr/logr"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
 )
 
 type pathRegistry struct {
 	logger    logr.Logger
 	parsedUrl *url.URL
 	globalregistry.Registry
-	*http.Client
+	client *transport.Client
 
 	// DockerRegistryName is the name of the registry created in Artifactory
 	DockerRegistryName string
@@ -42,18 +42,26 @@ var _ globalregistry.Registry = &pathRegistry{}
 var _ globalregistry.RegistryWithProjects = &pathRegistry{}
 var _ globalregistry.ProjectCreator = &pathRegistry{}
 
+// Capabilities is the capability descriptor of the path-based Artifactory
+// registries.
+var Capabilities = globalregistry.ProviderCapabilities{
+	globalregistry.FeatureProjects:        {Read: true, Write: true},
+	globalregistry.FeatureProjectDeletion: {Write: true},
+	globalregistry.FeatureProjectMembers:  {Read: true, Write: true},
+}
+
 func (r *pathRegistry) GetDockerRegistryName() string {
 	return r.DockerRegistryName
 }
 
 // newRegistry is the constructor if the registry type. It is a globalregistry RegistryCreator.
-func NewRegistry(logger logr.Logger, client *http.Client, config globalregistry.Registry, dockerRegistryName string) (globalregistry.Registry, error) {
+func NewRegistry(logger logr.Logger, client *transport.Client, config globalregistry.Registry, dockerRegistryName string) (globalregistry.Registry, error) {
 	var err error
 
 	c := &pathRegistry{
 		logger:             logger,
 		Registry:           config,
-		Client:             client,
+		client:             client,
 		DockerRegistryName: dockerRegistryName}
 	c.parsedUrl, err = url.Parse(config.GetAPIEndpoint())
 	if err != nil {
@@ -63,51 +71,15 @@ func NewRegistry(logger logr.Logger, client *http.Client, config globalregistry.
 	return c, nil
 }
 
-type bytesBody struct {
-	*bytes.Buffer
+// wrapError returns a *globalregistry.ProviderError of the artifactory
+// provider.
+func wrapError(action string, statusCode int, err error) error {
+	return globalregistry.WrapError("artifactory", action, statusCode, err)
 }
 
-func (bb bytesBody) Close() error { return nil }
-
-// do method of Registry will perform a normal http.Registry do operation plus
-// it prints extra information in case of unexpected response codes. The
-// response body is replaced with a bytesBody which provides the bytes.Buffer
-// (e.g. String()) methods too.
+// do method of Registry performs the HTTP request using the shared transport
+// client. The response body is a transport.BytesBody which provides the
+// bytes.Buffer (e.g. String()) methods too.
 func (r *pathRegistry) do(ctx context.Context, req *http.Request) (*http.Response, error) {
-	req = req.WithContext(ctx)
-	resp, err := r.Client.Do(req)
-	if err != nil {
-		r.logger.Error(err, "http.Client cannot Do",
-			"req-url", req.URL,
-		)
-		return nil, err
-	}
-
-	buf := bytesBody{
-		Buffer: new(bytes.Buffer),
-	}
-	n, err := buf.ReadFrom(resp.Body)
-	if err != nil {
-		r.logger.Error(err, "cannot read HTTP response body")
-		return nil, err
-	}
-	resp.Body = buf
-
-	switch {
-	case resp.StatusCode == 401:
-		// Unauthorized
-		//
-		// Harbor sometimes misses to return 401 status code. It tends
-		// to respond 200 even when the credentials are incorrect.
-		return nil, globalregistry.ErrUnauthorized
-	case resp.StatusCode < 200 || resp.StatusCode >= 300:
-		// Any other error code
-		r.logger.V(-1).Info("HTTP response status code is not OK",
-			"status-code", resp.StatusCode,
-			"resp-body-size", n,
-			"req-url", req.URL,
-		)
-		r.logger.V(1).Info(buf.String())
-	}
-	return resp, nil
+	return r.client.Do(ctx, req)
 }
@@ -34,6 +34,9 @@ var _ globalregistry.Project = &project{}
 var _ globalregistry.ProjectWithRepositories = &project{}
 var _ globalregistry.ProjectWithMembers = &project{}
 
+// The members are read-only (see Capabilities), so the reconciler does not
+// change them: neither MemberManipulatorProject nor MemberUpdaterProject is
+// implemented.
 // var _ globalregistry.MemberManipulatorProject = &project{}
 var _ globalregistry.DestructibleProject = &project{}
 
@@ -52,7 +55,7 @@ func (p *project) Delete(ctx context.Context) error {
 		switch opt := p.registry.GetOptions().(type) {
 		case globalregistry.CanForceDelete:
 			if f := opt.ForceDeleteProjects(); !f {
-				return fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.Name, globalregistry.ErrRecoverableError)
+				return wrapError("delete project", 0, fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.Name, globalregistry.ErrConflict))
 			}
 			for _, repo := range repos {
 				p.registry.logger.V(1).Info("deleting repository",
@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
 )
 
 const projectPath = "/access/api/v1/projects"
@@ -274,7 +275,7 @@ func (r *projectRegistry) listProjectRepositories(ctx context.Context, proj *pro
 
 	err = json.NewDecoder(resp.Body).Decode(&repositories)
 	if err != nil {
-		buf := resp.Body.(*bytesBody)
+		buf := resp.Body.(*transport.BytesBody)
 		r.logger.Error(err, "json decoding failed")
 		r.logger.Info(buf.String())
 	}
@@ -0,0 +1,32 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package projectbased
+
+import "testing"
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities.RegistryCapabilities("7.41.2")
+	if !caps.CanDeleteProject || !caps.HasProjectMembers || !caps.HasProjectStorageReport {
+		t.Errorf("supported features are missing: %+v", caps)
+	}
+	if caps.CanCreateProject || caps.CanManipulateProjectMembers || caps.HasProjectScanners {
+		t.Errorf("unsupported features are present: %+v", caps)
+	}
+	if caps = Capabilities.RegistryCapabilities("6.23.0"); caps.CanDeleteProject || caps.HasProjectStorageReport {
+		t.Errorf("the project features are reported before Artifactory 7.0: %+v", caps)
+	}
+}
@@ -19,13 +19,13 @@
 package artifactory
 
 import (
-	"crypto/tls"
 	"net/http"
 
 	"github.com/go-logr/logr"
 	"github.com/kubermatic-labs/registryman/pkg/artifactory/pathbased"
 	"github.com/kubermatic-labs/registryman/pkg/artifactory/projectbased"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
 )
 
 func init() {
@@ -33,22 +33,27 @@ func init() {
 	globalregistry.RegisterProviderImplementation(
 		"artifactory",
 		newRegistry,
-		artifactoryRegistryCapabilities{},
+		// the path-based flavor is the default, the project-based
+		// flavor provides its own capabilities
+		pathbased.Capabilities,
 	)
 }
 
 // newRegistry is the constructor if the registry type. It is a globalregistry RegistryCreator.
 func newRegistry(logger logr.Logger, config globalregistry.Registry) (globalregistry.Registry, error) {
-	var err error
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	client := &http.Client{Transport: customTransport}
-
-	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+	options, err := transport.OptionsOf(config)
+	if err != nil {
+		return nil, err
+	}
+	options.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		for key, val := range via[0].Header {
 			req.Header[key] = val
 		}
-		return err
+		return nil
+	}
+	client, err := transport.NewWithOptions(logger, config, options)
+	if err != nil {
+		return nil, err
 	}
 
 	dockerRegistryName := ""
@@ -82,13 +87,3 @@ func newRegistry(logger logr.Logger, config globalregistry.Registry) (globalregi
 	}
 	return c, nil
 }
-
-type artifactoryRegistryCapabilities struct{}
-
-func (cap artifactoryRegistryCapabilities) CanPull() bool {
-	return false
-}
-
-func (cap artifactoryRegistryCapabilities) CanPush() bool {
-	return false
-}
@@ -47,6 +47,13 @@ func init() {
 		panic(err)
 	}
 	secretScheme.AddKnownTypeWithName(secret.GroupVersionKind(), secret)
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+	}
+	secretScheme.AddKnownTypeWithName(configMap.GroupVersionKind(), configMap)
 }
 
 // ApiObjectStore interface is an abstract interface that hides the difference
@@ -0,0 +1,305 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+)
+
+const (
+	// AgeEncryption selects the age envelope format for the credential
+	// files. The whole serialized resource is encrypted and ASCII armored.
+	AgeEncryption = "age"
+
+	// SopsEncryption selects the SOPS-compatible envelope format for the
+	// credential files. Only the data and stringData fields are encrypted,
+	// the files can be decrypted with `sops -d`.
+	SopsEncryption = "sops"
+)
+
+// envelope interface describes how a credential resource is sealed before it
+// is written to the local filesystem.
+type envelope interface {
+	// fileName returns the name of the file where the sealed object is
+	// stored.
+	fileName(obj runtime.Object) string
+
+	// seal writes the encrypted representation of obj to w.
+	seal(w io.Writer, obj runtime.Object) error
+}
+
+// newEnvelope creates the envelope of the given format. The data encryption
+// keys are encrypted to the age recipients.
+func newEnvelope(format string, recipients []string, serializer *json.Serializer) (envelope, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%s encryption requires at least one recipient", format)
+	}
+	ageRecipients := make([]age.Recipient, len(recipients))
+	for i, recipient := range recipients {
+		r, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %s: %w", recipient, err)
+		}
+		ageRecipients[i] = r
+	}
+	switch format {
+	case AgeEncryption:
+		return &ageEnvelope{
+			recipients: ageRecipients,
+			serializer: serializer,
+		}, nil
+	case SopsEncryption:
+		return &sopsEnvelope{
+			recipients:      recipients,
+			ageRecipients:   ageRecipients,
+			encryptedRegexp: regexp.MustCompile(sopsEncryptedRegex),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential encryption format: %s", format)
+	}
+}
+
+// ageEncrypt encrypts plaintext to the recipients and returns the ASCII
+// armored age file.
+func ageEncrypt(w io.Writer, plaintext []byte, recipients []age.Recipient) error {
+	armorWriter := armor.NewWriter(w)
+	ageWriter, err := age.Encrypt(armorWriter, recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err = ageWriter.Write(plaintext); err != nil {
+		return err
+	}
+	if err = ageWriter.Close(); err != nil {
+		return err
+	}
+	return armorWriter.Close()
+}
+
+// ageEnvelope encrypts the complete serialized resource using age.
+type ageEnvelope struct {
+	recipients []age.Recipient
+	serializer *json.Serializer
+}
+
+var _ envelope = &ageEnvelope{}
+
+func (e *ageEnvelope) fileName(obj runtime.Object) string {
+	return getFileName(obj) + ".age"
+}
+
+func (e *ageEnvelope) seal(w io.Writer, obj runtime.Object) error {
+	buf := bytes.NewBuffer(nil)
+	if err := e.serializer.Encode(obj, buf); err != nil {
+		return err
+	}
+	return ageEncrypt(w, buf.Bytes(), e.recipients)
+}
+
+const (
+	sopsVersion        = "3.7.3"
+	sopsEncryptedRegex = "^(data|stringData)$"
+	sopsNonceSize      = 32
+)
+
+// sopsEnvelope encrypts the data and stringData fields of a Secret the same
+// way as SOPS does with age recipients and an encrypted_regex.
+type sopsEnvelope struct {
+	recipients      []string
+	ageRecipients   []age.Recipient
+	encryptedRegexp *regexp.Regexp
+}
+
+var _ envelope = &sopsEnvelope{}
+
+func (e *sopsEnvelope) fileName(obj runtime.Object) string {
+	metaV1Object := obj.(metav1.Object)
+	return fmt.Sprintf("%s.enc.yaml", metaV1Object.GetName())
+}
+
+// sortedMapSlice turns a string map into a yaml.MapSlice with sorted keys, so
+// that the serialized form (and thus the SOPS MAC) is deterministic.
+func sortedMapSlice(m map[string]string) yaml.MapSlice {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make(yaml.MapSlice, len(keys))
+	for i, k := range keys {
+		result[i] = yaml.MapItem{Key: k, Value: m[k]}
+	}
+	return result
+}
+
+// secretDocument returns the Secret as an ordered YAML tree. Only the fields
+// that registryman sets are included.
+func secretDocument(secret *corev1.Secret) yaml.MapSlice {
+	metadata := yaml.MapSlice{
+		{Key: "name", Value: secret.GetName()},
+	}
+	if secret.GetNamespace() != "" {
+		metadata = append(metadata, yaml.MapItem{Key: "namespace", Value: secret.GetNamespace()})
+	}
+	if len(secret.GetAnnotations()) > 0 {
+		metadata = append(metadata, yaml.MapItem{Key: "annotations", Value: sortedMapSlice(secret.GetAnnotations())})
+	}
+	doc := yaml.MapSlice{
+		{Key: "apiVersion", Value: "v1"},
+		{Key: "kind", Value: "Secret"},
+		{Key: "metadata", Value: metadata},
+		{Key: "type", Value: string(secret.Type)},
+	}
+	if len(secret.Data) > 0 {
+		data := make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = base64.StdEncoding.EncodeToString(v)
+		}
+		doc = append(doc, yaml.MapItem{Key: "data", Value: sortedMapSlice(data)})
+	}
+	if len(secret.StringData) > 0 {
+		doc = append(doc, yaml.MapItem{Key: "stringData", Value: sortedMapSlice(secret.StringData)})
+	}
+	return doc
+}
+
+// sopsEncryptValue encrypts a string value with AES256-GCM in the format used
+// by SOPS.
+func sopsEncryptValue(key []byte, value, additionalData string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, sopsNonceSize)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, sopsNonceSize)
+	if _, err = rand.Read(iv); err != nil {
+		return "", err
+	}
+	out := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	tagStart := len(out) - gcm.Overhead()
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(out[:tagStart]),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(out[tagStart:]),
+	), nil
+}
+
+// encryptTree walks the document in order, feeds every leaf value into the
+// MAC and encrypts the values whose path matches the encrypted regex.
+func (e *sopsEnvelope) encryptTree(doc yaml.MapSlice, path []string, key []byte, mac io.Writer) error {
+	for i, item := range doc {
+		itemPath := append(append([]string{}, path...), item.Key.(string))
+		switch value := item.Value.(type) {
+		case yaml.MapSlice:
+			if err := e.encryptTree(value, itemPath, key, mac); err != nil {
+				return err
+			}
+		case string:
+			if _, err := io.WriteString(mac, value); err != nil {
+				return err
+			}
+			encrypt := false
+			for _, p := range itemPath {
+				if e.encryptedRegexp.MatchString(p) {
+					encrypt = true
+					break
+				}
+			}
+			if !encrypt {
+				continue
+			}
+			encrypted, err := sopsEncryptValue(key, value, strings.Join(itemPath, ":")+":")
+			if err != nil {
+				return err
+			}
+			doc[i].Value = encrypted
+		default:
+			return fmt.Errorf("unsupported value type %T at %s", value, strings.Join(itemPath, "."))
+		}
+	}
+	return nil
+}
+
+func (e *sopsEnvelope) seal(w io.Writer, obj runtime.Object) error {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return fmt.Errorf("sops envelope supports only Secrets, got %s", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return err
+	}
+	doc := secretDocument(secret)
+	mac := sha512.New()
+	if err := e.encryptTree(doc, nil, dataKey, mac); err != nil {
+		return err
+	}
+	lastModified := time.Now().UTC().Format(time.RFC3339)
+	encryptedMac, err := sopsEncryptValue(dataKey, fmt.Sprintf("%X", mac.Sum(nil)), lastModified)
+	if err != nil {
+		return err
+	}
+	ageKeys := make([]yaml.MapSlice, len(e.ageRecipients))
+	for i, recipient := range e.ageRecipients {
+		buf := bytes.NewBuffer(nil)
+		if err = ageEncrypt(buf, dataKey, []age.Recipient{recipient}); err != nil {
+			return err
+		}
+		ageKeys[i] = yaml.MapSlice{
+			{Key: "recipient", Value: e.recipients[i]},
+			{Key: "enc", Value: buf.String()},
+		}
+	}
+	doc = append(doc, yaml.MapItem{
+		Key: "sops",
+		Value: yaml.MapSlice{
+			{Key: "kms", Value: []interface{}{}},
+			{Key: "gcp_kms", Value: []interface{}{}},
+			{Key: "azure_kv", Value: []interface{}{}},
+			{Key: "hc_vault", Value: []interface{}{}},
+			{Key: "age", Value: ageKeys},
+			{Key: "lastmodified", Value: lastModified},
+			{Key: "mac", Value: encryptedMac},
+			{Key: "pgp", Value: []interface{}{}},
+			{Key: "encrypted_regex", Value: sopsEncryptedRegex},
+			{Key: "version", Value: sopsVersion},
+		},
+	})
+	return yaml.NewEncoder(w).Encode(doc)
+}
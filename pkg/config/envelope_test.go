@@ -0,0 +1,204 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v2"
+)
+
+// sopsValueRegexp matches the values encrypted by SOPS.
+var sopsValueRegexp = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// sopsDecryptValue decrypts a value the way SOPS does: AES256-GCM with a 32
+// bytes nonce, authenticating additionalData.
+func sopsDecryptValue(t *testing.T, key []byte, value, additionalData string) string {
+	t.Helper()
+	matches := sopsValueRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		t.Fatalf("value is not encrypted in the SOPS format: %s", value)
+	}
+	if matches[4] != "str" {
+		t.Errorf("unexpected value type %s at %s", matches[4], additionalData)
+	}
+	decoded := make([][]byte, 3)
+	for i := range decoded {
+		b, err := base64.StdEncoding.DecodeString(matches[i+1])
+		if err != nil {
+			t.Fatalf("invalid base64 in %s: %v", value, err)
+		}
+		decoded[i] = b
+	}
+	data, iv, tag := decoded[0], decoded[1], decoded[2]
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		t.Fatalf("cannot decrypt the value at %s: %v", additionalData, err)
+	}
+	return string(plaintext)
+}
+
+// sopsDecryptTree decrypts the values of the document in place, like
+// `sops -d` does with an encrypted_regex: the values having a matching key in
+// their path are decrypted, the path (joined with colons) is the additional
+// data. Every value is fed into the MAC in document order.
+func sopsDecryptTree(t *testing.T, doc yaml.MapSlice, path []string, key []byte, encryptedRegexp *regexp.Regexp, mac hash.Hash) {
+	t.Helper()
+	for i, item := range doc {
+		itemPath := append(append([]string{}, path...), fmt.Sprint(item.Key))
+		switch value := item.Value.(type) {
+		case yaml.MapSlice:
+			sopsDecryptTree(t, value, itemPath, key, encryptedRegexp, mac)
+		case string:
+			encrypted := false
+			for _, p := range itemPath {
+				encrypted = encrypted || encryptedRegexp.MatchString(p)
+			}
+			if !encrypted {
+				if sopsValueRegexp.MatchString(value) {
+					t.Errorf("value at %s is encrypted", strings.Join(itemPath, "."))
+				}
+				mac.Write([]byte(value))
+				continue
+			}
+			plaintext := sopsDecryptValue(t, key, value, strings.Join(itemPath, ":")+":")
+			mac.Write([]byte(plaintext))
+			doc[i].Value = plaintext
+		default:
+			t.Fatalf("unexpected value type %T at %s", value, strings.Join(itemPath, "."))
+		}
+	}
+}
+
+// mapOf returns the YAML mapping as a map.
+func mapOf(t *testing.T, value interface{}) map[string]interface{} {
+	t.Helper()
+	mapping, ok := value.(yaml.MapSlice)
+	if !ok {
+		t.Fatalf("%v is not a mapping", value)
+	}
+	result := map[string]interface{}{}
+	for _, item := range mapping {
+		result[fmt.Sprint(item.Key)] = item.Value
+	}
+	return result
+}
+
+func TestSopsRoundTrip(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	aos, err := ReadLocalManifests(t.TempDir(), &testLocalFileOptions{
+		outputDir:  outputDir,
+		encryption: SopsEncryption,
+		recipients: []string{identity.Recipient().String()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := testSecret()
+	secret.SetNamespace("registryman")
+	secret.SetAnnotations(map[string]string{"globalregistry.org/registry-name": "harbor"})
+	secret.Data = map[string][]byte{"username": []byte("robot$ci"), "password": []byte("secret")}
+	if err = aos.WriteResource(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(outputDir, "harbor---app---robot---creds.enc.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc yaml.MapSlice
+	if err = yaml.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// the metadata is stored in the sops key, the rest is the document
+	var tree yaml.MapSlice
+	var sops map[string]interface{}
+	for _, item := range doc {
+		if item.Key == "sops" {
+			sops = mapOf(t, item.Value)
+		} else {
+			tree = append(tree, item)
+		}
+	}
+	ageKeys, ok := sops["age"].([]interface{})
+	if !ok || len(ageKeys) != 1 {
+		t.Fatalf("unexpected age keys: %v", sops["age"])
+	}
+	ageKey := mapOf(t, ageKeys[0])
+	if ageKey["recipient"] != identity.Recipient().String() {
+		t.Errorf("unexpected age recipient: %v", ageKey["recipient"])
+	}
+	dataKey := ageDecrypt(t, strings.NewReader(ageKey["enc"].(string)), identity)
+	encryptedRegex, _ := sops["encrypted_regex"].(string)
+	encryptedRegexp, err := regexp.Compile(encryptedRegex)
+	if err != nil || encryptedRegex == "" {
+		t.Fatalf("invalid encrypted_regex %q: %v", encryptedRegex, err)
+	}
+	lastModified, err := time.Parse(time.RFC3339, fmt.Sprint(sops["lastmodified"]))
+	if err != nil {
+		t.Fatalf("invalid lastmodified: %v", err)
+	}
+
+	mac := sha512.New()
+	sopsDecryptTree(t, tree, nil, dataKey, encryptedRegexp, mac)
+	expectedMac := sopsDecryptValue(t, dataKey, sops["mac"].(string), lastModified.Format(time.RFC3339))
+	if computed := fmt.Sprintf("%X", mac.Sum(nil)); computed != expectedMac {
+		t.Errorf("MAC mismatch: computed %s, stored %s", computed, expectedMac)
+	}
+
+	decrypted := mapOf(t, tree)
+	stringData := mapOf(t, decrypted["stringData"])
+	if stringData[".dockerconfigjson"] != secret.StringData[".dockerconfigjson"] {
+		t.Errorf("unexpected decrypted stringData: %v", stringData)
+	}
+	data := mapOf(t, decrypted["data"])
+	for key, expected := range secret.Data {
+		value, err := base64.StdEncoding.DecodeString(fmt.Sprint(data[key]))
+		if err != nil || !bytes.Equal(value, expected) {
+			t.Errorf("unexpected decrypted data %s: %q (%v)", key, value, err)
+		}
+	}
+	if metadata := mapOf(t, decrypted["metadata"]); metadata["namespace"] != "registryman" {
+		t.Errorf("unexpected metadata: %v", metadata)
+	}
+}
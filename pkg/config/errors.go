@@ -49,3 +49,11 @@ var ErrValidationScannerNameReference error = errors.New("validation error: proj
 // ErrValidationScannerNameReference error indicates that a project refers to a
 // non-existing Scanner.
 var ErrValidationGroupWithoutDN error = errors.New("validation error: project group member with missing DN field")
+
+// ErrValidationCredentialsRef error indicates that the credentialsRef of a
+// registry does not contain exactly one reference.
+var ErrValidationCredentialsRef error = errors.New("validation error: credentialsRef shall contain exactly one reference")
+
+// ErrValidationUnknownProvider error indicates that the provider of a registry
+// is neither a built-in provider nor a provider implemented by a plugin.
+var ErrValidationUnknownProvider error = errors.New("validation error: unknown registry provider")
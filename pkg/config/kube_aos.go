@@ -26,6 +26,7 @@ import (
 	regmanclient "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1/clientset/versioned"
 	"github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1/clientset/versioned/scheme"
 	regmaninformer "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1/informers/externalversions"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
@@ -33,10 +34,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	applyCoreV1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/reference"
 )
@@ -68,6 +71,7 @@ type kubeApiObjectStore struct {
 }
 
 var _ ApiObjectStore = &kubeApiObjectStore{}
+var _ registry.SecretReader = &kubeApiObjectStore{}
 
 func ConnectToKube(options globalregistry.RegistryOptions, ns string) (ApiObjectStore, *rest.Config, error) {
 	var err error
@@ -149,6 +153,26 @@ func (aos *kubeApiObjectStore) WriteResource(ctx context.Context, obj runtime.Ob
 		if err != nil {
 			return fmt.Errorf("error applying secret: %w", err)
 		}
+	case schema.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "ConfigMap",
+	}:
+		configMap := obj.(*corev1.ConfigMap)
+		logger.V(1).Info("applying configmap",
+			"name", configMap.GetName(),
+		)
+		applyConfig := applyCoreV1.ConfigMap(configMap.Name, aos.namespace).
+			WithAnnotations(configMap.GetAnnotations()).
+			WithData(configMap.Data)
+		_, err := aos.kubeClient.CoreV1().ConfigMaps(aos.namespace).Apply(ctx,
+			applyConfig,
+			v1.ApplyOptions{
+				FieldManager: fieldManager,
+			})
+		if err != nil {
+			return fmt.Errorf("error applying configmap: %w", err)
+		}
 	}
 	return nil
 }
@@ -183,10 +207,37 @@ func (aos *kubeApiObjectStore) RemoveResource(ctx context.Context, obj runtime.O
 		if err != nil {
 			return fmt.Errorf("error removing secret: %w", err)
 		}
+	case schema.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "ConfigMap",
+	}:
+		configMap := obj.(*corev1.ConfigMap)
+		logger.V(1).Info("removing configmap",
+			"name", configMap.GetName(),
+		)
+		err := aos.kubeClient.CoreV1().ConfigMaps(aos.namespace).Delete(ctx, configMap.GetName(), v1.DeleteOptions{})
+		if err != nil {
+			return fmt.Errorf("error removing configmap: %w", err)
+		}
 	}
 	return nil
 }
 
+// listFailed handles the failure of listing the resources. If ctx has been
+// cancelled or its deadline has been exceeded (e.g. during the shutdown), the
+// listing is interrupted and no resources are returned. The other failures are
+// fatal.
+func listFailed(ctx context.Context, resource string, err error) {
+	if ctx.Err() == nil {
+		panic(err)
+	}
+	logger.V(1).Info("listing interrupted",
+		"resource", resource,
+		"error", err,
+	)
+}
+
 // GetRegistries returns the parsed registries as API objects.
 func (aos *kubeApiObjectStore) GetRegistries(ctx context.Context) []*api.Registry {
 	logger.V(1).Info("GetRegistries invoked")
@@ -194,7 +245,8 @@ func (aos *kubeApiObjectStore) GetRegistries(ctx context.Context) []*api.Registr
 		"namespace", aos.namespace)
 	registryList, err := aos.regmanClient.RegistrymanV1alpha1().Registries(aos.namespace).List(ctx, v1.ListOptions{})
 	if err != nil {
-		panic(err)
+		listFailed(ctx, "registries", err)
+		return nil
 	}
 	apiRegistries := make([]*api.Registry, len(registryList.Items))
 	for i := range registryList.Items {
@@ -207,7 +259,8 @@ func (aos *kubeApiObjectStore) GetRegistries(ctx context.Context) []*api.Registr
 func (aos *kubeApiObjectStore) GetProjects(ctx context.Context) []*api.Project {
 	projectList, err := aos.regmanClient.RegistrymanV1alpha1().Projects(aos.namespace).List(ctx, v1.ListOptions{})
 	if err != nil {
-		panic(err)
+		listFailed(ctx, "projects", err)
+		return nil
 	}
 	apiProjects := make([]*api.Project, len(projectList.Items))
 	for i := range projectList.Items {
@@ -220,7 +273,8 @@ func (aos *kubeApiObjectStore) GetProjects(ctx context.Context) []*api.Project {
 func (aos *kubeApiObjectStore) GetScanners(ctx context.Context) []*api.Scanner {
 	scannerList, err := aos.regmanClient.RegistrymanV1alpha1().Scanners(aos.namespace).List(ctx, v1.ListOptions{})
 	if err != nil {
-		panic(err)
+		listFailed(ctx, "scanners", err)
+		return nil
 	}
 	apiScanners := make([]*api.Scanner, len(scannerList.Items))
 	for i := range scannerList.Items {
@@ -239,6 +293,29 @@ func (aos *kubeApiObjectStore) GetLogger() logr.Logger {
 	return logger
 }
 
+// GetSecretData returns the data of the Secret identified by namespace and
+// name. If namespace is empty, the namespace of the ApiObjectStore is used.
+func (aos *kubeApiObjectStore) GetSecretData(ctx context.Context, namespace, name string) (map[string][]byte, error) {
+	if namespace == "" {
+		namespace = aos.namespace
+	}
+	secret, err := aos.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// GetConfigMapData returns the data of the ConfigMap with the given name in
+// the namespace of the ApiObjectStore.
+func (aos *kubeApiObjectStore) GetConfigMapData(ctx context.Context, name string) (map[string]string, error) {
+	configMap, err := aos.kubeClient.CoreV1().ConfigMaps(aos.namespace).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return configMap.Data, nil
+}
+
 func (aos *kubeApiObjectStore) UpdateRegistryStatus(ctx context.Context, reg *api.Registry) error {
 	_, err := aos.regmanClient.RegistrymanV1alpha1().Registries(aos.namespace).UpdateStatus(ctx, reg, v1.UpdateOptions{
 		FieldManager: fieldManager,
@@ -246,11 +323,67 @@ func (aos *kubeApiObjectStore) UpdateRegistryStatus(ctx context.Context, reg *ap
 	return err
 }
 
+// UpdateRegistry persists the metadata and the spec of the given Registry
+// resource, e.g. its finalizers.
+func (aos *kubeApiObjectStore) UpdateRegistry(ctx context.Context, reg *api.Registry) error {
+	_, err := aos.regmanClient.RegistrymanV1alpha1().Registries(aos.namespace).Update(ctx, reg, v1.UpdateOptions{
+		FieldManager: fieldManager,
+	})
+	return err
+}
+
+// UpdateProject persists the metadata and the spec of the given Project
+// resource, e.g. its finalizers.
+func (aos *kubeApiObjectStore) UpdateProject(ctx context.Context, project *api.Project) error {
+	_, err := aos.regmanClient.RegistrymanV1alpha1().Projects(aos.namespace).Update(ctx, project, v1.UpdateOptions{
+		FieldManager: fieldManager,
+	})
+	return err
+}
+
+// UpdateProjectStatus persists the status of the given Project resource.
+func (aos *kubeApiObjectStore) UpdateProjectStatus(ctx context.Context, project *api.Project) error {
+	_, err := aos.regmanClient.RegistrymanV1alpha1().Projects(aos.namespace).UpdateStatus(ctx, project, v1.UpdateOptions{
+		FieldManager: fieldManager,
+	})
+	return err
+}
+
+// UpdateScannerStatus persists the status of the given Scanner resource.
+func (aos *kubeApiObjectStore) UpdateScannerStatus(ctx context.Context, scanner *api.Scanner) error {
+	_, err := aos.regmanClient.RegistrymanV1alpha1().Scanners(aos.namespace).UpdateStatus(ctx, scanner, v1.UpdateOptions{
+		FieldManager: fieldManager,
+	})
+	return err
+}
+
 // SharedInformerFactory returns a SharedInformerFactory.
 func (aos *kubeApiObjectStore) SharedInformerFactory(defaultResync time.Duration) regmaninformer.SharedInformerFactory {
 	return regmaninformer.NewSharedInformerFactory(aos.regmanClient, defaultResync)
 }
 
+// KubeSharedInformerFactory returns a SharedInformerFactory for the core
+// Kubernetes resources of the given namespace.
+func (aos *kubeApiObjectStore) KubeSharedInformerFactory(defaultResync time.Duration, namespace string) informers.SharedInformerFactory {
+	return informers.NewSharedInformerFactoryWithOptions(aos.kubeClient, defaultResync,
+		informers.WithNamespace(namespace))
+}
+
+// LeaseLock returns a lock of the Lease resource with the given name in the
+// namespace of the store. It can be used for leader election.
+func (aos *kubeApiObjectStore) LeaseLock(name, identity string) resourcelock.Interface {
+	return &resourcelock.LeaseLock{
+		LeaseMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: aos.namespace,
+		},
+		Client: aos.kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+}
+
 func (aos *kubeApiObjectStore) recordEvent(obj runtime.Object, eventType, reason, message string) {
 	ref, err := reference.GetReference(aos.scheme, obj)
 	if err != nil {
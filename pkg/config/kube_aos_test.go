@@ -0,0 +1,53 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	regmanclient "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1/clientset/versioned"
+	"k8s.io/client-go/rest"
+)
+
+func TestKubeListingInterrupted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client, err := regmanclient.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aos := &kubeApiObjectStore{
+		regmanClient: client,
+		namespace:    "default",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if registries := aos.GetRegistries(ctx); registries != nil {
+		t.Errorf("unexpected registries: %v", registries)
+	}
+	if projects := aos.GetProjects(ctx); projects != nil {
+		t.Errorf("unexpected projects: %v", projects)
+	}
+	if scanners := aos.GetScanners(ctx); scanners != nil {
+		t.Errorf("unexpected scanners: %v", scanners)
+	}
+}
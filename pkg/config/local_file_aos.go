@@ -26,7 +26,9 @@ import (
 
 	"github.com/go-logr/logr"
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -41,26 +43,67 @@ type localFileApiObjectStore struct {
 	serializer *json.Serializer
 	options    globalregistry.RegistryOptions
 	path       string
+	outputDir  string
+	envelope   envelope
 }
 
 var _ ApiObjectStore = &localFileApiObjectStore{}
+var _ registry.SecretReader = &localFileApiObjectStore{}
+var _ registry.FileReader = &localFileApiObjectStore{}
+
+// LocalFileStoreOptions interface describes the options that control how the
+// local file ApiObjectStore writes the generated resources (e.g. the robot
+// credential Secrets).
+type LocalFileStoreOptions interface {
+	// OutputDir returns the directory where the generated resources are
+	// written. If empty, the directory of the manifests is used.
+	OutputDir() string
+
+	// CredentialEncryption returns the envelope format of the credential
+	// files. It can be AgeEncryption, SopsEncryption or empty string for
+	// plaintext files.
+	CredentialEncryption() string
+
+	// CredentialRecipients returns the age recipients the credential files
+	// are encrypted to.
+	CredentialRecipients() []string
+}
 
 func getFileName(obj runtime.Object) string {
 	metaV1Object := obj.(metav1.Object)
 	return fmt.Sprintf("%s.yaml", metaV1Object.GetName())
 }
 
+// isCredential returns true if the object shall be encrypted when an envelope
+// is configured.
+func isCredential(obj runtime.Object) bool {
+	_, ok := obj.(*corev1.Secret)
+	return ok
+}
+
+// resourceFileName returns the path of the file where the object is stored.
+func (aos *localFileApiObjectStore) resourceFileName(obj runtime.Object) string {
+	if aos.envelope != nil && isCredential(obj) {
+		return filepath.Join(aos.outputDir, aos.envelope.fileName(obj))
+	}
+	return filepath.Join(aos.outputDir, getFileName(obj))
+}
+
 // WriteResource serializes the object specified by the obj parameter. The
-// filename is generated from the object name by appending .yaml to it. The path
-// where the file is created is set when the ReadLocalManifests function creates the
-// ApiObjectStore.
+// filename is generated from the object name by appending .yaml to it. The file
+// is created in the output directory which defaults to the path of the
+// manifests. If credential encryption is configured, Secrets are sealed in an
+// age or SOPS envelope.
 func (aos *localFileApiObjectStore) WriteResource(_ context.Context, obj runtime.Object) error {
-	f, err := os.Create(getFileName(obj))
+	f, err := os.OpenFile(aos.resourceFileName(obj), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
+	if aos.envelope != nil && isCredential(obj) {
+		return aos.envelope.seal(f, obj)
+	}
 	err = aos.serializer.Encode(obj, f)
 	if err != nil {
 		return err
@@ -68,20 +111,28 @@ func (aos *localFileApiObjectStore) WriteResource(_ context.Context, obj runtime
 	return nil
 }
 
-// RemoveResource removes a file from the filesystem. The filename is generated
-// from the object name by appending .yaml to it. The path where the file is
-// removed from is set when the ReadLocalManifests function creates the
-// ApiObjectStore.
+// RemoveResource removes a file from the filesystem. The file is looked up in
+// the output directory using the same naming as WriteResource. If there is no
+// encrypted file, the plaintext variant is removed.
 func (aos *localFileApiObjectStore) RemoveResource(_ context.Context, obj runtime.Object) error {
-	return os.Remove(getFileName(obj))
+	fileName := aos.resourceFileName(obj)
+	err := os.Remove(fileName)
+	if os.IsNotExist(err) && aos.envelope != nil {
+		plainFileName := filepath.Join(aos.outputDir, getFileName(obj))
+		if plainFileName != fileName {
+			return os.Remove(plainFileName)
+		}
+	}
+	return err
 }
 
 // ReadLocalManifests creates a new ApiObjectStore. It reads all files under path.
 // The files are deserialized and validated.
 func ReadLocalManifests(path string, options globalregistry.RegistryOptions) (*localFileApiObjectStore, error) {
 	aos := &localFileApiObjectStore{
-		path:    path,
-		options: options,
+		path:      path,
+		options:   options,
+		outputDir: path,
 	}
 	aos.serializer = json.NewSerializerWithOptions(
 		json.DefaultMetaFactory,
@@ -92,13 +143,25 @@ func ReadLocalManifests(path string, options globalregistry.RegistryOptions) (*l
 			Pretty: true,
 			Strict: true,
 		})
+	if lfOptions, ok := options.(LocalFileStoreOptions); ok {
+		if outputDir := lfOptions.OutputDir(); outputDir != "" {
+			aos.outputDir = outputDir
+		}
+		if encryption := lfOptions.CredentialEncryption(); encryption != "" {
+			env, err := newEnvelope(encryption, lfOptions.CredentialRecipients(), aos.serializer)
+			if err != nil {
+				return nil, err
+			}
+			aos.envelope = env
+		}
+	}
 	dir, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer dir.Close()
 	entries, err := dir.ReadDir(16 * 1024)
-	if err != nil {
+	if err != nil && err != io.EOF {
 		return nil, err
 	}
 	aos.store = make(map[schema.GroupVersionKind][]runtime.Object)
@@ -110,6 +173,10 @@ func ReadLocalManifests(path string, options globalregistry.RegistryOptions) (*l
 			// skip the non-yaml files
 			continue
 		}
+		if strings.HasSuffix(entry.Name(), ".enc.yaml") {
+			// skip the encrypted credential files
+			continue
+		}
 		f, err := os.Open(filepath.Join(path, entry.Name()))
 		if err != nil {
 			return nil, err
@@ -205,6 +272,53 @@ func (aos *localFileApiObjectStore) GetScanners(context.Context) []*api.Scanner
 	return scanners
 }
 
+// GetSecretData returns the data of the Secret identified by namespace and name.
+// The Secret shall be stored in the directory of the configuration files.
+func (aos *localFileApiObjectStore) GetSecretData(_ context.Context, namespace, name string) (map[string][]byte, error) {
+	for _, obj := range aos.store[corev1.SchemeGroupVersion.WithKind("Secret")] {
+		secret := obj.(*corev1.Secret)
+		if secret.GetName() != name || secret.GetNamespace() != namespace {
+			continue
+		}
+		data := make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+		for k, v := range secret.StringData {
+			data[k] = []byte(v)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("secret %s not found in %s", name, aos.path)
+}
+
+// GetConfigMapData returns the data of the ConfigMap with the given name. The
+// ConfigMap is read from the output directory, where WriteResource stores it.
+func (aos *localFileApiObjectStore) GetConfigMapData(_ context.Context, name string) (map[string]string, error) {
+	b, err := os.ReadFile(filepath.Join(aos.outputDir, fmt.Sprintf("%s.yaml", name)))
+	if err != nil {
+		return nil, err
+	}
+	o, _, err := aos.serializer.Decode(b, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	configMap, ok := o.(*corev1.ConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a ConfigMap", name)
+	}
+	return configMap.Data, nil
+}
+
+// ReadCredentialFile returns the content of the file specified by path.
+// Relative paths are resolved from the directory of the configuration files.
+func (aos *localFileApiObjectStore) ReadCredentialFile(path string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(aos.path, path)
+	}
+	return os.ReadFile(path)
+}
+
 // GetGlobalRegistryOptions returns the ApiObjectStore related CLI options of an
 // apply.
 func (aos *localFileApiObjectStore) GetGlobalRegistryOptions() globalregistry.RegistryOptions {
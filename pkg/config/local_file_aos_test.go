@@ -17,12 +17,67 @@
 package config
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+type testLocalFileOptions struct {
+	outputDir  string
+	encryption string
+	recipients []string
+}
+
+func (o *testLocalFileOptions) OutputDir() string {
+	return o.outputDir
+}
+
+func (o *testLocalFileOptions) CredentialEncryption() string {
+	return o.encryption
+}
+
+func (o *testLocalFileOptions) CredentialRecipients() []string {
+	return o.recipients
+}
+
+func testSecret() *corev1.Secret {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		StringData: map[string]string{
+			".dockerconfigjson": `{"auths":{"harbor":{"auth":"c2VjcmV0"}}}`,
+		},
+	}
+	secret.SetName("harbor---app---robot---creds")
+	return secret
+}
+
+func ageDecrypt(t *testing.T, r io.Reader, identity age.Identity) []byte {
+	t.Helper()
+	ageReader, err := age.Decrypt(armor.NewReader(r), identity)
+	if err != nil {
+		t.Fatalf("cannot decrypt: %v", err)
+	}
+	b, err := io.ReadAll(ageReader)
+	if err != nil {
+		t.Fatalf("cannot read decrypted content: %v", err)
+	}
+	return b
+}
+
 func TestGetFileName(t *testing.T) {
 	secret := &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
@@ -37,3 +92,262 @@ func TestGetFileName(t *testing.T) {
 	}
 
 }
+
+func TestWriteResourcePlaintext(t *testing.T) {
+	manifestDir := t.TempDir()
+	outputDir := t.TempDir()
+	aos, err := ReadLocalManifests(manifestDir, &testLocalFileOptions{
+		outputDir: outputDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := testSecret()
+	if err = aos.WriteResource(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(outputDir, "harbor---app---robot---creds.yaml")
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte("c2VjcmV0")) {
+		t.Errorf("credential missing from plaintext file:\n%s", b)
+	}
+	if err = aos.RemoveResource(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("file is not removed: %v", err)
+	}
+}
+
+func TestWriteResourceDefaultsToManifestDir(t *testing.T) {
+	manifestDir := t.TempDir()
+	aos, err := ReadLocalManifests(manifestDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = aos.WriteResource(context.Background(), testSecret()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(manifestDir, "harbor---app---robot---creds.yaml")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigMapData(t *testing.T) {
+	manifestDir := t.TempDir()
+	aos, err := ReadLocalManifests(manifestDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err = aos.GetConfigMapData(ctx, "harbor---ownership"); !os.IsNotExist(err) {
+		t.Errorf("missing ConfigMap is not reported: %v", err)
+	}
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		Data: map[string]string{
+			"projects": "app\nos-images",
+		},
+	}
+	configMap.SetName("harbor---ownership")
+	if err = aos.WriteResource(ctx, configMap); err != nil {
+		t.Fatal(err)
+	}
+	data, err := aos.GetConfigMapData(ctx, "harbor---ownership")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data["projects"] != "app\nos-images" {
+		t.Errorf("unexpected ConfigMap data: %v", data)
+	}
+	if _, err = ReadLocalManifests(manifestDir, nil); err != nil {
+		t.Errorf("the written ConfigMap cannot be read back: %v", err)
+	}
+}
+
+func TestWriteResourceAge(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	aos, err := ReadLocalManifests(t.TempDir(), &testLocalFileOptions{
+		outputDir:  outputDir,
+		encryption: AgeEncryption,
+		recipients: []string{identity.Recipient().String()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := testSecret()
+	if err = aos.WriteResource(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(outputDir, "harbor---app---robot---creds.yaml.age")
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b := ageDecrypt(t, f, identity)
+	if !bytes.Contains(b, []byte("c2VjcmV0")) {
+		t.Errorf("credential missing from decrypted file:\n%s", b)
+	}
+	if err = aos.RemoveResource(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("file is not removed: %v", err)
+	}
+}
+
+func TestWriteResourceSops(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	aos, err := ReadLocalManifests(t.TempDir(), &testLocalFileOptions{
+		outputDir:  outputDir,
+		encryption: SopsEncryption,
+		recipients: []string{identity.Recipient().String()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := testSecret()
+	if err = aos.WriteResource(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(outputDir, "harbor---app---robot---creds.enc.yaml")
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("c2VjcmV0")) {
+		t.Errorf("credential is stored in plaintext:\n%s", b)
+	}
+	var doc struct {
+		Kind       string            `yaml:"kind"`
+		StringData map[string]string `yaml:"stringData"`
+		Sops       struct {
+			Age []struct {
+				Recipient string `yaml:"recipient"`
+				Enc       string `yaml:"enc"`
+			} `yaml:"age"`
+			Mac string `yaml:"mac"`
+		} `yaml:"sops"`
+	}
+	if err = yaml.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Kind != "Secret" {
+		t.Errorf("unexpected kind: %s", doc.Kind)
+	}
+	if !strings.HasPrefix(doc.StringData[".dockerconfigjson"], "ENC[AES256_GCM,") {
+		t.Errorf("stringData is not encrypted: %s", doc.StringData[".dockerconfigjson"])
+	}
+	if !strings.HasPrefix(doc.Sops.Mac, "ENC[AES256_GCM,") {
+		t.Errorf("mac is not encrypted: %s", doc.Sops.Mac)
+	}
+	if len(doc.Sops.Age) != 1 || doc.Sops.Age[0].Recipient != identity.Recipient().String() {
+		t.Fatalf("unexpected age recipients: %+v", doc.Sops.Age)
+	}
+	if dataKey := ageDecrypt(t, strings.NewReader(doc.Sops.Age[0].Enc), identity); len(dataKey) != 32 {
+		t.Errorf("unexpected data key length: %d", len(dataKey))
+	}
+	if err = aos.RemoveResource(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("file is not removed: %v", err)
+	}
+}
+
+func TestInvalidCredentialEncryption(t *testing.T) {
+	_, err := ReadLocalManifests(t.TempDir(), &testLocalFileOptions{
+		encryption: AgeEncryption,
+	})
+	if err == nil {
+		t.Error("missing recipient is not detected")
+	}
+	_, err = ReadLocalManifests(t.TempDir(), &testLocalFileOptions{
+		encryption: "rot13",
+		recipients: []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"},
+	})
+	if err == nil {
+		t.Error("unknown encryption format is not detected")
+	}
+}
+
+func TestLocalCredentialReferences(t *testing.T) {
+	manifestDir := t.TempDir()
+	files := map[string]string{
+		"file-registry.yaml": `apiVersion: registryman.kubermatic.com/v1alpha1
+kind: Registry
+metadata:
+  name: file-registry
+spec:
+  provider: harbor
+  role: Local
+  apiEndpoint: http://core.harbor-1.demo
+  credentialsRef:
+    fileRef:
+      usernameFile: username.txt
+      passwordFile: password.txt
+`,
+		"secret-registry.yaml": `apiVersion: registryman.kubermatic.com/v1alpha1
+kind: Registry
+metadata:
+  name: secret-registry
+spec:
+  provider: harbor
+  role: Local
+  apiEndpoint: http://core.harbor-2.demo
+  credentialsRef:
+    secretRef:
+      name: harbor-2-credentials
+`,
+		"harbor-2-credentials.yaml": `apiVersion: v1
+kind: Secret
+metadata:
+  name: harbor-2-credentials
+stringData:
+  username: robot
+  password: token
+`,
+		"username.txt": "admin\n",
+		"password.txt": "secret\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(manifestDir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aos, err := ReadLocalManifests(manifestDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][2]string{
+		"file-registry":   {"admin", "secret"},
+		"secret-registry": {"robot", "token"},
+	}
+	for _, reg := range NewExpectedProvider(aos).GetRegistries(context.Background()) {
+		creds, found := expected[reg.GetName()]
+		if !found {
+			t.Fatalf("unexpected registry: %s", reg.GetName())
+		}
+		if username := reg.GetUsername(); username != creds[0] {
+			t.Errorf("%s: unexpected username: %s", reg.GetName(), username)
+		}
+		if password := reg.GetPassword(); password != creds[1] {
+			t.Errorf("%s: unexpected password: %s", reg.GetName(), password)
+		}
+	}
+}
@@ -31,6 +31,10 @@ import (
 type ProjectOfRegistry struct {
 	Registry globalregistry.Registry
 	Project  globalregistry.Project
+
+	// Config is the configuration of the registry, e.g. it provides the
+	// credentials and the TLS settings.
+	Config globalregistry.Registry
 }
 
 // GenerateProjectRepoName generates a project-level repository URL for a given
@@ -44,7 +48,8 @@ func (p *ProjectOfRegistry) GenerateProjectRepoName() (string, error) {
 }
 
 func newProject(ctx context.Context, aos ApiObjectStore, reg *api.Registry, proj *api.Project) (*ProjectOfRegistry, error) {
-	realRegistry, err := registry.New(reg, aos).ToReal()
+	expectedRegistry := registry.New(reg, aos)
+	realRegistry, err := expectedRegistry.ToReal()
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +62,7 @@ func newProject(ctx context.Context, aos ApiObjectStore, reg *api.Registry, proj
 		return nil, fmt.Errorf("%v Project doesn't exists in %v Registry, actual state differs from the expected state",
 			proj.GetName(), realRegistry.GetAPIEndpoint())
 	}
-	return &ProjectOfRegistry{Registry: realRegistry, Project: realProject}, nil
+	return &ProjectOfRegistry{Registry: realRegistry, Project: realProject, Config: expectedRegistry}, nil
 }
 
 // GetProjectByName returns a Project struct for a matching project name.
@@ -0,0 +1,531 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+)
+
+const (
+	defaultSecretUsernameKey = "username"
+	defaultSecretPasswordKey = "password"
+)
+
+// CredentialProvider interface describes a backend that provides the user name
+// and password of a registry.
+type CredentialProvider interface {
+	// GetCredentials returns the user name and the password.
+	GetCredentials(ctx context.Context) (username, password string, err error)
+}
+
+// CredentialProviderFactory creates a CredentialProvider from a URI-style
+// credential reference. The apiProvider parameter is the ApiObjectProvider of
+// the Registry; it can be used to access the resource store.
+type CredentialProviderFactory func(ref *url.URL, apiProvider ApiObjectProvider) (CredentialProvider, error)
+
+var credentialProviderFactories = map[string]CredentialProviderFactory{}
+
+// RegisterCredentialProvider registers a credential backend for the given URI
+// scheme. It is expected to be called from the init function of the backend
+// implementation.
+func RegisterCredentialProvider(scheme string, factory CredentialProviderFactory) {
+	credentialProviderFactories[scheme] = factory
+}
+
+func init() {
+	RegisterCredentialProvider("secret", newSecretCredentialProviderFromURI)
+	RegisterCredentialProvider("file", newFileCredentialProviderFromURI)
+	RegisterCredentialProvider("env", newEnvCredentialProviderFromURI)
+	RegisterCredentialProvider("vault", newVaultCredentialProviderFromURI)
+}
+
+// SecretReader interface is implemented by the ApiObjectProviders that can read
+// the data of a Kubernetes Secret.
+type SecretReader interface {
+	// GetSecretData returns the data of the Secret identified by namespace
+	// and name.
+	GetSecretData(ctx context.Context, namespace, name string) (map[string][]byte, error)
+}
+
+// FileReader interface is implemented by the ApiObjectProviders that can read
+// credentials from local files.
+type FileReader interface {
+	// ReadCredentialFile returns the content of the file. Relative paths are
+	// resolved by the ApiObjectProvider.
+	ReadCredentialFile(path string) ([]byte, error)
+}
+
+// SecretNamespace returns the namespace of the Secret referenced by the
+// Registry. If the reference does not specify the namespace, the namespace of
+// the Registry is used.
+func SecretNamespace(reg *api.Registry, ref *api.SecretCredentialsReference) string {
+	if ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return reg.GetNamespace()
+}
+
+// secretReference returns the Secret reference of the Registry, either from
+// the secretRef field or from a secret:// URI. If the Registry does not
+// reference a Secret, nil is returned.
+func secretReference(reg *api.Registry) *api.SecretCredentialsReference {
+	if reg.Spec == nil || reg.Spec.CredentialsRef == nil {
+		return nil
+	}
+	if ref := reg.Spec.CredentialsRef.SecretRef; ref != nil {
+		return ref
+	}
+	if uri := reg.Spec.CredentialsRef.URI; uri != "" {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme != "secret" {
+			return nil
+		}
+		return secretReferenceFromURI(u)
+	}
+	return nil
+}
+
+// ReferencesSecret returns true if the credentials or the TLS settings of the
+// Registry are stored in the Secret identified by namespace and name.
+func ReferencesSecret(reg *api.Registry, namespace, name string) bool {
+	matches := func(refName, refNamespace string) bool {
+		if refNamespace == "" {
+			refNamespace = reg.GetNamespace()
+		}
+		return refName == name && refNamespace == namespace
+	}
+	if ref := secretReference(reg); ref != nil && matches(ref.Name, ref.Namespace) {
+		return true
+	}
+	if reg.Spec == nil || reg.Spec.TLS == nil {
+		return false
+	}
+	if ref := reg.Spec.TLS.CABundleSecretRef; ref != nil && matches(ref.Name, ref.Namespace) {
+		return true
+	}
+	if ref := reg.Spec.TLS.ClientCertificateSecretRef; ref != nil && matches(ref.Name, ref.Namespace) {
+		return true
+	}
+	return false
+}
+
+// SecretNamespaces returns the namespaces of the Secrets referenced by the
+// credentials or the TLS settings of the Registry, without duplicates.
+func SecretNamespaces(reg *api.Registry) []string {
+	namespaces := []string{}
+	add := func(refNamespace string) {
+		if refNamespace == "" {
+			refNamespace = reg.GetNamespace()
+		}
+		for _, ns := range namespaces {
+			if ns == refNamespace {
+				return
+			}
+		}
+		namespaces = append(namespaces, refNamespace)
+	}
+	if ref := secretReference(reg); ref != nil {
+		add(ref.Namespace)
+	}
+	if reg.Spec != nil && reg.Spec.TLS != nil {
+		if ref := reg.Spec.TLS.CABundleSecretRef; ref != nil {
+			add(ref.Namespace)
+		}
+		if ref := reg.Spec.TLS.ClientCertificateSecretRef; ref != nil {
+			add(ref.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// staticCredentialProvider provides the credentials stored in the Registry
+// spec.
+type staticCredentialProvider struct {
+	username string
+	password string
+}
+
+func (p *staticCredentialProvider) GetCredentials(context.Context) (string, string, error) {
+	return p.username, p.password, nil
+}
+
+// secretCredentialProvider reads the credentials from a Kubernetes Secret.
+type secretCredentialProvider struct {
+	reader      SecretReader
+	namespace   string
+	name        string
+	usernameKey string
+	passwordKey string
+}
+
+func newSecretCredentialProvider(reg *api.Registry, ref *api.SecretCredentialsReference, apiProvider ApiObjectProvider) (CredentialProvider, error) {
+	secretReader, ok := apiProvider.(SecretReader)
+	if !ok {
+		return nil, fmt.Errorf("secret references are not supported by the resource store")
+	}
+	provider := &secretCredentialProvider{
+		reader:      secretReader,
+		namespace:   SecretNamespace(reg, ref),
+		name:        ref.Name,
+		usernameKey: ref.UsernameKey,
+		passwordKey: ref.PasswordKey,
+	}
+	if provider.usernameKey == "" {
+		provider.usernameKey = defaultSecretUsernameKey
+	}
+	if provider.passwordKey == "" {
+		provider.passwordKey = defaultSecretPasswordKey
+	}
+	return provider, nil
+}
+
+// secretReferenceFromURI parses secret://<name> and
+// secret://<namespace>/<name> references.
+func secretReferenceFromURI(ref *url.URL) *api.SecretCredentialsReference {
+	secretRef := &api.SecretCredentialsReference{
+		UsernameKey: ref.Query().Get("usernameKey"),
+		PasswordKey: ref.Query().Get("passwordKey"),
+	}
+	if name := strings.Trim(ref.Path, "/"); name != "" {
+		secretRef.Namespace = ref.Host
+		secretRef.Name = name
+	} else {
+		secretRef.Name = ref.Host
+	}
+	return secretRef
+}
+
+func newSecretCredentialProviderFromURI(ref *url.URL, apiProvider ApiObjectProvider) (CredentialProvider, error) {
+	secretRef := secretReferenceFromURI(ref)
+	if secretRef.Name == "" {
+		return nil, fmt.Errorf("secret name is missing from %s", ref.Redacted())
+	}
+	// The namespace of the Registry is filled in by the caller.
+	return newSecretCredentialProvider(&api.Registry{}, secretRef, apiProvider)
+}
+
+func (p *secretCredentialProvider) GetCredentials(ctx context.Context) (string, string, error) {
+	data, err := p.reader.GetSecretData(ctx, p.namespace, p.name)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot read secret %s/%s: %w", p.namespace, p.name, err)
+	}
+	username, found := data[p.usernameKey]
+	if !found {
+		return "", "", fmt.Errorf("key %s not found in secret %s/%s", p.usernameKey, p.namespace, p.name)
+	}
+	password, found := data[p.passwordKey]
+	if !found {
+		return "", "", fmt.Errorf("key %s not found in secret %s/%s", p.passwordKey, p.namespace, p.name)
+	}
+	return string(username), string(password), nil
+}
+
+// fileCredentialProvider reads the credentials from files. If the resource
+// store can read credential files (e.g. the local file store), relative paths
+// are resolved by the store, otherwise the files are read directly.
+type fileCredentialProvider struct {
+	reader       FileReader
+	usernameFile string
+	passwordFile string
+}
+
+type osFileReader struct{}
+
+func (osFileReader) ReadCredentialFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func newFileCredentialProvider(usernameFile, passwordFile string, apiProvider ApiObjectProvider) CredentialProvider {
+	fileReader, ok := apiProvider.(FileReader)
+	if !ok {
+		fileReader = osFileReader{}
+	}
+	return &fileCredentialProvider{
+		reader:       fileReader,
+		usernameFile: usernameFile,
+		passwordFile: passwordFile,
+	}
+}
+
+// newFileCredentialProviderFromURI parses file:///<dir> and file:<dir>
+// references. The directory shall contain the username and password files,
+// the file names can be changed with the usernameKey and passwordKey query
+// parameters. This layout matches the Secrets mounted by the Secrets Store
+// CSI driver.
+func newFileCredentialProviderFromURI(ref *url.URL, apiProvider ApiObjectProvider) (CredentialProvider, error) {
+	dir := ref.Path
+	if ref.Opaque != "" {
+		dir = ref.Opaque
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("directory is missing from %s", ref.Redacted())
+	}
+	usernameKey := ref.Query().Get("usernameKey")
+	if usernameKey == "" {
+		usernameKey = defaultSecretUsernameKey
+	}
+	passwordKey := ref.Query().Get("passwordKey")
+	if passwordKey == "" {
+		passwordKey = defaultSecretPasswordKey
+	}
+	return newFileCredentialProvider(
+		filepath.Join(dir, usernameKey),
+		filepath.Join(dir, passwordKey),
+		apiProvider), nil
+}
+
+func (p *fileCredentialProvider) GetCredentials(context.Context) (string, string, error) {
+	username, err := p.reader.ReadCredentialFile(p.usernameFile)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot read username file: %w", err)
+	}
+	password, err := p.reader.ReadCredentialFile(p.passwordFile)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot read password file: %w", err)
+	}
+	return strings.TrimRight(string(username), "\r\n"), strings.TrimRight(string(password), "\r\n"), nil
+}
+
+// envCredentialProvider reads the credentials from environment variables.
+type envCredentialProvider struct {
+	usernameVar string
+	passwordVar string
+}
+
+// newEnvCredentialProviderFromURI parses env://<PREFIX> references, the
+// credentials are read from the <PREFIX>_USERNAME and <PREFIX>_PASSWORD
+// variables. The variable names can be set explicitly with the usernameVar
+// and passwordVar query parameters.
+func newEnvCredentialProviderFromURI(ref *url.URL, _ ApiObjectProvider) (CredentialProvider, error) {
+	provider := &envCredentialProvider{
+		usernameVar: ref.Query().Get("usernameVar"),
+		passwordVar: ref.Query().Get("passwordVar"),
+	}
+	prefix := ref.Host
+	if ref.Opaque != "" {
+		prefix = ref.Opaque
+	}
+	if provider.usernameVar == "" {
+		provider.usernameVar = prefix + "_USERNAME"
+	}
+	if provider.passwordVar == "" {
+		provider.passwordVar = prefix + "_PASSWORD"
+	}
+	if strings.HasPrefix(provider.usernameVar, "_") || strings.HasPrefix(provider.passwordVar, "_") {
+		return nil, fmt.Errorf("variable prefix is missing from %s", ref.Redacted())
+	}
+	return provider, nil
+}
+
+func (p *envCredentialProvider) GetCredentials(context.Context) (string, string, error) {
+	username, found := os.LookupEnv(p.usernameVar)
+	if !found {
+		return "", "", fmt.Errorf("environment variable %s is not set", p.usernameVar)
+	}
+	password, found := os.LookupEnv(p.passwordVar)
+	if !found {
+		return "", "", fmt.Errorf("environment variable %s is not set", p.passwordVar)
+	}
+	return username, password, nil
+}
+
+// NewCredentialProvider returns the CredentialProvider of the Registry. If the
+// Registry does not reference external credentials, the values of the spec are
+// provided.
+func NewCredentialProvider(reg *api.Registry, apiProvider ApiObjectProvider) (CredentialProvider, error) {
+	credRef := reg.Spec.CredentialsRef
+	if credRef == nil {
+		return &staticCredentialProvider{
+			username: reg.Spec.Username,
+			password: reg.Spec.Password,
+		}, nil
+	}
+	switch {
+	case credRef.SecretRef != nil:
+		return newSecretCredentialProvider(reg, credRef.SecretRef, apiProvider)
+	case credRef.FileRef != nil:
+		if _, ok := apiProvider.(FileReader); !ok {
+			return nil, fmt.Errorf("file references are not supported by the resource store")
+		}
+		return newFileCredentialProvider(credRef.FileRef.UsernameFile, credRef.FileRef.PasswordFile, apiProvider), nil
+	case credRef.EnvRef != nil:
+		return &envCredentialProvider{
+			usernameVar: credRef.EnvRef.UsernameVar,
+			passwordVar: credRef.EnvRef.PasswordVar,
+		}, nil
+	case credRef.URI != "":
+		ref, err := url.Parse(credRef.URI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential reference: %w", err)
+		}
+		factory, found := credentialProviderFactories[ref.Scheme]
+		if !found {
+			return nil, fmt.Errorf("unsupported credential reference scheme: %s", ref.Scheme)
+		}
+		provider, err := factory(ref, apiProvider)
+		if err != nil {
+			return nil, err
+		}
+		if secretProvider, ok := provider.(*secretCredentialProvider); ok && secretProvider.namespace == "" {
+			secretProvider.namespace = reg.GetNamespace()
+		}
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("credentialsRef does not contain any reference")
+	}
+}
+
+// credentialCacheTTL is the duration while the resolved external credentials
+// are reused without contacting the backend again.
+var credentialCacheTTL = 5 * time.Minute
+
+type credentialCacheEntry struct {
+	username string
+	password string
+	expires  time.Time
+}
+
+// credentialCache stores the resolved external credentials of the registries.
+// The cache is shared by the Registry values, so that the backends are not
+// contacted at each synchronization.
+type credentialCache struct {
+	mu      sync.Mutex
+	entries map[string]credentialCacheEntry
+}
+
+var defaultCredentialCache = &credentialCache{
+	entries: make(map[string]credentialCacheEntry),
+}
+
+// credentialCacheKey identifies the credentials of a registry. The key
+// contains the reference itself, so that changing the reference invalidates
+// the cached value.
+func credentialCacheKey(reg *api.Registry) string {
+	ref, err := json.Marshal(reg.Spec.CredentialsRef)
+	if err != nil {
+		// cannot happen, the reference contains strings only
+		panic(err)
+	}
+	return fmt.Sprintf("%s/%s/%s", reg.GetNamespace(), reg.GetName(), ref)
+}
+
+func (c *credentialCache) get(key string) (credentialCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, found := c.entries[key]
+	if !found || time.Now().After(entry.expires) {
+		return credentialCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *credentialCache) set(key, username, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = credentialCacheEntry{
+		username: username,
+		password: password,
+		expires:  time.Now().Add(credentialCacheTTL),
+	}
+}
+
+func (c *credentialCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// InvalidateCredentials removes the cached credentials of the Registry, so
+// that they are resolved again when they are needed next time.
+func InvalidateCredentials(reg *api.Registry) {
+	if reg.Spec == nil || reg.Spec.CredentialsRef == nil {
+		return
+	}
+	defaultCredentialCache.invalidate(credentialCacheKey(reg))
+}
+
+// resolveCredentials returns the user name and password of the registry. The
+// external credentials are cached.
+func resolveCredentials(ctx context.Context, reg *api.Registry, apiProvider ApiObjectProvider) (string, string, error) {
+	if reg.Spec.CredentialsRef == nil {
+		return reg.Spec.Username, reg.Spec.Password, nil
+	}
+	key := credentialCacheKey(reg)
+	if entry, found := defaultCredentialCache.get(key); found {
+		return entry.username, entry.password, nil
+	}
+	provider, err := NewCredentialProvider(reg, apiProvider)
+	if err != nil {
+		return "", "", fmt.Errorf("registry %s: %w", reg.GetName(), err)
+	}
+	username, password, err := provider.GetCredentials(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("registry %s: %w", reg.GetName(), err)
+	}
+	defaultCredentialCache.set(key, username, password)
+	return username, password, nil
+}
+
+// credentials returns the resolved user name and password of the registry.
+func (reg *Registry) credentials() (string, string, error) {
+	reg.credentialsMu.Lock()
+	defer reg.credentialsMu.Unlock()
+	if !reg.credentialsResolved {
+		username, password, err := resolveCredentials(context.Background(), reg.apiRegistry, reg.apiProvider)
+		if err != nil {
+			return "", "", err
+		}
+		reg.username, reg.password = username, password
+		reg.credentialsResolved = true
+	}
+	return reg.username, reg.password, nil
+}
+
+// RefreshCredentials drops the cached credentials of the registry and resolves
+// them again from the backend. It shall be invoked when the registry API
+// responds with globalregistry.ErrUnauthorized. The returned bool value shows
+// whether the credentials have changed, i.e. whether it makes sense to retry
+// the failed operation.
+func (reg *Registry) RefreshCredentials() (bool, error) {
+	if reg.apiRegistry.Spec.CredentialsRef == nil {
+		return false, nil
+	}
+	key := credentialCacheKey(reg.apiRegistry)
+	reg.credentialsMu.Lock()
+	oldUsername, oldPassword, known := reg.username, reg.password, reg.credentialsResolved
+	if entry, found := defaultCredentialCache.get(key); !known && found {
+		oldUsername, oldPassword, known = entry.username, entry.password, true
+	}
+	defaultCredentialCache.invalidate(key)
+	reg.credentialsResolved = false
+	reg.credentialsMu.Unlock()
+	username, password, err := reg.credentials()
+	if err != nil {
+		return false, err
+	}
+	return !known || username != oldUsername || password != oldPassword, nil
+}
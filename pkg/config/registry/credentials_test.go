@@ -0,0 +1,410 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type mockSecretApiProvider struct {
+	mockApiProvider
+	secrets map[string]map[string][]byte
+	reads   int
+}
+
+func (ap *mockSecretApiProvider) GetSecretData(_ context.Context, namespace, name string) (map[string][]byte, error) {
+	ap.reads++
+	data, found := ap.secrets[namespace+"/"+name]
+	if !found {
+		return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+	}
+	return data, nil
+}
+
+var _ SecretReader = &mockSecretApiProvider{}
+
+func resetCredentialCache(t *testing.T) {
+	t.Helper()
+	defaultCredentialCache = &credentialCache{
+		entries: make(map[string]credentialCacheEntry),
+	}
+}
+
+func uriRegistry(uri string) *api.Registry {
+	return &api.Registry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "harbor",
+			Namespace: "registryman",
+		},
+		Spec: &api.RegistrySpec{
+			CredentialsRef: &api.CredentialsReference{
+				URI: uri,
+			},
+		},
+	}
+}
+
+func secretRefRegistry(ref *api.SecretCredentialsReference) *api.Registry {
+	return &api.Registry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "harbor",
+			Namespace: "registryman",
+		},
+		Spec: &api.RegistrySpec{
+			Username: "ignored",
+			Password: "ignored",
+			CredentialsRef: &api.CredentialsReference{
+				SecretRef: ref,
+			},
+		},
+	}
+}
+
+func TestRegistry_CredentialsFromSpec(t *testing.T) {
+	reg := New(&api.Registry{
+		Spec: &api.RegistrySpec{
+			Username: "admin",
+			Password: "secret",
+		},
+	}, apiProviderNoForceDelete)
+	if username := reg.GetUsername(); username != "admin" {
+		t.Errorf("unexpected username: %s", username)
+	}
+	if password := reg.GetPassword(); password != "secret" {
+		t.Errorf("unexpected password: %s", password)
+	}
+}
+
+func TestRegistry_CredentialsFromSecret(t *testing.T) {
+	resetCredentialCache(t)
+	ap := &mockSecretApiProvider{
+		secrets: map[string]map[string][]byte{
+			"registryman/harbor-creds": {
+				"username": []byte("admin"),
+				"password": []byte("secret"),
+				"user":     []byte("robot"),
+				"token":    []byte("token"),
+			},
+		},
+	}
+
+	reg := New(secretRefRegistry(&api.SecretCredentialsReference{
+		Name: "harbor-creds",
+	}), ap)
+	if username := reg.GetUsername(); username != "admin" {
+		t.Errorf("unexpected username: %s", username)
+	}
+	if password := reg.GetPassword(); password != "secret" {
+		t.Errorf("unexpected password: %s", password)
+	}
+	if ap.reads != 1 {
+		t.Errorf("secret is read %d times", ap.reads)
+	}
+
+	reg = New(secretRefRegistry(&api.SecretCredentialsReference{
+		Name:        "harbor-creds",
+		Namespace:   "registryman",
+		UsernameKey: "user",
+		PasswordKey: "token",
+	}), ap)
+	if username := reg.GetUsername(); username != "robot" {
+		t.Errorf("unexpected username: %s", username)
+	}
+	if password := reg.GetPassword(); password != "token" {
+		t.Errorf("unexpected password: %s", password)
+	}
+
+	reg = New(secretRefRegistry(&api.SecretCredentialsReference{
+		Name:      "harbor-creds",
+		Namespace: "other",
+	}), ap)
+	if _, err := reg.ToReal(); err == nil {
+		t.Error("missing secret is not detected")
+	}
+
+	reg = New(secretRefRegistry(&api.SecretCredentialsReference{
+		Name:        "harbor-creds",
+		UsernameKey: "missing",
+	}), ap)
+	if _, err := reg.ToReal(); err == nil {
+		t.Error("missing secret key is not detected")
+	}
+}
+
+func TestRegistry_CredentialsFromSecretUnsupported(t *testing.T) {
+	resetCredentialCache(t)
+	reg := New(secretRefRegistry(&api.SecretCredentialsReference{
+		Name: "harbor-creds",
+	}), apiProviderNoForceDelete)
+	if _, err := reg.ToReal(); err == nil {
+		t.Error("unsupported secret reference is not detected")
+	}
+}
+
+func TestRegistry_CredentialsFromEnv(t *testing.T) {
+	resetCredentialCache(t)
+	t.Setenv("TEST_REGISTRY_USERNAME", "admin")
+	t.Setenv("TEST_REGISTRY_PASSWORD", "secret")
+	reg := New(&api.Registry{
+		Spec: &api.RegistrySpec{
+			CredentialsRef: &api.CredentialsReference{
+				EnvRef: &api.EnvCredentialsReference{
+					UsernameVar: "TEST_REGISTRY_USERNAME",
+					PasswordVar: "TEST_REGISTRY_PASSWORD",
+				},
+			},
+		},
+	}, apiProviderNoForceDelete)
+	if username := reg.GetUsername(); username != "admin" {
+		t.Errorf("unexpected username: %s", username)
+	}
+	if password := reg.GetPassword(); password != "secret" {
+		t.Errorf("unexpected password: %s", password)
+	}
+}
+
+func TestReferencesSecret(t *testing.T) {
+	reg := secretRefRegistry(&api.SecretCredentialsReference{
+		Name: "harbor-creds",
+	})
+	if !ReferencesSecret(reg, "registryman", "harbor-creds") {
+		t.Error("secret in the registry namespace is not referenced")
+	}
+	if ReferencesSecret(reg, "other", "harbor-creds") {
+		t.Error("secret in other namespace is referenced")
+	}
+	if ReferencesSecret(registryMissingForceDelete, "registryman", "harbor-creds") {
+		t.Error("registry without spec references secret")
+	}
+}
+
+func TestReferencesSecretURI(t *testing.T) {
+	if !ReferencesSecret(uriRegistry("secret://harbor-creds"), "registryman", "harbor-creds") {
+		t.Error("secret in the registry namespace is not referenced")
+	}
+	if !ReferencesSecret(uriRegistry("secret://other/harbor-creds"), "other", "harbor-creds") {
+		t.Error("secret in other namespace is not referenced")
+	}
+	if ReferencesSecret(uriRegistry("env://HARBOR"), "registryman", "harbor-creds") {
+		t.Error("env reference references secret")
+	}
+}
+
+func TestSecretNamespaces(t *testing.T) {
+	reg := uriRegistry("secret://other/harbor-creds")
+	reg.Spec.TLS = &api.RegistryTLS{
+		CABundleSecretRef: &api.SecretKeyReference{
+			SecretReference: api.SecretReference{Name: "ca"},
+		},
+		ClientCertificateSecretRef: &api.SecretReference{Name: "client", Namespace: "other"},
+	}
+	namespaces := SecretNamespaces(reg)
+	if len(namespaces) != 2 || namespaces[0] != "other" || namespaces[1] != "registryman" {
+		t.Errorf("unexpected namespaces: %v", namespaces)
+	}
+	if namespaces = SecretNamespaces(uriRegistry("env://HARBOR")); len(namespaces) != 0 {
+		t.Errorf("env reference has secret namespaces: %v", namespaces)
+	}
+}
+
+func TestRegistry_CredentialsFromURI(t *testing.T) {
+	resetCredentialCache(t)
+	ap := &mockSecretApiProvider{
+		secrets: map[string]map[string][]byte{
+			"registryman/harbor-creds": {
+				"user":  []byte("secret-admin"),
+				"token": []byte("secret-password"),
+			},
+		},
+	}
+	credDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(credDir, "username"), []byte("file-admin\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(credDir, "password"), []byte("file-password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HARBOR_USERNAME", "env-admin")
+	t.Setenv("HARBOR_PASSWORD", "env-password")
+
+	testCases := []struct {
+		uri      string
+		username string
+		password string
+	}{
+		{uri: "secret://harbor-creds?usernameKey=user&passwordKey=token", username: "secret-admin", password: "secret-password"},
+		{uri: "file://" + credDir, username: "file-admin", password: "file-password"},
+		{uri: "env://HARBOR", username: "env-admin", password: "env-password"},
+		{uri: "env:?usernameVar=HARBOR_USERNAME&passwordVar=HARBOR_PASSWORD", username: "env-admin", password: "env-password"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.uri, func(t *testing.T) {
+			reg := New(uriRegistry(tc.uri), ap)
+			if username := reg.GetUsername(); username != tc.username {
+				t.Errorf("unexpected username: %s", username)
+			}
+			if password := reg.GetPassword(); password != tc.password {
+				t.Errorf("unexpected password: %s", password)
+			}
+		})
+	}
+
+	for _, uri := range []string{"unknown://harbor", "vault://secret", "env://", "secret://"} {
+		if _, err := New(uriRegistry(uri), ap).ToReal(); err == nil {
+			t.Errorf("invalid reference is not detected: %s", uri)
+		}
+	}
+}
+
+type vaultStandIn struct {
+	token    string
+	data     map[string]interface{}
+	requests int
+}
+
+func (v *vaultStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.requests++
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var data interface{}
+	switch r.URL.Path {
+	case "/v1/secret/data/registries/harbor":
+		data = map[string]interface{}{
+			"data": v.data,
+			"metadata": map[string]interface{}{
+				"version": 1,
+			},
+		}
+	case "/v1/kv/registries/harbor":
+		data = v.data
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": data}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegistry_CredentialsFromVault(t *testing.T) {
+	resetCredentialCache(t)
+	vault := &vaultStandIn{
+		token: "s.token",
+		data: map[string]interface{}{
+			"username": "vault-admin",
+			"password": "vault-password",
+		},
+	}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", vault.token)
+
+	reg := New(uriRegistry("vault://secret/registries/harbor"), apiProviderNoForceDelete)
+	if username := reg.GetUsername(); username != "vault-admin" {
+		t.Errorf("unexpected username: %s", username)
+	}
+	if password := reg.GetPassword(); password != "vault-password" {
+		t.Errorf("unexpected password: %s", password)
+	}
+
+	reg = New(uriRegistry("vault://kv/registries/harbor?kvVersion=1"), apiProviderNoForceDelete)
+	if username := reg.GetUsername(); username != "vault-admin" {
+		t.Errorf("unexpected username: %s", username)
+	}
+
+	for _, uri := range []string{
+		"vault://secret/registries/missing",
+		"vault://secret/registries/harbor?usernameKey=missing",
+		"vault://secret/registries/harbor?kvVersion=3",
+	} {
+		if _, err := New(uriRegistry(uri), apiProviderNoForceDelete).ToReal(); err == nil {
+			t.Errorf("invalid vault reference is not detected: %s", uri)
+		}
+	}
+
+	t.Setenv("VAULT_TOKEN", "s.invalid")
+	if _, err := New(uriRegistry("vault://secret/registries/other"), apiProviderNoForceDelete).ToReal(); err == nil {
+		t.Error("vault permission error is not detected")
+	}
+}
+
+func TestRegistry_CredentialsCacheAndRefresh(t *testing.T) {
+	resetCredentialCache(t)
+	vault := &vaultStandIn{
+		token: "s.token",
+		data: map[string]interface{}{
+			"username": "vault-admin",
+			"password": "vault-password",
+		},
+	}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", vault.token)
+
+	apiReg := uriRegistry("vault://secret/registries/harbor")
+	for i := 0; i < 3; i++ {
+		reg := New(apiReg, apiProviderNoForceDelete)
+		if password := reg.GetPassword(); password != "vault-password" {
+			t.Errorf("unexpected password: %s", password)
+		}
+	}
+	if vault.requests != 1 {
+		t.Errorf("credentials are not cached, vault requests: %d", vault.requests)
+	}
+
+	reg := New(apiReg, apiProviderNoForceDelete)
+	refreshed, err := reg.RefreshCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed {
+		t.Error("unchanged credentials are reported as refreshed")
+	}
+
+	vault.data["password"] = "rotated-password"
+	if password := reg.GetPassword(); password != "vault-password" {
+		t.Errorf("cached password is not used: %s", password)
+	}
+	refreshed, err = reg.RefreshCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refreshed {
+		t.Error("rotated credentials are not reported as refreshed")
+	}
+	if password := reg.GetPassword(); password != "rotated-password" {
+		t.Errorf("refreshed password is not used: %s", password)
+	}
+	if password := New(apiReg, apiProviderNoForceDelete).GetPassword(); password != "rotated-password" {
+		t.Errorf("refreshed password is not cached: %s", password)
+	}
+}
@@ -57,6 +57,10 @@ func (proj *project) GetReplicationRules(ctx context.Context, trigger globalregi
 	switch proj.Spec.Type {
 	case api.GlobalProjectType:
 		for _, r := range proj.registry.apiProvider.GetRegistries(ctx) {
+			if api.IsBeingCleanedUp(r) {
+				// no replication to a registry being cleaned up
+				continue
+			}
 			remoteReg := New(r, proj.registry.apiProvider)
 			if proj.registry.GetName() != r.GetName() {
 				calcRepl := calculateReplicationRule(
@@ -34,7 +34,7 @@ func (r *Registry) GetProjectByName(ctx context.Context, name string) (globalreg
 	}
 	projects := r.apiProvider.GetProjects(ctx)
 	for _, proj := range projects {
-		if proj.GetName() == name {
+		if proj.GetName() == name && !api.IsBeingCleanedUp(proj) {
 			return &project{
 				Project:  proj,
 				registry: r,
@@ -44,10 +44,19 @@ func (r *Registry) GetProjectByName(ctx context.Context, name string) (globalreg
 	return nil, nil
 }
 
+// ListProjects returns the projects expected at the registry. The projects
+// being cleaned up are not expected anywhere, and no project is expected at a
+// registry being cleaned up.
 func (r *Registry) ListProjects(ctx context.Context) ([]globalregistry.Project, error) {
-	projects := r.apiProvider.GetProjects(ctx)
 	result := make([]globalregistry.Project, 0)
+	if api.IsBeingCleanedUp(r.apiRegistry) {
+		return result, nil
+	}
+	projects := r.apiProvider.GetProjects(ctx)
 	for _, proj := range projects {
+		if api.IsBeingCleanedUp(proj) {
+			continue
+		}
 		myProject := false
 	LRegLoop:
 		for _, lReg := range proj.Spec.LocalRegistries {
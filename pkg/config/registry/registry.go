@@ -21,6 +21,7 @@ package registry
 import (
 	"context"
 	"strconv"
+	"sync"
 
 	"github.com/go-logr/logr"
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
@@ -42,6 +43,11 @@ type ApiObjectProvider interface {
 type Registry struct {
 	apiProvider ApiObjectProvider
 	apiRegistry *api.Registry
+
+	credentialsMu       sync.Mutex
+	credentialsResolved bool
+	username            string
+	password            string
 }
 
 var _ globalregistry.Registry = &Registry{}
@@ -71,13 +77,27 @@ func (reg *Registry) GetAPIEndpoint() string {
 }
 
 // GetUsername method implements the globalregistry.RegistryConfig interface.
+// If the Registry references external credentials, the user name is resolved
+// via the API provider.
 func (reg *Registry) GetUsername() string {
-	return reg.apiRegistry.Spec.Username
+	username, _, err := reg.credentials()
+	if err != nil {
+		reg.apiProvider.GetLogger().Error(err, "cannot resolve registry credentials",
+			"registry", reg.GetName())
+	}
+	return username
 }
 
 // GetPassword method implements the globalregistry.RegistryConfig interface.
+// If the Registry references external credentials, the password is resolved
+// via the API provider.
 func (reg *Registry) GetPassword() string {
-	return reg.apiRegistry.Spec.Password
+	_, password, err := reg.credentials()
+	if err != nil {
+		reg.apiProvider.GetLogger().Error(err, "cannot resolve registry credentials",
+			"registry", reg.GetName())
+	}
+	return password
 }
 
 // GetAnnotations method implements the globalregistry.RegistryConfig interface.
@@ -85,6 +105,18 @@ func (reg *Registry) GetAnnotations() map[string]string {
 	return reg.apiRegistry.Annotations
 }
 
+// GetProjectDeletionPolicy returns the project deletion policy of the registry.
+// If it is not specified, nil is returned.
+func (reg *Registry) GetProjectDeletionPolicy() *api.ProjectDeletionPolicy {
+	return reg.apiRegistry.Spec.ProjectDeletion
+}
+
+// GetMemberManagement returns the member management mode of the registry. If it
+// is not specified, the empty string is returned.
+func (reg *Registry) GetMemberManagement() api.MemberManagementMode {
+	return reg.apiRegistry.Spec.MemberManagement
+}
+
 type registryOptions struct {
 	forceDelete bool
 }
@@ -123,8 +155,12 @@ func (reg *Registry) GetInsecureSkipTLSVerify() bool {
 }
 
 // ToReal method turns the (i.e. expected) Registry value into a
-// provider-specific (i.e. actual) registry value.
+// provider-specific (i.e. actual) registry value. An error is returned if the
+// credentials of the registry cannot be resolved.
 func (reg *Registry) ToReal() (globalregistry.Registry, error) {
+	if _, _, err := reg.credentials(); err != nil {
+		return nil, err
+	}
 	return globalregistry.New(reg.apiProvider.GetLogger(), reg)
 }
 
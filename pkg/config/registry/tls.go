@@ -0,0 +1,94 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const defaultCABundleKey = "ca.crt"
+
+var _ globalregistry.RegistryWithTLS = &Registry{}
+
+// readSecretKeys reads the given keys of the Secret referenced by the Registry.
+func (reg *Registry) readSecretKeys(name, namespace string, keys ...string) ([][]byte, error) {
+	secretReader, ok := reg.apiProvider.(SecretReader)
+	if !ok {
+		return nil, fmt.Errorf("secret references are not supported by the resource store")
+	}
+	if namespace == "" {
+		namespace = reg.apiRegistry.GetNamespace()
+	}
+	data, err := secretReader.GetSecretData(context.Background(), namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read secret %s/%s: %w", namespace, name, err)
+	}
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, found := data[key]
+		if !found {
+			return nil, fmt.Errorf("key %s not found in secret %s/%s", key, namespace, name)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// GetCABundle method implements the globalregistry.RegistryWithTLS interface.
+// The CA bundle is taken either from the spec or from the referenced Secret.
+func (reg *Registry) GetCABundle() ([]byte, error) {
+	tlsSpec := reg.apiRegistry.Spec.TLS
+	if tlsSpec == nil {
+		return nil, nil
+	}
+	if tlsSpec.CABundle != "" {
+		return []byte(tlsSpec.CABundle), nil
+	}
+	ref := tlsSpec.CABundleSecretRef
+	if ref == nil {
+		return nil, nil
+	}
+	key := ref.Key
+	if key == "" {
+		key = defaultCABundleKey
+	}
+	values, err := reg.readSecretKeys(ref.Name, ref.Namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// GetClientCertificate method implements the globalregistry.RegistryWithTLS
+// interface. The certificate and the key are read from the referenced
+// kubernetes.io/tls Secret.
+func (reg *Registry) GetClientCertificate() ([]byte, []byte, error) {
+	tlsSpec := reg.apiRegistry.Spec.TLS
+	if tlsSpec == nil || tlsSpec.ClientCertificateSecretRef == nil {
+		return nil, nil, nil
+	}
+	ref := tlsSpec.ClientCertificateSecretRef
+	values, err := reg.readSecretKeys(ref.Name, ref.Namespace, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values[0], values[1], nil
+}
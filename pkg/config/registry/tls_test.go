@@ -0,0 +1,202 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// generateClientCertificate returns a self-signed client certificate and key
+// in PEM format.
+func generateClientCertificate(t *testing.T) ([]byte, []byte, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "registryman"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		cert
+}
+
+func TestRegistry_TLSConfig(t *testing.T) {
+	clientCertPEM, clientKeyPEM, clientCert := generateClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+	serverCAPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	ap := &mockSecretApiProvider{
+		secrets: map[string]map[string][]byte{
+			"registryman/harbor-ca": {
+				"bundle.pem": serverCAPEM,
+			},
+			"registryman/harbor-client": {
+				"tls.crt": clientCertPEM,
+				"tls.key": clientKeyPEM,
+			},
+		},
+	}
+	newRegistry := func(tlsSpec *api.RegistryTLS) *Registry {
+		return New(&api.Registry{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "harbor",
+				Namespace: "registryman",
+			},
+			Spec: &api.RegistrySpec{
+				APIEndpoint: server.URL,
+				TLS:         tlsSpec,
+			},
+		}, ap)
+	}
+	get := func(reg *Registry) error {
+		tlsConfig, err := globalregistry.TLSConfig(reg)
+		if err != nil {
+			return err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	if err := get(newRegistry(nil)); err == nil {
+		t.Error("untrusted server certificate is accepted")
+	}
+	if err := get(newRegistry(&api.RegistryTLS{
+		CABundle: string(serverCAPEM),
+	})); err == nil {
+		t.Error("connection without client certificate is accepted")
+	}
+	if err := get(newRegistry(&api.RegistryTLS{
+		CABundle: string(serverCAPEM),
+		ClientCertificateSecretRef: &api.SecretReference{
+			Name: "harbor-client",
+		},
+	})); err != nil {
+		t.Errorf("mutual TLS with inline CA bundle failed: %v", err)
+	}
+	if err := get(newRegistry(&api.RegistryTLS{
+		CABundleSecretRef: &api.SecretKeyReference{
+			SecretReference: api.SecretReference{
+				Name: "harbor-ca",
+			},
+			Key: "bundle.pem",
+		},
+		ClientCertificateSecretRef: &api.SecretReference{
+			Name:      "harbor-client",
+			Namespace: "registryman",
+		},
+	})); err != nil {
+		t.Errorf("mutual TLS with CA bundle secret failed: %v", err)
+	}
+	if _, err := globalregistry.TLSConfig(newRegistry(&api.RegistryTLS{
+		CABundle: "invalid",
+	})); err == nil {
+		t.Error("invalid CA bundle is not detected")
+	}
+	if _, err := globalregistry.TLSConfig(newRegistry(&api.RegistryTLS{
+		CABundleSecretRef: &api.SecretKeyReference{
+			SecretReference: api.SecretReference{
+				Name: "harbor-ca",
+			},
+		},
+	})); err == nil {
+		t.Error("missing CA bundle key is not detected")
+	}
+}
+
+func TestReferencesTLSSecret(t *testing.T) {
+	reg := &api.Registry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "harbor",
+			Namespace: "registryman",
+		},
+		Spec: &api.RegistrySpec{
+			TLS: &api.RegistryTLS{
+				CABundleSecretRef: &api.SecretKeyReference{
+					SecretReference: api.SecretReference{
+						Name: "harbor-ca",
+					},
+				},
+				ClientCertificateSecretRef: &api.SecretReference{
+					Name:      "harbor-client",
+					Namespace: "certs",
+				},
+			},
+		},
+	}
+	if !ReferencesSecret(reg, "registryman", "harbor-ca") {
+		t.Error("CA bundle secret is not referenced")
+	}
+	if !ReferencesSecret(reg, "certs", "harbor-client") {
+		t.Error("client certificate secret is not referenced")
+	}
+	if ReferencesSecret(reg, "registryman", "harbor-client") {
+		t.Error("client certificate secret is referenced in wrong namespace")
+	}
+}
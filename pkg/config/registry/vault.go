@@ -0,0 +1,166 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultCredentialProvider reads the credentials from a HashiCorp Vault KV
+// secret engine. The address of the Vault server and the token are taken from
+// the standard VAULT_ADDR and VAULT_TOKEN environment variables. If
+// VAULT_CACERT is set, the server certificate is validated against the given
+// CA bundle.
+type vaultCredentialProvider struct {
+	client      *http.Client
+	address     string
+	token       string
+	mount       string
+	path        string
+	kvVersion   string
+	usernameKey string
+	passwordKey string
+}
+
+// newVaultCredentialProviderFromURI parses vault://<mount>/<path> references.
+// By default, the KV version 2 API is used, version 1 can be selected with
+// the kvVersion=1 query parameter.
+func newVaultCredentialProviderFromURI(ref *url.URL, _ ApiObjectProvider) (CredentialProvider, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return nil, fmt.Errorf("VAULT_ADDR environment variable is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN environment variable is not set")
+	}
+	provider := &vaultCredentialProvider{
+		address:     strings.TrimRight(address, "/"),
+		token:       token,
+		mount:       ref.Host,
+		path:        strings.Trim(ref.Path, "/"),
+		kvVersion:   ref.Query().Get("kvVersion"),
+		usernameKey: ref.Query().Get("usernameKey"),
+		passwordKey: ref.Query().Get("passwordKey"),
+	}
+	if provider.mount == "" || provider.path == "" {
+		return nil, fmt.Errorf("vault reference shall be in vault://<mount>/<path> format: %s", ref.Redacted())
+	}
+	switch provider.kvVersion {
+	case "":
+		provider.kvVersion = "2"
+	case "1", "2":
+	default:
+		return nil, fmt.Errorf("unsupported vault KV version: %s", provider.kvVersion)
+	}
+	if provider.usernameKey == "" {
+		provider.usernameKey = defaultSecretUsernameKey
+	}
+	if provider.passwordKey == "" {
+		provider.passwordKey = defaultSecretPasswordKey
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile := os.Getenv("VAULT_CACERT"); caFile != "" {
+		caBundle, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read VAULT_CACERT: %w", err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificate found in VAULT_CACERT %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	provider.client = &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+	return provider, nil
+}
+
+func (p *vaultCredentialProvider) secretURL() string {
+	if p.kvVersion == "1" {
+		return fmt.Sprintf("%s/v1/%s/%s", p.address, p.mount, p.path)
+	}
+	return fmt.Sprintf("%s/v1/%s/data/%s", p.address, p.mount, p.path)
+}
+
+type vaultKVResponse struct {
+	Data json.RawMessage `json:"data"`
+}
+
+type vaultKV2Data struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+func (p *vaultCredentialProvider) GetCredentials(ctx context.Context) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.secretURL(), nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot read vault secret %s/%s: %w", p.mount, p.path, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", "", fmt.Errorf("vault secret %s/%s not found", p.mount, p.path)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", "", fmt.Errorf("permission denied reading vault secret %s/%s", p.mount, p.path)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return "", "", fmt.Errorf("reading vault secret %s/%s failed with status code %d", p.mount, p.path, resp.StatusCode)
+	}
+	kvResp := &vaultKVResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(kvResp); err != nil {
+		return "", "", fmt.Errorf("cannot decode vault response: %w", err)
+	}
+	var data map[string]interface{}
+	if p.kvVersion == "1" {
+		err = json.Unmarshal(kvResp.Data, &data)
+	} else {
+		kv2Data := &vaultKV2Data{}
+		err = json.Unmarshal(kvResp.Data, kv2Data)
+		data = kv2Data.Data
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("cannot decode vault secret data: %w", err)
+	}
+	username, ok := data[p.usernameKey].(string)
+	if !ok {
+		return "", "", fmt.Errorf("key %s not found in vault secret %s/%s", p.usernameKey, p.mount, p.path)
+	}
+	password, ok := data[p.passwordKey].(string)
+	if !ok {
+		return "", "", fmt.Errorf("key %s not found in vault secret %s/%s", p.passwordKey, p.mount, p.path)
+	}
+	return username, password, nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
 )
 
 // ValidateConsistency performs all validations that require the full context,
@@ -38,6 +39,12 @@ func ValidateConsistency(aos ApiObjectStore) error {
 		return err
 	}
 
+	// Checking registry providers
+	err = checkRegistryProviders(registries)
+	if err != nil {
+		return err
+	}
+
 	// Checking Artifactory annotations
 	err = checkArtifactoryAnnotations(registries)
 	if err != nil {
@@ -73,6 +80,12 @@ func ValidateConsistency(aos ApiObjectStore) error {
 	if err != nil {
 		return err
 	}
+
+	// Checking registry credential references
+	err = checkRegistryCredentialsRefs(registries)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -95,6 +108,25 @@ func checkGlobalRegistryCount(registries []*api.Registry) error {
 	return nil
 }
 
+// checkRegistryProviders checks that the providers of the registries are
+// registered, either as built-in providers or by plugins.
+func checkRegistryProviders(registries []*api.Registry) error {
+	var err error
+	providers := map[string]bool{}
+	for _, provider := range globalregistry.RegisteredProviders() {
+		providers[provider] = true
+	}
+	for _, registry := range registries {
+		if !providers[registry.Spec.Provider] {
+			logger.V(-1).Info("Unknown registry provider",
+				"registry_name", registry.Name,
+				"provider", registry.Spec.Provider)
+			err = ErrValidationUnknownProvider
+		}
+	}
+	return err
+}
+
 // checkGlobalRegistryCount checks that there is 1 or 0 registry configured with
 // the type GlobalHub.
 func checkArtifactoryAnnotations(registries []*api.Registry) error {
@@ -244,3 +276,36 @@ func checkRegistryNameUniqueness(registries []*api.Registry) error {
 	}
 	return err
 }
+
+// checkRegistryCredentialsRefs checks that the credentialsRef of the registries
+// contain exactly one reference.
+func checkRegistryCredentialsRefs(registries []*api.Registry) error {
+	var err error
+	for _, registry := range registries {
+		credRef := registry.Spec.CredentialsRef
+		if credRef == nil {
+			continue
+		}
+		refCount := 0
+		if credRef.SecretRef != nil {
+			refCount++
+		}
+		if credRef.FileRef != nil {
+			refCount++
+		}
+		if credRef.EnvRef != nil {
+			refCount++
+		}
+		if credRef.URI != "" {
+			refCount++
+		}
+		if refCount != 1 {
+			logger.V(-1).Info("Registry credentialsRef shall contain exactly one reference",
+				"registry_name", registry.GetName(),
+				"references", refCount,
+			)
+			err = ErrValidationCredentialsRef
+		}
+	}
+	return err
+}
@@ -116,4 +116,24 @@ var _ = Describe("Validation", func() {
 			Expect(err).Should(MatchError(config.ErrValidationScannerNameReference))
 		})
 	})
+	Context("when a registry has multiple credential references", func() {
+		It("should error", func() {
+			testDir := fmt.Sprintf("%s/test_credentials_ref", testdataDir)
+			manifests, err := config.ReadLocalManifests(testDir, nil)
+			Expect(manifests).NotTo(BeNil())
+			Expect(err).To(Succeed())
+			err = config.ValidateConsistency(manifests)
+			Expect(err).Should(MatchError(config.ErrValidationCredentialsRef))
+		})
+	})
+	Context("when a registry has an unknown provider", func() {
+		It("should error", func() {
+			testDir := fmt.Sprintf("%s/test_unknown_provider", testdataDir)
+			manifests, err := config.ReadLocalManifests(testDir, nil)
+			Expect(manifests).NotTo(BeNil())
+			Expect(err).To(Succeed())
+			err = config.ValidateConsistency(manifests)
+			Expect(err).Should(MatchError(config.ErrValidationUnknownProvider))
+		})
+	})
 })
@@ -0,0 +1,131 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package globalregistry
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Cache is a snapshot of the registry API lookups. It memoises the results of
+// the lookups (projects, members, user groups, remote registries, etc.) for the
+// lifetime of a reconciliation, so that the actions of a reconciliation do not
+// fetch the same resources again and again. The provider implementations
+// invalidate the affected entries when they modify the registry.
+//
+// The Cache is safe for concurrent use. A nil *Cache is valid and disables
+// caching.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	hits    int
+	misses  int
+}
+
+type cacheEntry struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+type cacheContextKey struct{}
+
+// WithCache returns a copy of ctx that carries the cache. The provider
+// implementations use the cache of the context to memoise their lookups.
+func WithCache(ctx context.Context, cache *Cache) context.Context {
+	return context.WithValue(ctx, cacheContextKey{}, cache)
+}
+
+// CacheOf returns the cache carried by the context. If the context does not
+// carry a cache, nil is returned, which disables the caching.
+func CacheOf(ctx context.Context) *Cache {
+	cache, _ := ctx.Value(cacheContextKey{}).(*Cache)
+	return cache
+}
+
+// CacheKey returns the cache key of a lookup of the registry. The key parts
+// identify the looked up resource, e.g. "projects", "12", "members".
+func CacheKey(reg Registry, parts ...string) string {
+	return reg.GetProvider() + "|" + reg.GetAPIEndpoint() + "|" + strings.Join(parts, "/")
+}
+
+// Get returns the memoised value of the key. If the key is not present, fetch
+// is called and its result is memoised. Failed lookups are not memoised.
+// Concurrent lookups of the same key wait for the first one to finish.
+func (c *Cache) Get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return fetch()
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		c.hits++
+		c.mu.Unlock()
+		<-entry.done
+		return entry.value, entry.err
+	}
+	c.misses++
+	entry = &cacheEntry{
+		done: make(chan struct{}),
+	}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.value, entry.err = fetch()
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.value, entry.err
+}
+
+// Invalidate removes the key and the keys below it (i.e. the keys with the
+// prefix key + "/") from the cache.
+func (c *Cache) Invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Stats returns the number of cache hits and misses.
+func (c *Cache) Stats() (hits int, misses int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
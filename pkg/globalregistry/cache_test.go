@@ -0,0 +1,114 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package globalregistry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := cache.Get("harbor|url|projects", fetch); err != nil || v != 1 {
+			t.Errorf("unexpected cached value: %v, %v", v, err)
+		}
+	}
+	cache.Get("harbor|url|projects/1/members", fetch)
+	cache.Get("harbor|url|projectsX", fetch)
+	if calls != 3 {
+		t.Errorf("expected 3 lookups, got %d", calls)
+	}
+	cache.Invalidate("harbor|url|projects")
+	cache.Get("harbor|url|projects", fetch)
+	cache.Get("harbor|url|projects/1/members", fetch)
+	cache.Get("harbor|url|projectsX", fetch)
+	if calls != 5 {
+		t.Errorf("invalidation failed, expected 5 lookups, got %d", calls)
+	}
+	if hits, misses := cache.Stats(); hits != 3 || misses != 5 {
+		t.Errorf("unexpected stats: %d hits, %d misses", hits, misses)
+	}
+}
+
+func TestCacheDoesNotMemoiseErrors(t *testing.T) {
+	cache := NewCache()
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return nil, ErrTransient
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get("key", fetch); !errors.Is(err, ErrTransient) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("failed lookup is memoised")
+	}
+}
+
+func TestCacheConcurrentLookups(t *testing.T) {
+	cache := NewCache()
+	var mu sync.Mutex
+	calls := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Get("key", func() (interface{}, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				return nil, nil
+			})
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("expected 1 lookup, got %d", calls)
+	}
+}
+
+func TestCacheOf(t *testing.T) {
+	if CacheOf(context.Background()) != nil {
+		t.Error("cache found in empty context")
+	}
+	// the nil cache disables caching
+	calls := 0
+	for i := 0; i < 2; i++ {
+		CacheOf(context.Background()).Get("key", func() (interface{}, error) {
+			calls++
+			return nil, nil
+		})
+	}
+	if calls != 2 {
+		t.Errorf("nil cache memoises the lookups")
+	}
+	cache := NewCache()
+	if CacheOf(WithCache(context.Background(), cache)) != cache {
+		t.Error("cache is not carried by the context")
+	}
+}
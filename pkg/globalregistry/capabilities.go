@@ -0,0 +1,242 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package globalregistry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+)
+
+// Feature identifies a functionality that a registry provider may support.
+type Feature string
+
+const (
+	// FeatureProjects is the listing (read) and the creation (write) of
+	// projects.
+	FeatureProjects Feature = "projects"
+
+	// FeatureProjectDeletion is the deletion (write) of projects.
+	FeatureProjectDeletion Feature = "projectDeletion"
+
+	// FeatureProjectMembers is the listing (read) and the
+	// addition/removal (write) of project members.
+	FeatureProjectMembers Feature = "projectMembers"
+
+	// FeatureProjectScanners is the inspection (read) and the
+	// configuration (write) of project level vulnerability scanners.
+	FeatureProjectScanners Feature = "projectScanners"
+
+	// FeatureProjectReplicationRules is the listing (read) and the
+	// addition/removal (write) of project level replication rules.
+	FeatureProjectReplicationRules Feature = "projectReplicationRules"
+
+	// FeatureProjectStorageReport is the reporting (read) of the storage
+	// used by the projects.
+	FeatureProjectStorageReport Feature = "projectStorageReport"
+
+	// FeaturePullReplication is the replication (write) of repositories
+	// by pulling them from remote registries.
+	FeaturePullReplication Feature = "pullReplication"
+
+	// FeaturePushReplication is the replication (write) of repositories
+	// by pushing them to remote registries.
+	FeaturePushReplication Feature = "pushReplication"
+)
+
+// Features contains all the known features in the order they are presented to
+// the user.
+var Features = []Feature{
+	FeatureProjects,
+	FeatureProjectDeletion,
+	FeatureProjectMembers,
+	FeatureProjectScanners,
+	FeatureProjectReplicationRules,
+	FeatureProjectStorageReport,
+	FeaturePullReplication,
+	FeaturePushReplication,
+}
+
+// FeatureSupport describes how a registry provider supports a feature.
+type FeatureSupport struct {
+	// Read shows whether the state of the feature can be inspected.
+	Read bool `json:"read" yaml:"read"`
+
+	// Write shows whether the state of the feature can be manipulated.
+	Write bool `json:"write" yaml:"write"`
+
+	// MinVersion is the lowest registry version that supports the
+	// feature. Empty string means no constraint.
+	MinVersion string `json:"minVersion,omitempty" yaml:"minVersion,omitempty"`
+
+	// MaxVersion is the highest registry version that supports the
+	// feature. Empty string means no constraint.
+	MaxVersion string `json:"maxVersion,omitempty" yaml:"maxVersion,omitempty"`
+}
+
+// SupportsVersion returns whether the feature is supported by the given
+// registry version. If the version is unknown (empty string), the version
+// constraints are considered to be satisfied.
+func (fs FeatureSupport) SupportsVersion(version string) bool {
+	if version == "" {
+		return true
+	}
+	if fs.MinVersion != "" && CompareVersions(version, fs.MinVersion) < 0 {
+		return false
+	}
+	if fs.MaxVersion != "" && CompareVersions(version, fs.MaxVersion) > 0 {
+		return false
+	}
+	return true
+}
+
+// ProviderCapabilities is the capability descriptor of a registry provider. The
+// features that are missing from the map are not supported.
+type ProviderCapabilities map[Feature]FeatureSupport
+
+var _ ReplicationCapabilities = ProviderCapabilities{}
+
+// CanPull implements the ReplicationCapabilities interface.
+func (pc ProviderCapabilities) CanPull() bool {
+	return pc[FeaturePullReplication].Write
+}
+
+// CanPush implements the ReplicationCapabilities interface.
+func (pc ProviderCapabilities) CanPush() bool {
+	return pc[FeaturePushReplication].Write
+}
+
+// CanRead returns whether the feature can be inspected on a registry of the
+// given version.
+func (pc ProviderCapabilities) CanRead(feature Feature, version string) bool {
+	fs, found := pc[feature]
+	return found && fs.Read && fs.SupportsVersion(version)
+}
+
+// CanWrite returns whether the feature can be manipulated on a registry of the
+// given version.
+func (pc ProviderCapabilities) CanWrite(feature Feature, version string) bool {
+	fs, found := pc[feature]
+	return found && fs.Write && fs.SupportsVersion(version)
+}
+
+// RegistryCapabilities returns the capabilities of a registry of the given
+// version in the form used by the RegistryStatus.
+func (pc ProviderCapabilities) RegistryCapabilities(version string) api.RegistryCapabilities {
+	return api.RegistryCapabilities{
+		CanCreateProject:                     pc.CanWrite(FeatureProjects, version),
+		CanDeleteProject:                     pc.CanWrite(FeatureProjectDeletion, version),
+		CanPullReplicate:                     pc.CanWrite(FeaturePullReplication, version),
+		CanPushReplicate:                     pc.CanWrite(FeaturePushReplication, version),
+		CanManipulateProjectMembers:          pc.CanWrite(FeatureProjectMembers, version),
+		CanManipulateProjectScanners:         pc.CanWrite(FeatureProjectScanners, version),
+		CanManipulateProjectReplicationRules: pc.CanWrite(FeatureProjectReplicationRules, version),
+		HasProjectMembers:                    pc.CanRead(FeatureProjectMembers, version),
+		HasProjectScanners:                   pc.CanRead(FeatureProjectScanners, version),
+		HasProjectReplicationRules:           pc.CanRead(FeatureProjectReplicationRules, version),
+		HasProjectStorageReport:              pc.CanRead(FeatureProjectStorageReport, version),
+	}
+}
+
+// RegistryWithCapabilities interface is implemented by the provider specific
+// Registry implementations whose capabilities differ from the ones registered
+// for the provider, e.g. because the provider has several flavors.
+type RegistryWithCapabilities interface {
+	GetCapabilities() ProviderCapabilities
+}
+
+// RegistryWithVersion interface is implemented by the provider specific
+// Registry implementations that can detect the version of the registry.
+type RegistryWithVersion interface {
+	GetVersion(ctx context.Context) (string, error)
+}
+
+// GetCapabilities returns the capability descriptor of the registry. If the
+// registry implements the RegistryWithCapabilities interface, its own
+// descriptor is returned, otherwise the descriptor registered for the
+// provider.
+func GetCapabilities(reg Registry) (ProviderCapabilities, error) {
+	if regWithCapabilities, ok := reg.(RegistryWithCapabilities); ok {
+		return regWithCapabilities.GetCapabilities(), nil
+	}
+	return GetProviderCapabilities(reg.GetProvider())
+}
+
+// GetProviderCapabilities returns the capability descriptor registered for the
+// provider. If the provider is not registered, an empty descriptor and an
+// error wrapping ErrNotImplemented are returned.
+func GetProviderCapabilities(provider string) (ProviderCapabilities, error) {
+	capabilities, found := registeredCapabilities[provider]
+	if !found {
+		return ProviderCapabilities{}, fmt.Errorf("provider %s is not registered: %w", provider, ErrNotImplemented)
+	}
+	return capabilities, nil
+}
+
+// RegisteredProviders returns the names of the registered providers in
+// alphabetical order.
+func RegisteredProviders() []string {
+	providers := make([]string, 0, len(registeredRegistryCreators))
+	for provider := range registeredRegistryCreators {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// CompareVersions compares two dotted version strings (e.g. v2.4.1) and returns
+// -1, 0 or +1. The leading "v" and any pre-release or build suffix (e.g.
+// -rc1, +build) are ignored. Missing components are considered to be 0.
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for len(pa) < len(pb) {
+		pa = append(pa, 0)
+	}
+	for len(pb) < len(pa) {
+		pb = append(pb, 0)
+	}
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	fields := strings.Split(version, ".")
+	parts := make([]int, len(fields))
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts[i] = n
+	}
+	return parts
+}
@@ -0,0 +1,74 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package globalregistry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"2.0", "2.0.0", 0},
+		{"v2.4.1", "2.4", 1},
+		{"1.10", "2.0", -1},
+		{"2.10.0", "2.9.3", 1},
+		{"v2.5.0-rc1", "2.5", 0},
+	} {
+		if result := CompareVersions(tc.a, tc.b); result != tc.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tc.a, tc.b, result, tc.expected)
+		}
+	}
+}
+
+func TestProviderCapabilities(t *testing.T) {
+	pc := ProviderCapabilities{
+		FeatureProjects:        {Read: true, Write: true},
+		FeatureProjectMembers:  {Read: true, Write: true, MinVersion: "2.0"},
+		FeaturePullReplication: {Write: true, MaxVersion: "2.4"},
+	}
+	caps := pc.RegistryCapabilities("")
+	if !caps.CanCreateProject || !caps.CanManipulateProjectMembers || !caps.CanPullReplicate {
+		t.Errorf("supported features are missing: %+v", caps)
+	}
+	if caps.CanDeleteProject || caps.HasProjectScanners || caps.CanPushReplicate {
+		t.Errorf("unsupported features are present: %+v", caps)
+	}
+	caps = pc.RegistryCapabilities("1.10.3")
+	if caps.HasProjectMembers || !caps.CanPullReplicate {
+		t.Errorf("minimal version constraint is not applied: %+v", caps)
+	}
+	if pc.RegistryCapabilities("2.5.0").CanPullReplicate {
+		t.Error("maximal version constraint is not applied")
+	}
+}
+
+func TestUnregisteredProviderCapabilities(t *testing.T) {
+	capabilities, err := GetProviderCapabilities("unregistered")
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(capabilities) != 0 {
+		t.Errorf("unexpected capabilities: %v", capabilities)
+	}
+	if replication := GetReplicationCapability("unregistered"); replication.CanPull() || replication.CanPush() {
+		t.Error("unregistered provider can replicate")
+	}
+}
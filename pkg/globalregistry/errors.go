@@ -18,6 +18,7 @@ package globalregistry
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
@@ -29,11 +30,128 @@ var (
 	// not implemented by a registry provider.
 	ErrNotImplemented error = errors.New("not implemented")
 
-	// ErrAlreadyExists is an error value that indicates that the resource
-	// to be created exists already.
-	ErrAlreadyExists error = errors.New("already exists")
-
 	// ErrUnauthorized is an error value that indicates that the API call
 	// failed due to the API user is not authorized.
 	ErrUnauthorized error = errors.New("unauthorized")
+
+	// ErrForbidden is an error value that indicates that the API user is
+	// authenticated but it has no permission to perform the API call.
+	ErrForbidden error = errors.New("forbidden")
+
+	// ErrNotFound is an error value that indicates that the resource
+	// addressed by the API call does not exist.
+	ErrNotFound error = errors.New("not found")
+
+	// ErrConflict is an error value that indicates that the API call
+	// conflicts with the current state of the resource.
+	ErrConflict error = errors.New("conflict")
+
+	// ErrAlreadyExists is an error value that indicates that the resource
+	// to be created exists already. It is a kind of ErrConflict.
+	ErrAlreadyExists error = WithClass(errors.New("already exists"), ErrConflict)
+
+	// ErrRateLimited is an error value that indicates that the API call
+	// was rejected because the registry rate limit was exceeded.
+	ErrRateLimited error = errors.New("rate limited")
+
+	// ErrTransient is an error value that indicates a temporary failure
+	// (e.g. connection error, server error) which may disappear if the
+	// API call is repeated.
+	ErrTransient error = errors.New("transient error")
+
+	// ErrValidation is an error value that indicates that the registry
+	// rejected the API call because of invalid input.
+	ErrValidation error = errors.New("validation error")
+
+	// ErrUnsupportedVersion is an error value that indicates that the
+	// version of the registry is not supported by the provider, or that the
+	// requested operation is not available in that version.
+	ErrUnsupportedVersion error = errors.New("unsupported version")
 )
+
+// classifiedError wraps an error and classifies it as one of the error values
+// of the package.
+type classifiedError struct {
+	err   error
+	class error
+}
+
+// WithClass returns an error that wraps err and that is classified as class,
+// i.e. errors.Is returns true for err, class and the classes of class.
+func WithClass(err error, class error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{
+		err:   err,
+		class: class,
+	}
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return errors.Is(e.class, target)
+}
+
+// ProviderError is the error returned by the provider implementations. Besides
+// the wrapped error, it carries the name of the provider, the failed action and
+// the HTTP status code of the API response (if any).
+type ProviderError struct {
+	// Provider is the name of the registry provider, e.g. harbor.
+	Provider string
+
+	// Action describes the failed operation, e.g. "create project".
+	Action string
+
+	// StatusCode is the HTTP status code of the API response. It is 0 if
+	// the failure is not related to an API response.
+	StatusCode int
+
+	// Err is the wrapped error. It shall wrap one of the error values of
+	// the package, so that the error class can be checked with errors.Is.
+	Err error
+}
+
+var _ error = &ProviderError{}
+
+// WrapError returns a *ProviderError wrapping err. If err is nil, nil is
+// returned.
+func WrapError(provider, action string, statusCode int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ProviderError{
+		Provider:   provider,
+		Action:     action,
+		StatusCode: statusCode,
+		Err:        err,
+	}
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: %s failed with status code %d: %s", e.Provider, e.Action, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %s failed: %s", e.Provider, e.Action, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCodeOf returns the HTTP status code carried by the error. If err is not
+// (or does not wrap) a *ProviderError, 0 is returned.
+func StatusCodeOf(err error) int {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.StatusCode
+	}
+	return 0
+}
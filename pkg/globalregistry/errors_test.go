@@ -0,0 +1,38 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package globalregistry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithClass(t *testing.T) {
+	cause := errors.New("member exists")
+	err := WithClass(cause, ErrAlreadyExists)
+	for _, target := range []error{cause, ErrAlreadyExists, ErrConflict} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v, %v) is false", err, target)
+		}
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("error is classified as not found")
+	}
+	if WithClass(nil, ErrConflict) != nil {
+		t.Error("nil error is classified")
+	}
+}
@@ -6,6 +6,16 @@ type CanForceDelete interface {
 	ForceDeleteProjects() bool
 }
 
+// CanPause interface describes the options that pause the reconciliation of
+// registries and projects, e.g. during a maintenance of a registry.
+type CanPause interface {
+	// PausedRegistries returns the names of the paused registries.
+	PausedRegistries() []string
+
+	// PausedProjects returns the names of the paused projects.
+	PausedProjects() []string
+}
+
 // RegistryOptions interface describes the registry options
 // coming from CLI options, or from the registry description.
 type RegistryOptions interface {
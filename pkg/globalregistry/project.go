@@ -89,6 +89,16 @@ type MemberManipulatorProject interface {
 	UnassignMember(context.Context, ProjectMember) error
 }
 
+// MemberUpdaterProject interface contains the methods that we use for updating
+// the project members in place, i.e. without removing and re-adding them.
+type MemberUpdaterProject interface {
+	// UpdateMember changes the role of a member which is already assigned
+	// to the project. The credentials of robot members are left intact.
+	// ErrNotImplemented is returned when the role of the member cannot be
+	// changed in place.
+	UpdateMember(context.Context, ProjectMember) error
+}
+
 // ProjectWithScanner interface contains the methods that we use for
 // project-level scanner related read-only operations.
 type ProjectWithScanner interface {
@@ -119,6 +129,12 @@ type ProjectWithReplication interface {
 type ReplicationRuleManipulatorProject interface {
 	// AssignReplicationRule assigns a replication rule to the project.
 	AssignReplicationRule(ctx context.Context, remote Registry, trigger ReplicationTrigger, direction string) (ReplicationRule, error)
+
+	// UpdateReplicationRule changes the trigger of the replication rule of
+	// the project which replicates with the remote registry in the given
+	// direction, keeping the rule and its history. ErrNotImplemented is
+	// returned when the rule cannot be changed in place.
+	UpdateReplicationRule(ctx context.Context, remote Registry, trigger ReplicationTrigger, direction string) (ReplicationRule, error)
 }
 
 // ProjectWithStorage interface contains the methods that we use for
@@ -0,0 +1,110 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// ErrorHandling describes how the reconciliation shall proceed when an Action
+// fails.
+type ErrorHandling int
+
+const (
+	// AbortOnError means that the reconciliation of the registry shall be
+	// stopped.
+	AbortOnError ErrorHandling = iota
+
+	// SkipOnError means that the failed action shall be reported and the
+	// reconciliation can continue with the next action.
+	SkipOnError
+
+	// RetryOnError means that the reconciliation of the registry shall be
+	// stopped and it can be repeated later.
+	RetryOnError
+)
+
+func (eh ErrorHandling) String() string {
+	switch eh {
+	case AbortOnError:
+		return "abort"
+	case SkipOnError:
+		return "skip"
+	case RetryOnError:
+		return "retry"
+	}
+	return fmt.Sprintf("ErrorHandling(%d)", int(eh))
+}
+
+// ErrorHandlingOf returns how the reconciliation shall proceed based on the
+// class of the error.
+//
+// Rate limiting and transient failures are worth retrying later. Missing resources,
+// conflicts, invalid inputs, and unimplemented or version restricted features
+// affect only the failed action, so it is skipped. Authentication and
+// authorization failures, and any unclassified error abort the reconciliation
+// of the registry.
+func ErrorHandlingOf(err error) ErrorHandling {
+	switch {
+	case err == nil:
+		return SkipOnError
+	case errors.Is(err, globalregistry.ErrRateLimited),
+		errors.Is(err, globalregistry.ErrTransient):
+		return RetryOnError
+	case errors.Is(err, globalregistry.ErrRecoverableError),
+		errors.Is(err, globalregistry.ErrNotFound),
+		errors.Is(err, globalregistry.ErrConflict),
+		errors.Is(err, globalregistry.ErrValidation),
+		errors.Is(err, globalregistry.ErrNotImplemented),
+		errors.Is(err, globalregistry.ErrUnsupportedVersion):
+		return SkipOnError
+	}
+	return AbortOnError
+}
+
+// SideEffectOnFailure is implemented by the Actions that can fail after
+// having changed the registry partially. If SideEffectOnFailure returns true,
+// the SideEffect returned together with the error describes the partial change
+// and shall be performed nevertheless.
+type SideEffectOnFailure interface {
+	SideEffectOnFailure() bool
+}
+
+// PerformAction performs the action on the registry. The returned error is
+// annotated with the action description.
+//
+// The failed action is not repeated here: the API clients retry the failed
+// idempotent requests and the rate limited requests themselves, and the
+// reconciliation of a registry failing with a retriable error (see
+// ErrorHandlingOf) is repeated later as a whole.
+//
+// When the action fails, the returned SideEffect is nil unless the action
+// implements SideEffectOnFailure and reports that its SideEffect is valid.
+func PerformAction(ctx context.Context, action Action, reg globalregistry.Registry) (SideEffect, error) {
+	sideEffect, err := action.Perform(ctx, reg)
+	if err == nil {
+		return sideEffect, nil
+	}
+	if partial, ok := action.(SideEffectOnFailure); !ok || !partial.SideEffectOnFailure() {
+		sideEffect = nil
+	}
+	return sideEffect, fmt.Errorf("%s: %w", action, err)
+}
@@ -0,0 +1,127 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+)
+
+type failingAction struct {
+	errs   []error
+	effect reconciler.SideEffect
+	calls  int
+}
+
+func (fa *failingAction) String() string {
+	return "failing action"
+}
+
+func (fa *failingAction) Perform(context.Context, globalregistry.Registry) (reconciler.SideEffect, error) {
+	fa.calls++
+	if len(fa.errs) == 0 {
+		return nil, nil
+	}
+	err := fa.errs[0]
+	fa.errs = fa.errs[1:]
+	return fa.effect, err
+}
+
+type partialFailingAction struct {
+	failingAction
+}
+
+func (pfa *partialFailingAction) SideEffectOnFailure() bool {
+	return true
+}
+
+type noopSideEffect struct{}
+
+func (noopSideEffect) Perform(context.Context, reconciler.SideEffectPerformer) error {
+	return nil
+}
+
+var _ = Describe("ErrorHandling", func() {
+	It("classifies the provider errors", func() {
+		wrap := func(statusCode int, err error) error {
+			return globalregistry.WrapError("harbor", "create project", statusCode, err)
+		}
+		Expect(reconciler.ErrorHandlingOf(wrap(429, globalregistry.ErrRateLimited))).To(Equal(reconciler.RetryOnError))
+		Expect(reconciler.ErrorHandlingOf(wrap(503, globalregistry.ErrTransient))).To(Equal(reconciler.RetryOnError))
+		Expect(reconciler.ErrorHandlingOf(wrap(404, globalregistry.ErrNotFound))).To(Equal(reconciler.SkipOnError))
+		Expect(reconciler.ErrorHandlingOf(wrap(409, globalregistry.ErrAlreadyExists))).To(Equal(reconciler.SkipOnError))
+		Expect(reconciler.ErrorHandlingOf(wrap(400, globalregistry.ErrValidation))).To(Equal(reconciler.SkipOnError))
+		Expect(reconciler.ErrorHandlingOf(wrap(0, globalregistry.ErrRecoverableError))).To(Equal(reconciler.SkipOnError))
+		Expect(reconciler.ErrorHandlingOf(wrap(401, globalregistry.ErrUnauthorized))).To(Equal(reconciler.AbortOnError))
+		Expect(reconciler.ErrorHandlingOf(wrap(403, globalregistry.ErrForbidden))).To(Equal(reconciler.AbortOnError))
+		Expect(reconciler.ErrorHandlingOf(errors.New("unknown"))).To(Equal(reconciler.AbortOnError))
+	})
+
+	It("keeps the provider, action and status code", func() {
+		err := fmt.Errorf("adding member: %w",
+			globalregistry.WrapError("harbor", "add project member", 409,
+				fmt.Errorf("member exists: %w", globalregistry.ErrAlreadyExists)))
+		Expect(errors.Is(err, globalregistry.ErrAlreadyExists)).To(BeTrue())
+		Expect(errors.Is(err, globalregistry.ErrConflict)).To(BeTrue())
+		Expect(globalregistry.StatusCodeOf(err)).To(Equal(409))
+		var providerErr *globalregistry.ProviderError
+		Expect(errors.As(err, &providerErr)).To(BeTrue())
+		Expect(providerErr.Provider).To(Equal("harbor"))
+		Expect(providerErr.Action).To(Equal("add project member"))
+	})
+
+	It("does not repeat the failed actions", func() {
+		for _, actionErr := range []error{
+			globalregistry.ErrTransient,
+			globalregistry.ErrRateLimited,
+			globalregistry.ErrConflict,
+		} {
+			action := &failingAction{
+				errs: []error{actionErr},
+			}
+			_, err := reconciler.PerformAction(context.Background(), action, nil)
+			Expect(errors.Is(err, actionErr)).To(BeTrue())
+			Expect(action.calls).To(Equal(1))
+		}
+	})
+
+	It("discards the side effect of a failed action", func() {
+		action := &failingAction{
+			errs:   []error{globalregistry.ErrNotFound},
+			effect: noopSideEffect{},
+		}
+		sideEffect, err := reconciler.PerformAction(context.Background(), action, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(sideEffect).To(BeNil())
+
+		By("keeping the side effect of a partial change")
+		partial := &partialFailingAction{failingAction{
+			errs:   []error{globalregistry.ErrNotFound},
+			effect: noopSideEffect{},
+		}}
+		sideEffect, err = reconciler.PerformAction(context.Background(), partial, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(sideEffect).To(Equal(noopSideEffect{}))
+	})
+})
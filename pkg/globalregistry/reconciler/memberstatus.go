@@ -19,6 +19,7 @@ package reconciler
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 
 	"encoding/base64"
@@ -76,6 +77,7 @@ func (m *ldapStatus) GetDN() string {
 type memberAddAction struct {
 	api.MemberStatus
 	projectName string
+	ownership   *Ownership
 }
 
 var _ Action = &memberAddAction{}
@@ -148,14 +150,22 @@ func (ma *memberAddAction) Perform(ctx context.Context, reg globalregistry.Regis
 	if err != nil {
 		return nilEffect, err
 	}
+	effects := sideEffects{
+		&ownershipEffect{
+			ownership:   ma.ownership,
+			projectName: ma.projectName,
+			memberName:  ma.Name,
+			owned:       true,
+		},
+	}
 	if creds != nil {
-		return &persistMemberCredentials{
+		effects = append(effects, &persistMemberCredentials{
 			ProjectMemberCredentials: *creds,
 			action:                   ma,
 			registry:                 reg,
-		}, nil
+		})
 	}
-	return nilEffect, nil
+	return effects, nil
 }
 
 type removeMemberCredentials struct {
@@ -184,6 +194,7 @@ func (rmc *removeMemberCredentials) Perform(ctx context.Context, performer SideE
 type memberRemoveAction struct {
 	api.MemberStatus
 	projectName string
+	ownership   *Ownership
 }
 
 var _ Action = &memberRemoveAction{}
@@ -207,19 +218,113 @@ func (ma *memberRemoveAction) Perform(ctx context.Context, reg globalregistry.Re
 	if err != nil {
 		return nilEffect, err
 	}
+	effects := sideEffects{
+		&ownershipEffect{
+			ownership:   ma.ownership,
+			projectName: ma.projectName,
+			memberName:  ma.Name,
+			owned:       false,
+		},
+	}
 	if ma.Type == "Robot" {
-		return &removeMemberCredentials{
+		effects = append(effects, &removeMemberCredentials{
 			action:   ma,
 			registry: reg,
-		}, nil
+		})
+	}
+	return effects, nil
+}
+
+type memberRoleUpdateAction struct {
+	api.MemberStatus
+	projectName string
+	ownership   *Ownership
+}
+
+var _ Action = &memberRoleUpdateAction{}
+var _ SideEffectOnFailure = &memberRoleUpdateAction{}
+
+func (ma *memberRoleUpdateAction) String() string {
+	return fmt.Sprintf("updating role of member %s in %s to %s",
+		ma.Name, ma.projectName, ma.Role)
+}
+
+// SideEffectOnFailure returns true: if adding the member with the new role
+// fails, the member has been removed already, so the side effect of the
+// removal is returned together with the error.
+func (ma *memberRoleUpdateAction) SideEffectOnFailure() bool {
+	return true
+}
+
+// Perform changes the role of the member in place. When the project cannot
+// update its members, the member is removed and added again with the new
+// role. The member keeps its ownership, as it is not created by the update.
+func (ma *memberRoleUpdateAction) Perform(ctx context.Context, reg globalregistry.Registry) (SideEffect, error) {
+	project, err := reg.(globalregistry.RegistryWithProjects).GetProjectByName(ctx, ma.projectName)
+	if err != nil {
+		return nilEffect, err
+	}
+	if project == nil {
+		// project not found
+		return nilEffect, fmt.Errorf("project %s not found", ma.projectName)
+	}
+	if memberUpdaterProject, ok := project.(globalregistry.MemberUpdaterProject); ok {
+		err = memberUpdaterProject.UpdateMember(ctx, toProjectMember(&ma.MemberStatus))
+		if !errors.Is(err, globalregistry.ErrNotImplemented) {
+			return nilEffect, err
+		}
+	}
+	owned := ma.ownership.ownsMember(ma.projectName, ma.Name)
+	removeEffect, err := (&memberRemoveAction{
+		MemberStatus: ma.MemberStatus,
+		projectName:  ma.projectName,
+		ownership:    ma.ownership,
+	}).Perform(ctx, reg)
+	if err != nil {
+		return nilEffect, err
+	}
+	addEffect, err := (&memberAddAction{
+		MemberStatus: ma.MemberStatus,
+		projectName:  ma.projectName,
+		ownership:    ma.ownership,
+	}).Perform(ctx, reg)
+	if err != nil {
+		return removeEffect, err
 	}
-	return nilEffect, nil
+	return sideEffects{
+		withoutOwnershipEffects(removeEffect),
+		withoutOwnershipEffects(addEffect),
+		&ownershipEffect{
+			ownership:   ma.ownership,
+			projectName: ma.projectName,
+			memberName:  ma.Name,
+			owned:       owned,
+		},
+	}, nil
+}
+
+// sameMember returns whether the member statuses describe the same member,
+// regardless of its role.
+func sameMember(a, b api.MemberStatus) bool {
+	return a.Name == b.Name && a.Type == b.Type && a.DN == b.DN
 }
 
 // CompareMemberStatuses compares the actual and expected status of the members
 // of a project. The function returns the actions that are needed to synchronize
 // the actual state to the expected state.
+//
+// Every actual member which is not expected is removed. The members whose
+// role differs only are updated in place. To respect the member
+// management mode of the project, filter the actual members with
+// Ownership.ManagedMembers.
 func CompareMemberStatuses(projectName string, actual, expected []api.MemberStatus, regCapabilities api.RegistryCapabilities) []Action {
+	return compareMemberStatuses(projectName, actual, expected, regCapabilities, nil)
+}
+
+// compareMemberStatuses compares the members managed according to the
+// ownership and records the added and the removed members in it.
+func compareMemberStatuses(projectName string, actual, expected []api.MemberStatus, regCapabilities api.RegistryCapabilities, ownership *Ownership) []Action {
+	actual = ownership.ManagedMembers(projectName, actual, expected)
 	actualDiff := []api.MemberStatus{}
 	expectedDiff := []api.MemberStatus{}
 ActLoop:
@@ -245,19 +350,44 @@ ExpLoop:
 	actions := make([]Action, 0)
 
 	if regCapabilities.CanManipulateProjectMembers {
+		// the members found in both diffs differ only in their role,
+		// so their role is updated instead of re-adding them
+		roleDiff := []api.MemberStatus{}
+		removedDiff := []api.MemberStatus{}
+	RoleLoop:
+		for _, act := range actualDiff {
+			for i, exp := range expectedDiff {
+				if sameMember(act, exp) {
+					roleDiff = append(roleDiff, exp)
+					expectedDiff = append(expectedDiff[:i:i], expectedDiff[i+1:]...)
+					continue RoleLoop
+				}
+			}
+			removedDiff = append(removedDiff, act)
+		}
+		actualDiff = removedDiff
+		for _, exp := range roleDiff {
+			actions = append(actions, &memberRoleUpdateAction{
+				MemberStatus: exp,
+				projectName:  projectName,
+				ownership:    ownership,
+			})
+		}
 		// actualDiff contains the members which are there but are not needed
 		for _, act := range actualDiff {
 			actions = append(actions, &memberRemoveAction{
-				act,
-				projectName,
+				MemberStatus: act,
+				projectName:  projectName,
+				ownership:    ownership,
 			})
 		}
 		// expectedClone contains the members which are missing and thus they
 		// shall be created
 		for _, exp := range expectedDiff {
 			actions = append(actions, &memberAddAction{
-				exp,
-				projectName,
+				MemberStatus: exp,
+				projectName:  projectName,
+				ownership:    ownership,
 			})
 		}
 	}
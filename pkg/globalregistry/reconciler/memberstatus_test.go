@@ -35,6 +35,11 @@ var (
 		Type: "otherType",
 		Role: "role",
 	}
+	alphaAdmin = api.MemberStatus{
+		Name: "alpha",
+		Type: "type",
+		Role: "admin",
+	}
 	beta = api.MemberStatus{
 		Name: "beta",
 		Type: "type",
@@ -164,4 +169,40 @@ var _ = Describe("Memberstatus", func() {
 			"adding member alpha to proj",
 		}))
 	})
+
+	It("updates the role of members in place", func() {
+		act := []api.MemberStatus{
+			alpha,
+			beta,
+		}
+		exp := []api.MemberStatus{
+			alphaAdmin,
+			beta,
+		}
+		actions := reconciler.CompareMemberStatuses("proj", act, exp, api.RegistryCapabilities{
+			CanManipulateProjectMembers: true,
+		})
+		Expect(actionsToStrings(actions)).To(Equal([]string{
+			"updating role of member alpha in proj to admin",
+		}))
+
+		act = []api.MemberStatus{
+			alphaAdmin,
+		}
+		exp = []api.MemberStatus{
+			alphaPrime,
+			beta,
+		}
+		actions = reconciler.CompareMemberStatuses("proj", act, exp, api.RegistryCapabilities{
+			CanManipulateProjectMembers: true,
+		})
+		Expect(actionsToStrings(actions)).To(Equal([]string{
+			"removing member alpha from proj",
+			"adding member alpha to proj",
+			"adding member beta to proj",
+		}))
+
+		actions = reconciler.CompareMemberStatuses("proj", []api.MemberStatus{alpha}, []api.MemberStatus{alphaAdmin}, api.RegistryCapabilities{})
+		Expect(actions).To(BeEmpty())
+	})
 })
@@ -0,0 +1,454 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OwnershipReader interface is implemented by the stores that can read back
+// the ownership records written by the reconciler.
+type OwnershipReader interface {
+	// GetConfigMapData returns the data of the ConfigMap with the given
+	// name.
+	GetConfigMapData(ctx context.Context, name string) (map[string]string, error)
+}
+
+// The keys of the ownership record. The owned projects are listed by name, the
+// owned members as <project>/<member> lines.
+const (
+	ownedProjectsKey = "projects"
+	ownedMembersKey  = "members"
+)
+
+// Ownership records the projects and the project members of a registry that
+// have been created by registryman. Together with the project deletion policy
+// and the member management modes, it decides which of the undeclared projects
+// and members can be removed.
+//
+// The record is persisted as a ConfigMap, written by the side effects of the
+// project and member actions.
+type Ownership struct {
+	registryName string
+	reader       OwnershipReader
+	policy       api.ProjectDeletionPolicy
+	memberMode   api.MemberManagementMode
+	memberModes  map[string]api.MemberManagementMode
+
+	mu       sync.Mutex
+	projects map[string]bool
+	members  map[string]bool
+	// unseeded is true if the record could be read but it did not exist
+	unseeded bool
+}
+
+// ownershipLocks serialise the updates of the ownership record of each
+// registry. The record of a registry is updated by the resync of the registry
+// and by the removal of the Project resources, which may be handled by the
+// resync of another registry.
+var (
+	ownershipLocksMu sync.Mutex
+	ownershipLocks   = map[string]*sync.Mutex{}
+)
+
+// lockOwnershipRecord locks the ownership record of the registry and returns
+// the function unlocking it.
+func lockOwnershipRecord(registryName string) func() {
+	ownershipLocksMu.Lock()
+	lock, ok := ownershipLocks[registryName]
+	if !ok {
+		lock = &sync.Mutex{}
+		ownershipLocks[registryName] = lock
+	}
+	ownershipLocksMu.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+// isRecordNotFound returns true if err reports a missing ownership record.
+func isRecordNotFound(err error) bool {
+	return apierrors.IsNotFound(err) || errors.Is(err, fs.ErrNotExist)
+}
+
+// ownershipRecordName returns the name of the ConfigMap storing the ownership
+// record of the registry.
+func ownershipRecordName(registryName string) string {
+	return fmt.Sprintf("%s---ownership", registryName)
+}
+
+// memberKey returns the key of a member in the ownership record.
+func memberKey(projectName, memberName string) string {
+	return projectName + "/" + memberName
+}
+
+// GetOwnership reads the ownership record of the registry. If reader is nil
+// (i.e. the store cannot read ownership records) or the record does not exist
+// yet, an empty record is returned. In the latter case the record shall be
+// seeded (see Seed) before it is used. A nil policy means the DeleteOwned
+// policy.
+func GetOwnership(ctx context.Context, reader OwnershipReader, registryName string, policy *api.ProjectDeletionPolicy) (*Ownership, error) {
+	ownership := &Ownership{
+		registryName: registryName,
+		reader:       reader,
+		projects:     map[string]bool{},
+		members:      map[string]bool{},
+	}
+	if policy != nil {
+		ownership.policy = *policy
+	}
+	if reader == nil {
+		return ownership, nil
+	}
+	data, err := reader.GetConfigMapData(ctx, ownershipRecordName(registryName))
+	if isRecordNotFound(err) {
+		ownership.unseeded = true
+		return ownership, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ownership record of registry %s: %w", registryName, err)
+	}
+	ownership.load(data)
+	return ownership, nil
+}
+
+// load replaces the record with the data of the persisted record.
+func (o *Ownership) load(data map[string]string) {
+	o.projects = map[string]bool{}
+	o.members = map[string]bool{}
+	for _, name := range strings.Split(data[ownedProjectsKey], "\n") {
+		if name != "" {
+			o.projects[name] = true
+		}
+	}
+	for _, key := range strings.Split(data[ownedMembersKey], "\n") {
+		if key != "" {
+			o.members[key] = true
+		}
+	}
+	o.unseeded = false
+}
+
+// update reads back the persisted record, if it can be read, applies mutate to
+// it and persists the result unless mutate reports no change or performer is
+// nil. The updates of the record of a registry are serialised, so that the
+// concurrent updates do not overwrite each other's changes.
+func (o *Ownership) update(ctx context.Context, performer SideEffectPerformer, mutate func() bool) error {
+	unlock := lockOwnershipRecord(o.registryName)
+	defer unlock()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.reader != nil {
+		data, err := o.reader.GetConfigMapData(ctx, ownershipRecordName(o.registryName))
+		switch {
+		case err == nil:
+			o.load(data)
+		case !isRecordNotFound(err):
+			return fmt.Errorf("reading ownership record of registry %s: %w", o.registryName, err)
+		}
+	}
+	if !mutate() || performer == nil {
+		return nil
+	}
+	return performer.WriteResource(ctx, o.record())
+}
+
+// NeedsSeeding returns true if the ownership record does not exist yet, i.e.
+// registryman has not recorded the projects it has created.
+func (o *Ownership) NeedsSeeding() bool {
+	if o == nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.unseeded
+}
+
+// Seed creates the missing ownership record. The earlier versions of
+// registryman did not record the projects they created, so the given projects
+// (the actual projects described by a Project resource) are recorded as owned:
+// they are removed when their Project resource is deleted, like before the
+// upgrade. The record is persisted unless performer is nil. If the record
+// exists, Seed does nothing.
+func (o *Ownership) Seed(ctx context.Context, performer SideEffectPerformer, projectNames []string) error {
+	if o == nil {
+		return nil
+	}
+	return o.update(ctx, performer, func() bool {
+		if !o.unseeded {
+			return false
+		}
+		for _, projectName := range projectNames {
+			o.projects[projectName] = true
+		}
+		o.unseeded = false
+		return true
+	})
+}
+
+// WithMemberManagement sets the member management mode of the registry and the
+// modes of the projects that override it. The empty mode means
+// AuthoritativeMembers.
+func (o *Ownership) WithMemberManagement(registryMode api.MemberManagementMode, projectModes map[string]api.MemberManagementMode) *Ownership {
+	if o != nil {
+		o.memberMode = registryMode
+		o.memberModes = projectModes
+	}
+	return o
+}
+
+// memberManagementOf returns the member management mode of the project.
+func (o *Ownership) memberManagementOf(projectName string) api.MemberManagementMode {
+	if mode := o.memberModes[projectName]; mode != "" {
+		return mode
+	}
+	return o.memberMode
+}
+
+// Owns returns true if the project has been created by registryman.
+func (o *Ownership) Owns(projectName string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.projects[projectName]
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRemoveProject returns true if the project can be removed from the
+// registry when it is not described by a Project resource. A nil Ownership
+// allows the removal of every project.
+func (o *Ownership) CanRemoveProject(projectName string) bool {
+	if o == nil {
+		return true
+	}
+	if matchesAny(o.policy.Exclude, projectName) {
+		return false
+	}
+	if len(o.policy.Include) > 0 && !matchesAny(o.policy.Include, projectName) {
+		return false
+	}
+	switch o.policy.Policy {
+	case api.DeleteAll:
+		return true
+	case api.NeverDelete:
+		return false
+	default:
+		return o.Owns(projectName)
+	}
+}
+
+// managedProjects returns the actual projects which are either expected or can
+// be removed. The other projects are left untouched by the reconciliation.
+func (o *Ownership) managedProjects(actual, expected []api.ProjectStatus) []api.ProjectStatus {
+	if o == nil {
+		return actual
+	}
+	declared := make(map[string]bool, len(expected))
+	for _, exp := range expected {
+		declared[exp.Name] = true
+	}
+	managed := []api.ProjectStatus{}
+	for _, act := range actual {
+		if declared[act.Name] || o.CanRemoveProject(act.Name) {
+			managed = append(managed, act)
+		}
+	}
+	return managed
+}
+
+// ManagedMembers returns the actual members of the project which are either
+// expected or can be removed according to the member management mode of the
+// project. The other members are left untouched by the reconciliation. A nil
+// Ownership manages every member.
+func (o *Ownership) ManagedMembers(projectName string, actual, expected []api.MemberStatus) []api.MemberStatus {
+	if o == nil {
+		return actual
+	}
+	mode := o.memberManagementOf(projectName)
+	if mode == "" || mode == api.AuthoritativeMembers {
+		return actual
+	}
+	declared := make(map[string]bool, len(expected))
+	for _, exp := range expected {
+		declared[exp.Name] = true
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	managed := []api.MemberStatus{}
+	for _, act := range actual {
+		if declared[act.Name] ||
+			mode == api.OwnedMembers && o.members[memberKey(projectName, act.Name)] {
+			managed = append(managed, act)
+		}
+	}
+	return managed
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// record returns the ConfigMap persisting the ownership record.
+func (o *Ownership) record() *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		Data: map[string]string{
+			ownedProjectsKey: strings.Join(sortedKeys(o.projects), "\n"),
+			ownedMembersKey:  strings.Join(sortedKeys(o.members), "\n"),
+		},
+	}
+	configMap.SetName(ownershipRecordName(o.registryName))
+	configMap.SetAnnotations(map[string]string{
+		"globalregistry.org/registry-name": o.registryName,
+	})
+	return configMap
+}
+
+// setOwned records whether the project is owned by registryman and persists
+// the record if it has changed. The members of a removed project are dropped
+// from the record, too.
+func (o *Ownership) setOwned(ctx context.Context, performer SideEffectPerformer, projectName string, owned bool) error {
+	if o == nil {
+		return nil
+	}
+	return o.update(ctx, performer, func() bool {
+		changed := o.projects[projectName] != owned
+		if owned {
+			o.projects[projectName] = true
+		} else {
+			delete(o.projects, projectName)
+			for key := range o.members {
+				if strings.HasPrefix(key, projectName+"/") {
+					delete(o.members, key)
+					changed = true
+				}
+			}
+		}
+		return changed
+	})
+}
+
+// ownsMember returns true if the project member has been added by registryman.
+func (o *Ownership) ownsMember(projectName, memberName string) bool {
+	if o == nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.members[memberKey(projectName, memberName)]
+}
+
+// setMemberOwned records whether the project member has been added by
+// registryman and persists the record if it has changed.
+func (o *Ownership) setMemberOwned(ctx context.Context, performer SideEffectPerformer, projectName, memberName string, owned bool) error {
+	if o == nil {
+		return nil
+	}
+	key := memberKey(projectName, memberName)
+	return o.update(ctx, performer, func() bool {
+		if o.members[key] == owned {
+			return false
+		}
+		if owned {
+			o.members[key] = true
+		} else {
+			delete(o.members, key)
+		}
+		return true
+	})
+}
+
+// Disown removes the project from the ownership record, so that it is left in
+// place when it is not described by a Project resource any more (e.g. when the
+// Project resource is deleted with the orphan annotation).
+func (o *Ownership) Disown(ctx context.Context, performer SideEffectPerformer, projectName string) error {
+	return o.setOwned(ctx, performer, projectName, false)
+}
+
+// ownershipEffect records the creation or the removal of a project or, if
+// memberName is set, of a project member in the ownership record.
+type ownershipEffect struct {
+	ownership   *Ownership
+	projectName string
+	memberName  string
+	owned       bool
+}
+
+var _ SideEffect = &ownershipEffect{}
+
+func (oe *ownershipEffect) Perform(ctx context.Context, performer SideEffectPerformer) error {
+	if oe.memberName != "" {
+		return oe.ownership.setMemberOwned(ctx, performer, oe.projectName, oe.memberName, oe.owned)
+	}
+	return oe.ownership.setOwned(ctx, performer, oe.projectName, oe.owned)
+}
+
+// sideEffects performs several side effects in order.
+type sideEffects []SideEffect
+
+// withoutOwnershipEffects returns the side effect without the updates of the
+// ownership record.
+func withoutOwnershipEffects(sideEffect SideEffect) SideEffect {
+	switch se := sideEffect.(type) {
+	case *ownershipEffect:
+		return nilEffect
+	case sideEffects:
+		result := make(sideEffects, len(se))
+		for i, effect := range se {
+			result[i] = withoutOwnershipEffects(effect)
+		}
+		return result
+	}
+	return sideEffect
+}
+
+var _ SideEffect = sideEffects{}
+
+func (se sideEffects) Perform(ctx context.Context, performer SideEffectPerformer) error {
+	for _, sideEffect := range se {
+		if err := sideEffect.Perform(ctx, performer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ownershipStore keeps the written ConfigMaps in memory.
+type ownershipStore struct {
+	configMaps map[string]map[string]string
+}
+
+var _ reconciler.SideEffectPerformer = &ownershipStore{}
+var _ reconciler.OwnershipReader = &ownershipStore{}
+
+func (s *ownershipStore) WriteResource(_ context.Context, obj runtime.Object) error {
+	configMap := obj.(*corev1.ConfigMap)
+	s.configMaps[configMap.GetName()] = configMap.Data
+	return nil
+}
+
+func (s *ownershipStore) RemoveResource(_ context.Context, obj runtime.Object) error {
+	delete(s.configMaps, obj.(*corev1.ConfigMap).GetName())
+	return nil
+}
+
+func (s *ownershipStore) GetConfigMapData(_ context.Context, name string) (map[string]string, error) {
+	data, ok := s.configMaps[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+	return data, nil
+}
+
+// memberRegistry is a registry whose projects can add and remove members, but
+// cannot update them in place.
+type memberRegistry struct {
+	globalregistry.Registry
+	assignErr error
+}
+
+func (r *memberRegistry) ListProjects(context.Context) ([]globalregistry.Project, error) {
+	return nil, nil
+}
+
+func (r *memberRegistry) GetProjectByName(_ context.Context, name string) (globalregistry.Project, error) {
+	return &memberProject{registry: r, name: name}, nil
+}
+
+type memberProject struct {
+	registry *memberRegistry
+	name     string
+}
+
+var _ globalregistry.MemberManipulatorProject = &memberProject{}
+
+func (p *memberProject) GetName() string {
+	return p.name
+}
+
+func (p *memberProject) AssignMember(context.Context, globalregistry.ProjectMember) (*globalregistry.ProjectMemberCredentials, error) {
+	return nil, p.registry.assignErr
+}
+
+func (p *memberProject) UnassignMember(context.Context, globalregistry.ProjectMember) error {
+	return nil
+}
+
+func actionStrings(actions []reconciler.Action) []string {
+	result := make([]string, len(actions))
+	for i, action := range actions {
+		result[i] = action.String()
+	}
+	return result
+}
+
+var _ = Describe("Ownership", func() {
+	var store *ownershipStore
+
+	BeforeEach(func() {
+		store = &ownershipStore{
+			configMaps: map[string]map[string]string{
+				"harbor---ownership": {
+					"projects": "proj1\napp-1",
+					"members":  "proj1/alpha\napp-1/alpha",
+				},
+			},
+		}
+	})
+
+	It("returns an empty record if there is no record", func() {
+		ownership, err := reconciler.GetOwnership(context.Background(), store, "other", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownership.Owns("proj1")).To(BeFalse())
+		Expect(ownership.CanRemoveProject("proj1")).To(BeFalse())
+		Expect(ownership.NeedsSeeding()).To(BeTrue())
+	})
+
+	It("seeds the missing record", func() {
+		ctx := context.Background()
+		ownership, err := reconciler.GetOwnership(ctx, store, "other", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownership.Seed(ctx, store, []string{"proj1", "app-1"})).To(Succeed())
+		Expect(ownership.NeedsSeeding()).To(BeFalse())
+		Expect(ownership.CanRemoveProject("proj1")).To(BeTrue())
+		Expect(store.configMaps["other---ownership"]).To(Equal(map[string]string{
+			"projects": "app-1\nproj1",
+			"members":  "",
+		}))
+
+		By("leaving the existing record intact")
+		ownership, err = reconciler.GetOwnership(ctx, store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownership.NeedsSeeding()).To(BeFalse())
+		Expect(ownership.Seed(ctx, store, []string{"proj2"})).To(Succeed())
+		Expect(ownership.Owns("proj2")).To(BeFalse())
+		Expect(store.configMaps["harbor---ownership"]["projects"]).To(Equal("proj1\napp-1"))
+	})
+
+	It("applies the project deletion policy", func() {
+		ctx := context.Background()
+		ownership, err := reconciler.GetOwnership(ctx, store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownership.CanRemoveProject("proj1")).To(BeTrue())
+		Expect(ownership.CanRemoveProject("proj2")).To(BeFalse())
+
+		ownership, err = reconciler.GetOwnership(ctx, store, "harbor", &api.ProjectDeletionPolicy{
+			Policy: api.NeverDelete,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownership.CanRemoveProject("proj1")).To(BeFalse())
+
+		ownership, err = reconciler.GetOwnership(ctx, store, "harbor", &api.ProjectDeletionPolicy{
+			Policy:  api.DeleteAll,
+			Include: []string{"proj*", "app-*"},
+			Exclude: []string{"proj2"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownership.CanRemoveProject("proj1")).To(BeTrue())
+		Expect(ownership.CanRemoveProject("proj2")).To(BeFalse())
+		Expect(ownership.CanRemoveProject("app-2")).To(BeTrue())
+		Expect(ownership.CanRemoveProject("library")).To(BeFalse())
+	})
+
+	It("removes only the owned projects", func() {
+		ownership, err := reconciler.GetOwnership(context.Background(), store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		actions := reconciler.Compare(nil,
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{proj1, proj2},
+				Capabilities: api.RegistryCapabilities{
+					CanDeleteProject: true,
+				},
+			},
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{},
+			},
+			ownership)
+		Expect(actionStrings(actions)).To(Equal([]string{"removing project proj1"}))
+
+		actions = reconciler.Compare(nil,
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{proj1, proj2},
+				Capabilities: api.RegistryCapabilities{
+					CanDeleteProject: true,
+				},
+			},
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{},
+			},
+			nil)
+		Expect(actionStrings(actions)).To(ConsistOf("removing project proj1", "removing project proj2"))
+	})
+
+	It("persists the disowned projects", func() {
+		ctx := context.Background()
+		ownership, err := reconciler.GetOwnership(ctx, store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownership.Disown(ctx, store, "proj1")).To(Succeed())
+		Expect(ownership.Owns("proj1")).To(BeFalse())
+		Expect(store.configMaps["harbor---ownership"]).To(Equal(map[string]string{
+			"projects": "app-1",
+			"members":  "app-1/alpha",
+		}))
+	})
+
+	It("keeps the changes of the other readers of the record", func() {
+		ctx := context.Background()
+		first, err := reconciler.GetOwnership(ctx, store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		second, err := reconciler.GetOwnership(ctx, store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.Disown(ctx, store, "proj1")).To(Succeed())
+		Expect(second.Disown(ctx, store, "app-1")).To(Succeed())
+		Expect(second.Owns("proj1")).To(BeFalse())
+		Expect(store.configMaps["harbor---ownership"]).To(Equal(map[string]string{
+			"projects": "",
+			"members":  "",
+		}))
+	})
+
+	It("leaves the members in place according to the member management mode", func() {
+		ownership, err := reconciler.GetOwnership(context.Background(), store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		admin := api.MemberStatus{Name: "admin", Type: "User", Role: "admin"}
+		alpha := api.MemberStatus{Name: "alpha", Type: "User", Role: "Developer"}
+		beta := api.MemberStatus{Name: "beta", Type: "User", Role: "Developer"}
+		actual := []api.MemberStatus{admin, alpha, beta}
+		expected := []api.MemberStatus{admin}
+
+		Expect(ownership.ManagedMembers("proj1", actual, expected)).To(Equal(actual))
+		ownership.WithMemberManagement(api.AdditiveMembers, map[string]api.MemberManagementMode{
+			"app-1": api.OwnedMembers,
+		})
+		Expect(ownership.ManagedMembers("proj1", actual, expected)).To(Equal([]api.MemberStatus{admin}))
+		Expect(ownership.ManagedMembers("app-1", actual, expected)).To(Equal([]api.MemberStatus{admin, alpha}))
+
+		actions := reconciler.Compare(nil,
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{
+					{Name: "proj1", Members: actual},
+					{Name: "app-1", Members: actual},
+				},
+				Capabilities: api.RegistryCapabilities{
+					CanManipulateProjectMembers: true,
+				},
+			},
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{
+					{Name: "proj1", Members: expected},
+					{Name: "app-1", Members: expected},
+				},
+			},
+			ownership)
+		Expect(actionStrings(actions)).To(Equal([]string{"removing member alpha from app-1"}))
+	})
+	It("keeps the ownership of the members when their role is updated", func() {
+		ctx := context.Background()
+		ownership, err := reconciler.GetOwnership(ctx, store, "harbor", nil)
+		Expect(err).ToNot(HaveOccurred())
+		alpha := api.MemberStatus{Name: "alpha", Type: "User", Role: "Developer"}
+		beta := api.MemberStatus{Name: "beta", Type: "User", Role: "Developer"}
+		alphaAdmin := api.MemberStatus{Name: "alpha", Type: "User", Role: "admin"}
+		betaAdmin := api.MemberStatus{Name: "beta", Type: "User", Role: "admin"}
+		actions := reconciler.Compare(nil,
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{
+					{Name: "proj1", Members: []api.MemberStatus{alpha, beta}},
+				},
+				Capabilities: api.RegistryCapabilities{
+					CanManipulateProjectMembers: true,
+				},
+			},
+			&api.RegistryStatus{
+				Projects: []api.ProjectStatus{
+					{Name: "proj1", Members: []api.MemberStatus{alphaAdmin, betaAdmin}},
+				},
+			},
+			ownership)
+		Expect(actionStrings(actions)).To(Equal([]string{
+			"updating role of member alpha in proj1 to admin",
+			"updating role of member beta in proj1 to admin",
+		}))
+		reg := &memberRegistry{}
+		for _, action := range actions {
+			sideEffect, err := reconciler.PerformAction(ctx, action, reg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sideEffect.Perform(ctx, store)).To(Succeed())
+		}
+		// the record is not changed
+		Expect(store.configMaps["harbor---ownership"]["members"]).To(Equal("proj1/alpha\napp-1/alpha"))
+
+		By("disowning the removed member if it cannot be added again")
+		reg.assignErr = globalregistry.ErrValidation
+		sideEffect, err := reconciler.PerformAction(ctx, actions[0], reg)
+		Expect(err).To(MatchError(globalregistry.ErrValidation))
+		Expect(sideEffect).ToNot(BeNil())
+		Expect(sideEffect.Perform(ctx, store)).To(Succeed())
+		Expect(store.configMaps["harbor---ownership"]["members"]).To(Equal("app-1/alpha"))
+	})
+})
@@ -18,15 +18,19 @@ package reconciler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
 	"github.com/kubermatic-labs/registryman/pkg/config"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type projectAddAction struct {
 	api.ProjectStatus
+	ownership *Ownership
 }
 
 var _ Action = &projectAddAction{}
@@ -41,12 +45,19 @@ func (pa *projectAddAction) Perform(ctx context.Context, reg globalregistry.Regi
 		// registry provider does not implement project creation
 		return nilEffect, nil
 	}
-	_, err := papi.CreateProject(ctx, pa.Name)
-	return nilEffect, err
+	if _, err := papi.CreateProject(ctx, pa.Name); err != nil {
+		return nilEffect, err
+	}
+	return &ownershipEffect{
+		ownership:   pa.ownership,
+		projectName: pa.Name,
+		owned:       true,
+	}, nil
 }
 
 type projectRemoveAction struct {
 	api.ProjectStatus
+	ownership *Ownership
 }
 
 var _ Action = &projectRemoveAction{}
@@ -64,13 +75,61 @@ func (pa *projectRemoveAction) Perform(ctx context.Context, reg globalregistry.R
 	if !ok {
 		return nilEffect, nil
 	}
-	return nilEffect, destructibleProject.Delete(ctx)
+	if err = destructibleProject.Delete(ctx); err != nil {
+		return nilEffect, err
+	}
+	return sideEffects{
+		&removeProjectCredentials{
+			project:  pa.ProjectStatus,
+			registry: reg,
+		},
+		&ownershipEffect{
+			ownership:   pa.ownership,
+			projectName: pa.Name,
+			owned:       false,
+		},
+	}, nil
+}
+
+// removeProjectCredentials removes the credential Secrets of the robot members
+// of a removed project.
+type removeProjectCredentials struct {
+	project  api.ProjectStatus
+	registry globalregistry.Registry
+}
+
+var _ SideEffect = &removeProjectCredentials{}
+
+func (rpc *removeProjectCredentials) Perform(ctx context.Context, performer SideEffectPerformer) error {
+	for _, member := range rpc.project.Members {
+		if member.Type != "Robot" {
+			continue
+		}
+		err := (&removeMemberCredentials{
+			action: &memberRemoveAction{
+				MemberStatus: member,
+				projectName:  rpc.project.Name,
+			},
+			registry: rpc.registry,
+		}).Perform(ctx, performer)
+		if err != nil && !apierrors.IsNotFound(err) && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
 }
 
 // CompareProjectStatuses compares the actual and expected status of the projects
 // of a registry. The function returns the actions that are needed to synchronize
 // the actual state to the expected state.
+//
+// Every actual project which is not expected is removed. The creation and the
+// removal of the projects are not recorded in an ownership record.
 func CompareProjectStatuses(store *config.ExpectedProvider, actual, expected []api.ProjectStatus, regCapabilities api.RegistryCapabilities) []Action {
+	return compareProjectStatuses(store, actual, expected, regCapabilities, nil)
+}
+
+func compareProjectStatuses(store *config.ExpectedProvider, actual, expected []api.ProjectStatus, regCapabilities api.RegistryCapabilities, ownership *Ownership) []Action {
 	same := make(map[string][2]api.ProjectStatus)
 	actualDiff := []api.ProjectStatus{}
 	expectedDiff := []api.ProjectStatus{}
@@ -118,6 +177,7 @@ ExpLoop:
 			// Then remove the project itself
 			actions = append(actions, &projectRemoveAction{
 				ProjectStatus: act,
+				ownership:     ownership,
 			})
 		}
 	}
@@ -127,10 +187,11 @@ ExpLoop:
 	// differences.
 	for projectName, projectPair := range same {
 		actions = append(actions,
-			CompareMemberStatuses(projectName,
+			compareMemberStatuses(projectName,
 				projectPair[0].Members,
 				projectPair[1].Members,
 				regCapabilities,
+				ownership,
 			)...,
 		)
 		actions = append(actions,
@@ -155,14 +216,16 @@ ExpLoop:
 	for _, exp := range expectedDiff {
 		if regCapabilities.CanCreateProject {
 			actions = append(actions, &projectAddAction{
-				exp,
+				ProjectStatus: exp,
+				ownership:     ownership,
 			})
 		}
 		if regCapabilities.CanManipulateProjectMembers {
 			for _, member := range exp.Members {
 				actions = append(actions, &memberAddAction{
-					member,
-					exp.Name,
+					MemberStatus: member,
+					projectName:  exp.Name,
+					ownership:    ownership,
 				})
 			}
 		}
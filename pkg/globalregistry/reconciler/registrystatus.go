@@ -22,139 +22,157 @@ import (
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
 	"github.com/kubermatic-labs/registryman/pkg/config"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"golang.org/x/sync/errgroup"
 )
 
 // Compare compares the actual and expected status of a registry. The function
 // returns the actions that are needed to synchronize the actual state to the
 // expected state.
-func Compare(store *config.ExpectedProvider, actual, expected *api.RegistryStatus) []Action {
-	return CompareProjectStatuses(store, actual.Projects, expected.Projects, actual.Capabilities)
+//
+// The actual projects which are not expected are removed only if the
+// ownership allows their removal, the others are left untouched. The creation
+// and the removal of the projects are recorded in the ownership. A nil
+// ownership allows the removal of every project.
+func Compare(store *config.ExpectedProvider, actual, expected *api.RegistryStatus, ownership *Ownership) []Action {
+	return compareProjectStatuses(store,
+		ownership.managedProjects(actual.Projects, expected.Projects),
+		expected.Projects, actual.Capabilities, ownership)
 }
 
-func getRegistryCapabilities(ctx context.Context, reg globalregistry.Registry) (api.RegistryCapabilities, error) {
-	replCap := globalregistry.GetReplicationCapability(reg.GetProvider())
-	regWithProjects := reg.(globalregistry.RegistryWithProjects)
-	registryCapabilities := api.RegistryCapabilities{
-		CanPullReplicate: replCap.CanPull(),
-		CanPushReplicate: replCap.CanPush(),
-	}
-	dummyProject, err := regWithProjects.GetProjectByName(ctx, "")
-	if err != nil {
-		return registryCapabilities, err
-	}
-
-	if _, ok := reg.(globalregistry.ProjectCreator); ok {
-		registryCapabilities.CanCreateProject = true
-	}
-	if _, ok := dummyProject.(globalregistry.DestructibleProject); ok {
-		registryCapabilities.CanDeleteProject = true
-	}
-	if _, ok := dummyProject.(globalregistry.ProjectWithMembers); ok {
-		registryCapabilities.HasProjectMembers = true
-	}
-	if _, ok := dummyProject.(globalregistry.MemberManipulatorProject); ok {
-		registryCapabilities.CanManipulateProjectMembers = true
-	}
-	if _, ok := dummyProject.(globalregistry.ProjectWithScanner); ok {
-		registryCapabilities.HasProjectScanners = true
-	}
-	if _, ok := dummyProject.(globalregistry.ScannerManipulatorProject); ok {
-		registryCapabilities.CanManipulateProjectScanners = true
-	}
-	if _, ok := dummyProject.(globalregistry.ProjectWithReplication); ok {
-		registryCapabilities.HasProjectReplicationRules = true
-	}
-	if _, ok := dummyProject.(globalregistry.ReplicationRuleManipulatorProject); ok {
-		registryCapabilities.CanManipulateProjectReplicationRules = true
+// getRegistryVersion returns the version of the registry. If the registry
+// cannot report its version, an empty string is returned.
+func getRegistryVersion(ctx context.Context, reg globalregistry.Registry) (string, error) {
+	if regWithVersion, ok := reg.(globalregistry.RegistryWithVersion); ok {
+		return regWithVersion.GetVersion(ctx)
 	}
-	if _, ok := dummyProject.(globalregistry.ProjectWithStorage); ok {
-		registryCapabilities.HasProjectStorageReport = true
-	}
-	return registryCapabilities, nil
+	return "", nil
 }
 
+// StatusWorkers is the maximum number of projects whose status is fetched
+// concurrently by GetRegistryStatus.
+var StatusWorkers = 8
+
 // GetRegistryStatus function calculate the status of a registry. If the
 // registry represents a configuration of registry, then the expected registry
 // status is returned. If the registry represents an actual (real) registry, the
 // actual status is returned.
+//
+// The details of the projects are fetched concurrently by at most
+// StatusWorkers workers. The project statuses are returned in the order of
+// the projects returned by the registry. If fetching the status of a project
+// fails, the context of the other workers is cancelled and the first error is
+// returned.
 func GetRegistryStatus(ctx context.Context, reg globalregistry.Registry) (*api.RegistryStatus, error) {
 	regWithProjects := reg.(globalregistry.RegistryWithProjects)
-	registryCapabilities, err := getRegistryCapabilities(ctx, reg)
+	// the capabilities are calculated based on the capability descriptor
+	// of the provider, considering the version constraints of the features
+	version, err := getRegistryVersion(ctx, reg)
+	if err != nil {
+		return nil, err
+	}
+	capabilities, err := globalregistry.GetCapabilities(reg)
 	if err != nil {
 		return nil, err
 	}
+	registryCapabilities := capabilities.RegistryCapabilities(version)
 	projects, err := regWithProjects.ListProjects(ctx)
 	if err != nil {
 		return nil, err
 	}
 	projectStatuses := make([]api.ProjectStatus, len(projects))
+	g, gctx := errgroup.WithContext(ctx)
+	workers := StatusWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	g.SetLimit(workers)
 	for i, project := range projects {
-		projectStatuses[i].Name = project.GetName()
-
-		projectWithMembers, ok := project.(globalregistry.ProjectWithMembers)
-		if ok {
-			// registry supports projects with members
-			members, err := projectWithMembers.GetMembers(ctx)
+		i, project := i, project
+		g.Go(func() error {
+			projectStatus, err := getProjectStatus(gctx, project)
 			if err != nil {
-				return nil, err
-			}
-			projectStatuses[i].Members = make([]api.MemberStatus, len(members))
-			for n, member := range members {
-				projectStatuses[i].Members[n].Name = member.GetName()
-				projectStatuses[i].Members[n].Type = member.GetType()
-				projectStatuses[i].Members[n].Role = member.GetRole()
-				switch m := member.(type) {
-				case globalregistry.LdapMember:
-					projectStatuses[i].Members[n].DN = m.GetDN()
-				}
+				return err
 			}
-		} else {
-			projectStatuses[i].Members = make([]api.MemberStatus, 0)
+			projectStatuses[i] = *projectStatus
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+	return &api.RegistryStatus{
+		Projects:     projectStatuses,
+		Capabilities: registryCapabilities,
+		Version:      version,
+	}, nil
+}
+
+// getProjectStatus returns the status of the project.
+func getProjectStatus(ctx context.Context, project globalregistry.Project) (*api.ProjectStatus, error) {
+	projectStatus := &api.ProjectStatus{
+		Name: project.GetName(),
+	}
+
+	projectWithMembers, ok := project.(globalregistry.ProjectWithMembers)
+	if ok {
+		// registry supports projects with members
+		members, err := projectWithMembers.GetMembers(ctx)
+		if err != nil {
+			return nil, err
 		}
-		projectWithReplication, ok := project.(globalregistry.ProjectWithReplication)
-		if ok {
-			replicationRules, err := projectWithReplication.GetReplicationRules(ctx, nil, "")
-			if err != nil {
-				return nil, err
+		projectStatus.Members = make([]api.MemberStatus, len(members))
+		for n, member := range members {
+			projectStatus.Members[n].Name = member.GetName()
+			projectStatus.Members[n].Type = member.GetType()
+			projectStatus.Members[n].Role = member.GetRole()
+			switch m := member.(type) {
+			case globalregistry.LdapMember:
+				projectStatus.Members[n].DN = m.GetDN()
 			}
-			projectStatuses[i].ReplicationRules = make([]api.ReplicationRuleStatus, len(replicationRules))
-			for n, rule := range replicationRules {
-				projectStatuses[i].ReplicationRules[n].RemoteRegistryName = rule.RemoteRegistry().GetName()
-				projectStatuses[i].ReplicationRules[n].Trigger = api.ReplicationTrigger{
-					Type:     rule.Trigger().TriggerType(),
-					Schedule: rule.Trigger().TriggerSchedule(),
-				}
-				projectStatuses[i].ReplicationRules[n].Direction = rule.Direction()
+		}
+	} else {
+		projectStatus.Members = make([]api.MemberStatus, 0)
+	}
+	projectWithReplication, ok := project.(globalregistry.ProjectWithReplication)
+	if ok {
+		replicationRules, err := projectWithReplication.GetReplicationRules(ctx, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		projectStatus.ReplicationRules = make([]api.ReplicationRuleStatus, len(replicationRules))
+		for n, rule := range replicationRules {
+			projectStatus.ReplicationRules[n].RemoteRegistryName = rule.RemoteRegistry().GetName()
+			projectStatus.ReplicationRules[n].Trigger = api.ReplicationTrigger{
+				Type:     rule.Trigger().TriggerType(),
+				Schedule: rule.Trigger().TriggerSchedule(),
 			}
-		} else {
-			projectStatuses[i].ReplicationRules = make([]api.ReplicationRuleStatus, 0)
+			projectStatus.ReplicationRules[n].Direction = rule.Direction()
 		}
+	} else {
+		projectStatus.ReplicationRules = make([]api.ReplicationRuleStatus, 0)
+	}
 
-		projectWithStorage, ok := project.(globalregistry.ProjectWithStorage)
-		if ok {
-			storageUsed, err := projectWithStorage.GetUsedStorage(ctx)
-			if err != nil {
-				return nil, err
-			}
-			projectStatuses[i].StorageUsed = storageUsed
+	projectWithStorage, ok := project.(globalregistry.ProjectWithStorage)
+	if ok {
+		storageUsed, err := projectWithStorage.GetUsedStorage(ctx)
+		if err != nil {
+			return nil, err
 		}
+		projectStatus.StorageUsed = storageUsed
+	}
 
-		projectWithScanner, ok := project.(globalregistry.ProjectWithScanner)
-		if ok {
-			projectScanner, err := projectWithScanner.GetScanner(ctx)
-			if err != nil {
-				return nil, err
-			}
-			if projectScanner != nil {
-				projectStatuses[i].ScannerStatus = api.ScannerStatus{
-					Name: projectScanner.GetName(),
-					URL:  projectScanner.GetURL(),
-				}
+	projectWithScanner, ok := project.(globalregistry.ProjectWithScanner)
+	if ok {
+		projectScanner, err := projectWithScanner.GetScanner(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if projectScanner != nil {
+			projectStatus.ScannerStatus = api.ScannerStatus{
+				Name: projectScanner.GetName(),
+				URL:  projectScanner.GetURL(),
 			}
 		}
 	}
-	return &api.RegistryStatus{
-		Projects:     projectStatuses,
-		Capabilities: registryCapabilities,
-	}, nil
+	return projectStatus, nil
 }
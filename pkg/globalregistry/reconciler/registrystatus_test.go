@@ -0,0 +1,155 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+)
+
+// slowRegistry is a registry whose projects report their storage usage with
+// a delay. It records the maximum number of concurrent calls.
+type slowRegistry struct {
+	projects  int
+	failingAt int
+	delay     time.Duration
+
+	mu        sync.Mutex
+	running   int
+	maxActive int
+	cancelled int
+}
+
+var _ globalregistry.Registry = &slowRegistry{}
+var _ globalregistry.RegistryWithProjects = &slowRegistry{}
+var _ globalregistry.RegistryWithCapabilities = &slowRegistry{}
+
+func (r *slowRegistry) GetProvider() string                        { return "slow" }
+func (r *slowRegistry) GetUsername() string                        { return "" }
+func (r *slowRegistry) GetPassword() string                        { return "" }
+func (r *slowRegistry) GetAPIEndpoint() string                     { return "" }
+func (r *slowRegistry) GetName() string                            { return "slow" }
+func (r *slowRegistry) GetOptions() globalregistry.RegistryOptions { return nil }
+func (r *slowRegistry) GetAnnotations() map[string]string          { return nil }
+func (r *slowRegistry) GetInsecureSkipTLSVerify() bool             { return false }
+
+func (r *slowRegistry) GetCapabilities() globalregistry.ProviderCapabilities {
+	return globalregistry.ProviderCapabilities{}
+}
+
+func (r *slowRegistry) ListProjects(context.Context) ([]globalregistry.Project, error) {
+	projects := make([]globalregistry.Project, r.projects)
+	for i := range projects {
+		projects[i] = &slowProject{registry: r, index: i}
+	}
+	return projects, nil
+}
+
+func (r *slowRegistry) GetProjectByName(context.Context, string) (globalregistry.Project, error) {
+	return nil, nil
+}
+
+// unregisteredRegistry hides the capabilities of the slowRegistry, so they
+// are looked up by its provider, which is not registered.
+type unregisteredRegistry struct {
+	globalregistry.Registry
+	globalregistry.RegistryWithProjects
+}
+
+type slowProject struct {
+	registry *slowRegistry
+	index    int
+}
+
+func (p *slowProject) GetName() string {
+	return fmt.Sprintf("project-%02d", p.index)
+}
+
+func (p *slowProject) GetUsedStorage(ctx context.Context) (int, error) {
+	r := p.registry
+	r.mu.Lock()
+	r.running++
+	if r.running > r.maxActive {
+		r.maxActive = r.running
+	}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.running--
+		r.mu.Unlock()
+	}()
+	if p.index == r.failingAt {
+		return 0, fmt.Errorf("project %d: %w", p.index, globalregistry.ErrForbidden)
+	}
+	// the later projects finish earlier
+	select {
+	case <-time.After(r.delay * time.Duration(r.projects-p.index)):
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.cancelled++
+		r.mu.Unlock()
+		return 0, ctx.Err()
+	}
+	return p.index, nil
+}
+
+var _ = Describe("GetRegistryStatus", func() {
+	var defaultWorkers int
+	BeforeEach(func() {
+		defaultWorkers = reconciler.StatusWorkers
+	})
+	AfterEach(func() {
+		reconciler.StatusWorkers = defaultWorkers
+	})
+
+	It("fetches the project statuses concurrently in deterministic order", func() {
+		reconciler.StatusWorkers = 3
+		reg := &slowRegistry{projects: 10, failingAt: -1, delay: time.Millisecond}
+		status, err := reconciler.GetRegistryStatus(context.Background(), reg)
+		Expect(err).To(Succeed())
+		Expect(status.Projects).To(HaveLen(10))
+		for i, projectStatus := range status.Projects {
+			Expect(projectStatus.Name).To(Equal(fmt.Sprintf("project-%02d", i)))
+			Expect(projectStatus.StorageUsed).To(Equal(i))
+		}
+		Expect(reg.maxActive).To(BeNumerically(">", 1))
+		Expect(reg.maxActive).To(BeNumerically("<=", 3))
+	})
+
+	It("returns the first error and cancels the other workers", func() {
+		reconciler.StatusWorkers = 4
+		reg := &slowRegistry{projects: 8, failingAt: 2, delay: time.Second}
+		start := time.Now()
+		_, err := reconciler.GetRegistryStatus(context.Background(), reg)
+		Expect(err).To(MatchError(globalregistry.ErrForbidden))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(reg.cancelled).To(BeNumerically(">", 0))
+	})
+	It("returns an error for a registry of an unregistered provider", func() {
+		reg := &slowRegistry{projects: 1, failingAt: -1}
+		_, err := reconciler.GetRegistryStatus(context.Background(), unregisteredRegistry{reg, reg})
+		Expect(err).To(MatchError(globalregistry.ErrNotImplemented))
+	})
+})
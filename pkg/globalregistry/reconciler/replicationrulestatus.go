@@ -18,6 +18,7 @@ package reconciler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
@@ -104,9 +105,57 @@ func (ra *rRuleRemoveAction) Perform(ctx context.Context, reg globalregistry.Reg
 	return nilEffect, nil
 }
 
+type rRuleUpdateAction struct {
+	api.ReplicationRuleStatus
+	actual      api.ReplicationRuleStatus
+	store       *config.ExpectedProvider
+	projectName string
+}
+
+var _ Action = &rRuleUpdateAction{}
+
+func (ra *rRuleUpdateAction) String() string {
+	return fmt.Sprintf("updating replication rule for %s: %s [%s] on %s",
+		ra.projectName,
+		ra.RemoteRegistryName,
+		ra.Direction,
+		ra.Trigger.TriggerType(),
+	)
+}
+
+// Perform updates the replication rule in place. When the project cannot update
+// its replication rules, the rule is removed and added again.
+func (ra *rRuleUpdateAction) Perform(ctx context.Context, reg globalregistry.Registry) (SideEffect, error) {
+	project, err := reg.(globalregistry.RegistryWithProjects).GetProjectByName(ctx, ra.projectName)
+	if err != nil {
+		return nilEffect, err
+	}
+	remoteRegistry := ra.store.GetRegistryByName(ctx, ra.RemoteRegistryName)
+	if remoteRegistry == nil {
+		return nilEffect, fmt.Errorf("registry %s not found in object store", ra.RemoteRegistryName)
+	}
+	replicationRuleManipulatorProject, ok := project.(globalregistry.ReplicationRuleManipulatorProject)
+	if !ok {
+		// registry does not support project level replication
+		return nilEffect, nil
+	}
+	_, err = replicationRuleManipulatorProject.UpdateReplicationRule(ctx, remoteRegistry, ra.Trigger, ra.Direction)
+	if !errors.Is(err, globalregistry.ErrNotImplemented) {
+		return nilEffect, err
+	}
+	_, err = (&rRuleRemoveAction{ra.actual, ra.store, ra.projectName}).Perform(ctx, reg)
+	if err != nil {
+		return nilEffect, err
+	}
+	return (&rRuleAddAction{ra.ReplicationRuleStatus, ra.store, ra.projectName}).Perform(ctx, reg)
+}
+
 // CompareReplicationRuleStatus compares the actual and expected status of the
 // replication rules of a project. The function returns the actions that are
 // needed to synchronize the actual state to the expected state.
+//
+// The replication rules are identified by the remote registry and the
+// direction. The rules whose trigger differs only are updated in place.
 func CompareReplicationRuleStatus(store *config.ExpectedProvider, projectName string, actual, expected []api.ReplicationRuleStatus, regCapabilities api.RegistryCapabilities) []Action {
 	actualDiff := []api.ReplicationRuleStatus{}
 	expectedDiff := []api.ReplicationRuleStatus{}
@@ -133,6 +182,29 @@ ExpLoop:
 	actions := make([]Action, 0)
 
 	if regCapabilities.CanManipulateProjectReplicationRules {
+		// the rules found in both diffs replicate with the same remote
+		// registry in the same direction, so they are updated instead
+		// of re-adding them
+		removedDiff := []api.ReplicationRuleStatus{}
+	UpdateLoop:
+		for _, act := range actualDiff {
+			for i, exp := range expectedDiff {
+				if act.RemoteRegistryName == exp.RemoteRegistryName &&
+					act.Direction == exp.Direction {
+					actions = append(actions, &rRuleUpdateAction{
+						exp,
+						act,
+						store,
+						projectName,
+					})
+					expectedDiff = append(expectedDiff[:i:i], expectedDiff[i+1:]...)
+					continue UpdateLoop
+				}
+			}
+			removedDiff = append(removedDiff, act)
+		}
+		actualDiff = removedDiff
+
 		// actualDiff contains the members which are there but are not needed
 		for _, act := range actualDiff {
 			actions = append(actions, &rRuleRemoveAction{
@@ -188,10 +188,9 @@ var _ = Describe("Memberstatus", func() {
 			CanManipulateProjectReplicationRules: true,
 		})
 		Expect(actions).ToNot(BeNil())
-		Expect(len(actions)).To(Equal(2))
+		Expect(len(actions)).To(Equal(1))
 		Expect(actionsToStrings(actions)).To(Equal([]string{
-			"removing replication rule for proj: reg1 [Push] on event_based",
-			"adding replication rule for proj: reg1 [Push] on manual",
+			"updating replication rule for proj: reg1 [Push] on manual",
 		}))
 		act = []api.ReplicationRuleStatus{
 			rrule1,
@@ -209,4 +208,34 @@ var _ = Describe("Memberstatus", func() {
 			"adding replication rule for proj: reg1 [Pull] on event_based",
 		}))
 	})
+
+	It("updates the trigger of rules in place", func() {
+		cron := func(schedule string) api.ReplicationRuleStatus {
+			return api.ReplicationRuleStatus{
+				RemoteRegistryName: "reg1",
+				Trigger: api.ReplicationTrigger{
+					Type:     api.CronReplicationTriggerType,
+					Schedule: schedule,
+				},
+				Direction: "Pull",
+			}
+		}
+		act := []api.ReplicationRuleStatus{
+			cron("0 * * * *"),
+			rrule2,
+		}
+		exp := []api.ReplicationRuleStatus{
+			rrule2,
+			cron("30 * * * *"),
+		}
+		actions := reconciler.CompareReplicationRuleStatus(nil, "proj", act, exp, api.RegistryCapabilities{
+			CanManipulateProjectReplicationRules: true,
+		})
+		Expect(actionsToStrings(actions)).To(Equal([]string{
+			"updating replication rule for proj: reg1 [Pull] on cron",
+		}))
+
+		actions = reconciler.CompareReplicationRuleStatus(nil, "proj", act, exp, api.RegistryCapabilities{})
+		Expect(actions).To(BeEmpty())
+	})
 })
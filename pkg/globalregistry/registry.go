@@ -27,13 +27,13 @@ import (
 type RegistryCreator func(logr.Logger, Registry) (Registry, error)
 
 var (
-	registeredRegistryCreators        map[string]RegistryCreator
-	registeredReplicationCapabilities map[string]ReplicationCapabilities
+	registeredRegistryCreators map[string]RegistryCreator
+	registeredCapabilities     map[string]ProviderCapabilities
 )
 
 func init() {
 	registeredRegistryCreators = make(map[string]RegistryCreator)
-	registeredReplicationCapabilities = make(map[string]ReplicationCapabilities)
+	registeredCapabilities = make(map[string]ProviderCapabilities)
 }
 
 // ReplicationCapabilities interface defines the methods that show the
@@ -44,13 +44,11 @@ type ReplicationCapabilities interface {
 }
 
 // GetReplicationCapability function returns the ReplicationCapabilities of a
-// registered registry provider.
+// registered registry provider. An unregistered provider can neither pull nor
+// push.
 func GetReplicationCapability(provider string) ReplicationCapabilities {
-	cap, found := registeredReplicationCapabilities[provider]
-	if !found {
-		panic(fmt.Sprintf("provider %s is not registered", provider))
-	}
-	return cap
+	capabilities, _ := GetProviderCapabilities(provider)
+	return capabilities
 }
 
 // Registry interface describes a registry configuration that is needed to
@@ -86,12 +84,13 @@ func New(logger logr.Logger, config Registry) (Registry, error) {
 }
 
 // RegisterProviderImplementation is used by the different Registry interface
-// implementations to register the Register constructors. After a constructor
-// function is registered, a new Registry can be created using the New function.
+// implementations to register the Register constructors together with the
+// capability descriptor of the provider. After a constructor function is
+// registered, a new Registry can be created using the New function.
 func RegisterProviderImplementation(providerName string,
 	constructor RegistryCreator,
-	repCap ReplicationCapabilities,
+	capabilities ProviderCapabilities,
 ) {
 	registeredRegistryCreators[providerName] = constructor
-	registeredReplicationCapabilities[providerName] = repCap
+	registeredCapabilities[providerName] = capabilities
 }
@@ -16,6 +16,8 @@
 
 package globalregistry
 
+import "context"
+
 // Scanner interface contains the methods that can be used to inspect the
 // parameters of an external vulnerability scanner.
 type Scanner interface {
@@ -26,3 +28,21 @@ type Scanner interface {
 	// GetURL returns the URL of the vulnerability scanner.
 	GetURL() string
 }
+
+// RegistryWithScanners interface is implemented by the provider specific
+// Registry implementations that register the vulnerability scanners at the
+// registry level.
+type RegistryWithScanners interface {
+	// ListScanners returns the scanners registered in the registry.
+	ListScanners(context.Context) ([]Scanner, error)
+}
+
+// ScannerWithHealth interface is implemented by the registered scanners whose
+// health can be checked by the registry.
+type ScannerWithHealth interface {
+	Scanner
+
+	// Ping checks whether the registry can reach the scanner. It returns
+	// nil if the scanner is healthy.
+	Ping(context.Context) error
+}
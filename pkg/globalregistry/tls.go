@@ -0,0 +1,78 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package globalregistry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// RegistryWithTLS interface is implemented by the Registry configurations that
+// provide custom TLS settings for the registry endpoint.
+type RegistryWithTLS interface {
+	// GetCABundle returns the PEM encoded CA bundle used to validate the
+	// certificate of the registry endpoint. If nil is returned, the system
+	// certificate pool is used.
+	GetCABundle() ([]byte, error)
+
+	// GetClientCertificate returns the PEM encoded client certificate and
+	// key used for mutual TLS authentication. If nil values are returned,
+	// no client certificate is presented.
+	GetClientCertificate() (certPEM, keyPEM []byte, err error)
+}
+
+// TLSConfig returns the TLS configuration that shall be used by the provider
+// implementations when they connect to the registry endpoint. The returned
+// value honors the insecureSkipTlsVerify setting and, if the registry
+// implements the RegistryWithTLS interface, the custom CA bundle and client
+// certificate.
+func TLSConfig(reg Registry) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: reg.GetInsecureSkipTLSVerify(),
+	}
+	regWithTLS, ok := reg.(RegistryWithTLS)
+	if !ok {
+		return tlsConfig, nil
+	}
+	caBundle, err := regWithTLS.GetCABundle()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get CA bundle of registry %s: %w", reg.GetName(), err)
+	}
+	if caBundle != nil {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no valid certificate found in the CA bundle of registry %s", reg.GetName())
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	certPEM, keyPEM, err := regWithTLS.GetClientCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get client certificate of registry %s: %w", reg.GetName(), err)
+	}
+	if certPEM != nil || keyPEM != nil {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate of registry %s: %w", reg.GetName(), err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,122 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package harbor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// countingHarbor serves a Harbor instance with a single project and counts
+// the lookups.
+type countingHarbor struct {
+	mu      sync.Mutex
+	lookups int
+}
+
+func (h *countingHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path != systemInfoPath {
+		h.mu.Lock()
+		h.lookups++
+		h.mu.Unlock()
+	}
+	switch {
+	case r.URL.Path == systemInfoPath:
+		w.Write([]byte(`{"harbor_version":"v2.3.0","auth_mode":"ldap_auth"}`))
+	case r.Method == http.MethodPost && r.URL.Path == path:
+		w.Header().Set("Location", path+"/2")
+		w.WriteHeader(http.StatusCreated)
+	case r.URL.Path == path:
+		w.Write([]byte(`[{"project_id":1,"name":"app"}]`))
+	case r.URL.Path == path+"/1/members":
+		w.Write([]byte(`[{"id":5,"entity_name":"devs","entity_type":"g","role_id":2}]`))
+	case r.URL.Path == "/api/v2.0/ldap/groups/search":
+		w.Write([]byte(`[{"group_name":"devs","ldap_group_dn":"cn=devs,dc=example"}]`))
+	default:
+		w.Write([]byte(`[]`))
+	}
+}
+
+var _ = Describe("Snapshot cache", func() {
+	var fake *countingHarbor
+	var server *httptest.Server
+	var reg *registry
+
+	BeforeEach(func() {
+		fake = &countingHarbor{}
+		server = httptest.NewServer(fake)
+		r, err := newRegistry(logr.Discard(), &testConfig{apiEndpoint: server.URL})
+		Expect(err).To(Succeed())
+		reg = r.(*registry)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// lookupMembers performs the lookups of 10 member actions, each of them
+	// fetching the project and its members.
+	lookupMembers := func(ctx context.Context) {
+		for i := 0; i < 10; i++ {
+			project, err := reg.GetProjectByName(ctx, "app")
+			Expect(err).To(Succeed())
+			members, err := project.(globalregistry.ProjectWithMembers).GetMembers(ctx)
+			Expect(err).To(Succeed())
+			Expect(members).To(HaveLen(1))
+			Expect(members[0].(globalregistry.LdapMember).GetDN()).To(Equal("cn=devs,dc=example"))
+		}
+	}
+
+	It("reduces the number of API calls", func() {
+		lookupMembers(context.Background())
+		uncached := fake.lookups
+
+		fake.lookups = 0
+		cache := globalregistry.NewCache()
+		lookupMembers(globalregistry.WithCache(context.Background(), cache))
+		cached := fake.lookups
+
+		// projects, members, LDAP group search, robots
+		Expect(cached).To(Equal(4))
+		Expect(uncached).To(Equal(10 * cached))
+		hits, misses := cache.Stats()
+		Expect(misses).To(Equal(4))
+		Expect(hits).To(Equal(27))
+	})
+	It("is invalidated on writes", func() {
+		ctx := globalregistry.WithCache(context.Background(), globalregistry.NewCache())
+		_, err := reg.ListProjects(ctx)
+		Expect(err).To(Succeed())
+		_, err = reg.ListProjects(ctx)
+		Expect(err).To(Succeed())
+		Expect(fake.lookups).To(Equal(1))
+
+		_, err = reg.CreateProject(ctx, "new")
+		Expect(err).To(Succeed())
+		fake.lookups = 0
+		_, err = reg.ListProjects(ctx)
+		Expect(err).To(Succeed())
+		Expect(fake.lookups).To(Equal(1))
+	})
+})
@@ -59,6 +59,18 @@ func (m *projectMemberEntity) toProjectMember() globalregistry.ProjectMember {
 	}
 }
 
+// is returns true if the entity is the user or group member with the given
+// name and type.
+func (m *projectMemberEntity) is(name, memberType string) bool {
+	switch memberType {
+	case userType:
+		return m.EntityType == "u" && m.EntityName == name
+	case groupType:
+		return m.EntityType == "g" && m.EntityName == name
+	}
+	return false
+}
+
 type projectMember projectMemberEntity
 
 var _ globalregistry.ProjectMember = &projectMember{}
@@ -102,6 +114,39 @@ type userGroup struct {
 	Id          int    `json:"id"`
 }
 
+// The group types of the Harbor user groups.
+const (
+	ldapGroupType = 1
+	oidcGroupType = 3
+)
+
+// userGroupOf returns the user group corresponding to the group member. If the
+// member has a distinguished name, an LDAP group is returned. Otherwise, if
+// Harbor authenticates the users with OIDC, an OIDC group is returned.
+func (r *registry) userGroupOf(ctx context.Context, member globalregistry.ProjectMember) (*userGroup, error) {
+	if groupMember, ok := member.(globalregistry.LdapMember); ok && groupMember.GetDN() != "" {
+		return &userGroup{
+			GroupName:   member.GetName(),
+			LdapGroupDn: groupMember.GetDN(),
+			GroupType:   ldapGroupType,
+		}, nil
+	}
+	si, err := r.getSystemInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if si.AuthMode != oidcAuthMode {
+		return nil, fmt.Errorf("group is not LDAP group: %w", globalregistry.ErrValidation)
+	}
+	if err = r.requireVersion(ctx, oidcGroupVersion, "OIDC group"); err != nil {
+		return nil, err
+	}
+	return &userGroup{
+		GroupName: member.GetName(),
+		GroupType: oidcGroupType,
+	}, nil
+}
+
 type userEntity struct {
 	Username string `json:"username"`
 	UserId   int    `json:"user_id"`
@@ -115,6 +160,18 @@ type projectMemberRequestBody struct {
 }
 
 func (r *registry) getMembers(ctx context.Context, projectID int) ([]*projectMemberEntity, error) {
+	members, err := r.cached(ctx, func() (interface{}, error) {
+		return r.fetchMembers(ctx, projectID)
+	}, "projects", strconv.Itoa(projectID), "members")
+	ms, _ := members.([]*projectMemberEntity)
+	return ms, err
+}
+
+func (r *registry) fetchMembers(ctx context.Context, projectID int) ([]*projectMemberEntity, error) {
+	si, err := r.getSystemInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("%s/%d/members", path, projectID)
 	r.logger.V(1).Info("creating new request", "url", url.String())
@@ -146,7 +203,8 @@ func (r *registry) getMembers(ctx context.Context, projectID int) ([]*projectMem
 		fmt.Printf("body: %+v\n", b.String())
 	}
 	for _, member := range projectMembersResult {
-		if member.EntityType == "g" {
+		// OIDC groups have no distinguished name
+		if member.EntityType == "g" && si.AuthMode != oidcAuthMode {
 			member.dn, err = r.searchLdapGroup(ctx, member.EntityName)
 			if err != nil {
 				return nil, err
@@ -157,6 +215,7 @@ func (r *registry) getMembers(ctx context.Context, projectID int) ([]*projectMem
 }
 
 func (r *registry) createProjectMember(ctx context.Context, projectID int, projectMember *projectMemberRequestBody) (int, error) {
+	defer r.invalidate(ctx, "projects", strconv.Itoa(projectID), "members")
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("%s/%d/members", path, projectID)
 	reqBodyBuf := bytes.NewBuffer(nil)
@@ -173,26 +232,30 @@ func (r *registry) createProjectMember(ctx context.Context, projectID int, proje
 	req.SetBasicAuth(r.GetUsername(), r.GetPassword())
 
 	resp, err := r.do(ctx, req)
-	if err != nil {
-		return 0, err
-	}
-
-	defer resp.Body.Close()
-	switch resp.StatusCode {
+	switch statusCode := globalregistry.StatusCodeOf(err); statusCode {
 	case 409:
-		return 0, fmt.Errorf("project member cannot be added: %w", globalregistry.ErrAlreadyExists)
+		return 0, wrapError("add project member", statusCode,
+			fmt.Errorf("project member cannot be added: %w", globalregistry.ErrAlreadyExists))
 	case 500:
 		switch {
 		case projectMember.MemberUser != nil:
 			name := projectMember.MemberUser.Username
-			return 0, fmt.Errorf("internal server error, invalid name? (%s)", name)
+			return 0, wrapError("add project member", statusCode, globalregistry.WithClass(
+				fmt.Errorf("internal server error, invalid name? (%s)", name),
+				globalregistry.ErrValidation))
 		case projectMember.MemberGroup != nil:
 			name := projectMember.MemberGroup.LdapGroupDn
-			return 0, fmt.Errorf("internal server error, invalid DN? (%s)", name)
+			return 0, wrapError("add project member", statusCode, globalregistry.WithClass(
+				fmt.Errorf("internal server error, invalid DN? (%s)", name),
+				globalregistry.ErrValidation))
 		default:
 			panic("projectMember is neither user nor group")
 		}
 	}
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
 
 	memberID, err := strconv.Atoi(strings.TrimPrefix(
 		resp.Header.Get("Location"),
@@ -207,6 +270,7 @@ func (r *registry) createProjectMember(ctx context.Context, projectID int, proje
 }
 
 func (r *registry) deleteProjectMember(ctx context.Context, projectID int, memberId int) error {
+	defer r.invalidate(ctx, "projects", strconv.Itoa(projectID), "members")
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("%s/%d/members/%d", path, projectID, memberId)
 	r.logger.V(1).Info("creating new request", "url", url.String())
@@ -228,3 +292,37 @@ func (r *registry) deleteProjectMember(ctx context.Context, projectID int, membe
 
 	return nil
 }
+
+type projectMemberRoleRequestBody struct {
+	RoleId role `json:"role_id"`
+}
+
+func (r *registry) updateProjectMemberRole(ctx context.Context, projectID int, memberId int, role role) error {
+	defer r.invalidate(ctx, "projects", strconv.Itoa(projectID), "members")
+	url := *r.parsedUrl
+	url.Path = fmt.Sprintf("%s/%d/members/%d", path, projectID, memberId)
+	reqBodyBuf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(reqBodyBuf).Encode(&projectMemberRoleRequestBody{
+		RoleId: role,
+	})
+	if err != nil {
+		return err
+	}
+	r.logger.V(1).Info("creating new request", "url", url.String())
+	req, err := http.NewRequest(http.MethodPut, url.String(), reqBodyBuf)
+	if err != nil {
+		return err
+	}
+
+	req.Header["Content-Type"] = []string{"application/json"}
+	req.SetBasicAuth(r.GetUsername(), r.GetPassword())
+
+	resp, err := r.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	return nil
+}
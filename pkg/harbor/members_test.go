@@ -0,0 +1,133 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package harbor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// memberHarbor serves a Harbor instance with a project that has a user and a
+// robot member and records the update requests.
+type memberHarbor struct {
+	mu      sync.Mutex
+	version string
+	updates map[string]map[string]interface{}
+}
+
+func (h *memberHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == systemInfoPath:
+		w.Write([]byte(`{"harbor_version":"` + h.version + `","auth_mode":"db_auth"}`))
+	case r.Method == http.MethodPut:
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		h.mu.Lock()
+		h.updates[r.URL.Path] = body
+		h.mu.Unlock()
+	case r.URL.Path == path:
+		w.Write([]byte(`[{"project_id":1,"name":"app"}]`))
+	case r.URL.Path == path+"/1/members":
+		w.Write([]byte(`[{"id":5,"entity_name":"alice","entity_type":"u","role_id":2}]`))
+	case r.URL.Path == path+"/1/robots":
+		w.Write([]byte(`[{"id":7,"name":"robot$app+ci","level":"project","permissions":[{"kind":"project","namespace":"app","access":[{"action":"pull","resource":"repository"}]}]}]`))
+	default:
+		w.Write([]byte(`[]`))
+	}
+}
+
+type testMember struct {
+	name, memberType, role string
+}
+
+func (m *testMember) GetName() string { return m.name }
+func (m *testMember) GetType() string { return m.memberType }
+func (m *testMember) GetRole() string { return m.role }
+
+var _ = Describe("Member update", func() {
+	var fake *memberHarbor
+	var server *httptest.Server
+	var proj globalregistry.MemberUpdaterProject
+
+	BeforeEach(func() {
+		fake = &memberHarbor{
+			version: "v2.3.0",
+			updates: map[string]map[string]interface{}{},
+		}
+	})
+	JustBeforeEach(func() {
+		server = httptest.NewServer(fake)
+		r, err := newRegistry(logr.Discard(), &testConfig{apiEndpoint: server.URL})
+		Expect(err).To(Succeed())
+		p, err := r.(*registry).GetProjectByName(context.Background(), "app")
+		Expect(err).To(Succeed())
+		proj = p.(globalregistry.MemberUpdaterProject)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("changes the role of a user member", func() {
+		err := proj.UpdateMember(context.Background(), &testMember{"alice", userType, "Maintainer"})
+		Expect(err).To(Succeed())
+		Expect(fake.updates).To(HaveKey(path + "/1/members/5"))
+		Expect(fake.updates[path+"/1/members/5"]["role_id"]).To(BeEquivalentTo(4))
+	})
+	It("does not change the role of a group with the name of a user", func() {
+		err := proj.UpdateMember(context.Background(), &testMember{"alice", groupType, "Maintainer"})
+		Expect(errors.Is(err, globalregistry.ErrNotFound)).To(BeTrue())
+		Expect(fake.updates).To(BeEmpty())
+	})
+	It("reports the missing robot member", func() {
+		err := proj.UpdateMember(context.Background(), &testMember{"cd", robotType, "PullAndPush"})
+		Expect(errors.Is(err, globalregistry.ErrNotFound)).To(BeTrue())
+	})
+	It("changes the permissions of a robot member", func() {
+		err := proj.UpdateMember(context.Background(), &testMember{"ci", robotType, "PullAndPush"})
+		Expect(err).To(Succeed())
+		body := fake.updates["/api/v2.0/robots/7"]
+		Expect(body).ToNot(BeNil())
+		Expect(body["name"]).To(Equal("robot$app+ci"))
+		Expect(body).ToNot(HaveKey("secret"))
+		permissions := body["permissions"].([]interface{})
+		Expect(permissions).To(HaveLen(1))
+		Expect(permissions[0].(map[string]interface{})["access"]).To(HaveLen(2))
+	})
+	Context("with the robot v1 API", func() {
+		BeforeEach(func() {
+			fake.version = "v2.1.0"
+		})
+		It("cannot change the permissions of a robot member", func() {
+			err := proj.UpdateMember(context.Background(), &testMember{"ci", robotType, "PullAndPush"})
+			Expect(errors.Is(err, globalregistry.ErrNotImplemented)).To(BeTrue())
+			Expect(fake.updates).To(BeEmpty())
+		})
+	})
+})
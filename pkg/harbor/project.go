@@ -38,6 +38,7 @@ var _ globalregistry.Project = &project{}
 var _ globalregistry.ProjectWithRepositories = &project{}
 var _ globalregistry.ProjectWithMembers = &project{}
 var _ globalregistry.MemberManipulatorProject = &project{}
+var _ globalregistry.MemberUpdaterProject = &project{}
 var _ globalregistry.ProjectWithScanner = &project{}
 var _ globalregistry.ScannerManipulatorProject = &project{}
 var _ globalregistry.ProjectWithReplication = &project{}
@@ -60,7 +61,7 @@ func (p *project) Delete(ctx context.Context) error {
 		switch opt := p.registry.GetOptions().(type) {
 		case globalregistry.CanForceDelete:
 			if f := opt.ForceDeleteProjects(); !f {
-				return fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.Name, globalregistry.ErrRecoverableError)
+				return wrapError("delete project", 0, fmt.Errorf("%s: repositories are present, please delete them before deleting the project, %w", p.Name, globalregistry.ErrConflict))
 			}
 			for _, repo := range repos {
 				p.registry.logger.V(1).Info("deleting repository",
@@ -132,19 +133,14 @@ func (p *project) AssignMember(ctx context.Context, member globalregistry.Projec
 		_, err = p.registry.createProjectMember(ctx, p.id, pum)
 		return nil, err
 	case groupType:
-		groupMember, ok := member.(globalregistry.LdapMember)
-		if !ok {
-			return nil, fmt.Errorf("error assigning group %s to project %s: group is not LDAP group",
-				member.GetName(), p.Name)
-		}
 		role, err := roleFromString(member.GetRole())
 		if err != nil {
 			return nil, err
 		}
-		userGroup := &userGroup{
-			GroupName:   member.GetName(),
-			LdapGroupDn: groupMember.GetDN(),
-			GroupType:   1,
+		userGroup, err := p.registry.userGroupOf(ctx, member)
+		if err != nil {
+			return nil, fmt.Errorf("error assigning group %s to project %s: %w",
+				member.GetName(), p.Name, err)
 		}
 
 		_, err = p.registry.updateIDOfUserGroup(ctx, userGroup)
@@ -180,7 +176,7 @@ func (p *project) AssignMember(ctx context.Context, member globalregistry.Projec
 		// ExpiresAt:   1024,
 		// Description: "generated robot member",
 		// Access:      robotRoleToAccess(member.GetRole()),
-		r, err := p.registry.createProjectRobotMember(ctx, prm)
+		r, err := p.registry.createProjectRobotMember(ctx, p.id, prm)
 		if err != nil {
 			return nil, err
 		}
@@ -201,6 +197,10 @@ func (p *project) GetMembers(ctx context.Context) ([]globalregistry.ProjectMembe
 	if err != nil {
 		return nil, err
 	}
+	robotV2, err := p.registry.hasRobotV2API(ctx)
+	if err != nil {
+		return nil, err
+	}
 	members := make([]globalregistry.ProjectMember, len(userGroupMembers)+len(robotMembers))
 
 	c := 0
@@ -212,8 +212,11 @@ func (p *project) GetMembers(ctx context.Context) ([]globalregistry.ProjectMembe
 
 	// collecting the members of type Robot
 	for _, robot := range robotMembers {
-		robot.Name = strings.TrimPrefix(robot.Name, fmt.Sprintf("robot$%s+", p.GetName()))
-		members[c] = robot
+		// the robots may be cached, so they are copied before the
+		// name is trimmed
+		robotMember := *robot
+		robotMember.Name = strings.TrimPrefix(robot.Name, robotName(robotV2, p.GetName(), ""))
+		members[c] = &robotMember
 		c++
 	}
 	return members, nil
@@ -249,7 +252,12 @@ func (p *project) UnassignMember(ctx context.Context, member globalregistry.Proj
 		if err != nil {
 			return err
 		}
-		expectedName := fmt.Sprintf("robot$%s+%s", p.GetName(), member.GetName())
+		var robotV2 bool
+		robotV2, err = p.registry.hasRobotV2API(ctx)
+		if err != nil {
+			return err
+		}
+		expectedName := robotName(robotV2, p.GetName(), member.GetName())
 		for _, memb := range members {
 			if memb.GetName() == expectedName {
 				m = memb
@@ -264,10 +272,90 @@ func (p *project) UnassignMember(ctx context.Context, member globalregistry.Proj
 	return err
 }
 
+// UpdateMember changes the role of a user or group member with the project
+// member API. The permissions of robot members are changed with the robot v2
+// API, so that their secret remains valid.
+func (p *project) UpdateMember(ctx context.Context, member globalregistry.ProjectMember) error {
+	memberType := member.GetType()
+	switch memberType {
+	default:
+		return fmt.Errorf("unhandled member type: %s", memberType)
+	case userType, groupType:
+		role, err := roleFromString(member.GetRole())
+		if err != nil {
+			return err
+		}
+		members, err := p.registry.getMembers(ctx, p.id)
+		if err != nil {
+			return err
+		}
+		for _, memb := range members {
+			if memb.is(member.GetName(), memberType) {
+				return p.registry.updateProjectMemberRole(ctx, p.id, memb.Id, role)
+			}
+		}
+		return wrapError("update project member", 0, fmt.Errorf(
+			"%s member %s of project %s, %w",
+			memberType, member.GetName(), p.Name, globalregistry.ErrNotFound))
+	case robotType:
+		robotV2, err := p.registry.hasRobotV2API(ctx)
+		if err != nil {
+			return err
+		}
+		if !robotV2 {
+			// the robot v1 API cannot change the permissions
+			return wrapError("update robot member", 0, fmt.Errorf(
+				"%s: robot permissions cannot be changed, %w",
+				member.GetName(), globalregistry.ErrNotImplemented))
+		}
+		members, err := p.registry.getRobotMembers(ctx, p.id)
+		if err != nil {
+			return err
+		}
+		expectedName := robotName(robotV2, p.GetName(), member.GetName())
+		for _, memb := range members {
+			if memb.GetName() == expectedName {
+				// the robots may be cached, so they are copied
+				// before the permissions are changed
+				robotMember := *memb
+				robotMember.Access = nil
+				robotMember.Permissions = []robotPermission{
+					{
+						Access:    robotRoleToAccess(member.GetRole()),
+						Kind:      "project",
+						Namespace: p.GetName(),
+					},
+				}
+				return p.registry.updateProjectRobotMember(ctx, p.id, &robotMember)
+			}
+		}
+		return wrapError("update robot member", 0, fmt.Errorf(
+			"robot member %s of project %s, %w",
+			member.GetName(), p.Name, globalregistry.ErrNotFound))
+	}
+}
+
 func (p *project) AssignReplicationRule(ctx context.Context, remoteReg globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
 	return p.registry.createReplicationRule(ctx, p, remoteReg, trigger, direction)
 }
 
+// UpdateReplicationRule updates the replication policy of the project which
+// replicates with the remote registry in the given direction.
+func (p *project) UpdateReplicationRule(ctx context.Context, remoteReg globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	replRules, err := p.GetReplicationRules(ctx, nil, direction)
+	if err != nil {
+		return nil, err
+	}
+	for _, replRule := range replRules {
+		if replRule.RemoteRegistry().GetName() == remoteReg.GetName() {
+			return p.registry.updateReplicationRule(ctx, p, replRule.(*replicationRule), remoteReg, trigger, direction)
+		}
+	}
+	return nil, wrapError("update replication rule", 0, fmt.Errorf(
+		"replication rule of %s with %s not found, %w",
+		p.Name, remoteReg.GetName(), globalregistry.ErrNotFound))
+}
+
 func (p *project) GetRepositories(ctx context.Context) ([]string, error) {
 	return p.registry.listProjectRepositories(ctx, p)
 }
@@ -28,6 +28,7 @@ import (
 	"strings"
 
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
 )
 
 const path = "/api/v2.0/projects"
@@ -97,6 +98,14 @@ func (r *registry) GetProjectByName(ctx context.Context, name string) (globalreg
 }
 
 func (r *registry) ListProjects(ctx context.Context) ([]globalregistry.Project, error) {
+	projects, err := r.cached(ctx, func() (interface{}, error) {
+		return r.listProjects(ctx)
+	}, "projects")
+	ps, _ := projects.([]globalregistry.Project)
+	return ps, err
+}
+
+func (r *registry) listProjects(ctx context.Context) ([]globalregistry.Project, error) {
 	r.logger.V(1).Info("listing projects",
 		"registry", r.GetName(),
 	)
@@ -139,6 +148,7 @@ func (r *registry) ListProjects(ctx context.Context) ([]globalregistry.Project,
 }
 
 func (r *registry) CreateProject(ctx context.Context, name string) (globalregistry.Project, error) {
+	defer r.invalidate(ctx, "projects")
 	proj := &project{
 		registry: r,
 		Name:     name,
@@ -204,6 +214,7 @@ func (r *registry) CreateProject(ctx context.Context, name string) (globalregist
 }
 
 func (r *registry) delete(ctx context.Context, id int) error {
+	defer r.invalidate(ctx, "projects")
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("%s/%d", path, id)
 	r.logger.V(1).Info("creating new request", "url", url.String())
@@ -250,7 +261,7 @@ func (r *registry) listProjectRepositories(ctx context.Context, proj *project) (
 
 	err = json.NewDecoder(resp.Body).Decode(&repositories)
 	if err != nil {
-		buf := resp.Body.(*bytesBody)
+		buf := resp.Body.(*transport.BytesBody)
 		r.logger.Error(err, "json decoding failed")
 		r.logger.Info(buf.String())
 	}
@@ -21,16 +21,17 @@ package harbor
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
 )
 
 func init() {
@@ -38,7 +39,7 @@ func init() {
 	globalregistry.RegisterProviderImplementation(
 		"harbor",
 		newRegistry,
-		harborRegistryCapabilities{},
+		harborRegistryCapabilities,
 	)
 }
 
@@ -46,26 +47,28 @@ type registry struct {
 	logger    logr.Logger
 	parsedUrl *url.URL
 	globalregistry.Registry
-	*http.Client
+	client *transport.Client
+
+	systemInfoMu sync.Mutex
+	systemInfo   *systemInfo
 }
 
 var _ globalregistry.Registry = &registry{}
 var _ globalregistry.RegistryWithProjects = &registry{}
 var _ globalregistry.ProjectCreator = &registry{}
+var _ globalregistry.RegistryWithVersion = &registry{}
+var _ globalregistry.RegistryWithScanners = &registry{}
 
 // newRegistry is the constructor if the registry type. It is a globalregistry RegistryCreator.
 func newRegistry(logger logr.Logger, config globalregistry.Registry) (globalregistry.Registry, error) {
-	var err error
+	client, err := transport.New(logger, config)
+	if err != nil {
+		return nil, err
+	}
 	c := &registry{
 		logger:   logger,
 		Registry: config,
-		Client: &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: config.GetInsecureSkipTLSVerify(),
-				},
-			},
-		},
+		client:   client,
 	}
 	c.parsedUrl, err = url.Parse(config.GetAPIEndpoint())
 	if err != nil {
@@ -74,53 +77,38 @@ func newRegistry(logger logr.Logger, config globalregistry.Registry) (globalregi
 	return c, nil
 }
 
-type bytesBody struct {
-	*bytes.Buffer
+// wrapError returns a *globalregistry.ProviderError of the harbor provider.
+func wrapError(action string, statusCode int, err error) error {
+	return globalregistry.WrapError("harbor", action, statusCode, err)
 }
 
-func (bb bytesBody) Close() error { return nil }
+// cached returns the result of the lookup identified by the key parts. The
+// result is memoised in the globalregistry.Cache of the context.
+func (r *registry) cached(ctx context.Context, fetch func() (interface{}, error), parts ...string) (interface{}, error) {
+	return globalregistry.CacheOf(ctx).Get(globalregistry.CacheKey(r, parts...), fetch)
+}
 
-// do method of Registry will perform a normal http.Registry do operation plus
-// it prints extra information in case of unexpected response codes. The
-// response body is replaced with a bytesBody which provides the bytes.Buffer
-// (e.g. String()) methods too.
-func (r *registry) do(ctx context.Context, req *http.Request) (*http.Response, error) {
-	req = req.WithContext(ctx)
-	resp, err := r.Client.Do(req)
-	if err != nil {
-		r.logger.Error(err, "http.Client cannot Do",
-			"req-url", req.URL,
-		)
-		return nil, err
-	}
+// invalidate removes the lookup results identified by the key parts from the
+// globalregistry.Cache of the context. It shall be called when the registry is
+// modified.
+func (r *registry) invalidate(ctx context.Context, parts ...string) {
+	globalregistry.CacheOf(ctx).Invalidate(globalregistry.CacheKey(r, parts...))
+}
 
-	buf := bytesBody{
-		Buffer: new(bytes.Buffer),
-	}
-	n, err := buf.ReadFrom(resp.Body)
-	if err != nil {
-		r.logger.Error(err, "cannot read HTTP response body")
-		return nil, err
-	}
-	resp.Body = buf
-
-	switch {
-	case resp.StatusCode == 401:
-		// Unauthorized
-		//
-		// Harbor sometimes misses to return 401 status code. It tends
-		// to respond 200 even when the credentials are incorrect.
-		return nil, globalregistry.ErrUnauthorized
-	case resp.StatusCode < 200 || resp.StatusCode >= 300:
-		// Any other error code
-		r.logger.V(-1).Info("HTTP response status code is not OK",
-			"status-code", resp.StatusCode,
-			"resp-body-size", n,
-			"req-url", req.URL,
-		)
-		r.logger.V(1).Info(buf.String())
+// do method of Registry performs the HTTP request using the shared transport
+// client. The response body is a transport.BytesBody which provides the
+// bytes.Buffer (e.g. String()) methods too. Before the first request, the
+// version of Harbor is detected using the context of the request, so that the
+// requests sent to unsupported Harbor versions fail with a clear error. The
+// version is not detected when the registry is created, since that could not be
+// cancelled.
+func (r *registry) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if req.URL.Path != systemInfoPath {
+		if _, err := r.getSystemInfo(ctx); err != nil {
+			return nil, err
+		}
 	}
-	return resp, nil
+	return r.client.Do(ctx, req)
 }
 
 type searchLdapGroupRespBody struct {
@@ -131,6 +119,14 @@ type searchLdapGroupRespBody struct {
 // searchLdapGroup returns with the distinguished name of the LDAP group. If
 // LDAP group is not found, it returns with "", nil.
 func (r *registry) searchLdapGroup(ctx context.Context, ldapGroupName string) (string, error) {
+	dn, err := r.cached(ctx, func() (interface{}, error) {
+		return r.fetchLdapGroup(ctx, ldapGroupName)
+	}, "ldapgroups", ldapGroupName)
+	ldapGroupDN, _ := dn.(string)
+	return ldapGroupDN, err
+}
+
+func (r *registry) fetchLdapGroup(ctx context.Context, ldapGroupName string) (string, error) {
 	r.logger.V(1).Info("searching for LDAP group",
 		"ldapGroupName", ldapGroupName,
 	)
@@ -180,6 +176,14 @@ func (r *registry) searchLdapGroup(ctx context.Context, ldapGroupName string) (s
 }
 
 func (r *registry) getUserGroups(ctx context.Context) ([]*userGroup, error) {
+	userGroups, err := r.cached(ctx, func() (interface{}, error) {
+		return r.fetchUserGroups(ctx)
+	}, "usergroups")
+	ugs, _ := userGroups.([]*userGroup)
+	return ugs, err
+}
+
+func (r *registry) fetchUserGroups(ctx context.Context) ([]*userGroup, error) {
 	r.logger.V(1).Info("listing usergroups")
 	url := *r.parsedUrl
 	url.Path = "/api/v2.0/usergroups"
@@ -219,6 +223,7 @@ func (r *registry) createUserGroup(ctx context.Context, ug *userGroup) error {
 		"LdapGroupDN", ug.LdapGroupDn,
 		"GroupType", ug.GroupType,
 	)
+	defer r.invalidate(ctx, "usergroups")
 
 	url := *r.parsedUrl
 	url.Path = "/api/v2.0/usergroups"
@@ -257,6 +262,7 @@ func (r *registry) deleteUserGroup(ctx context.Context, ug *userGroup) error {
 		"LdapGroupDN", ug.LdapGroupDn,
 		"GroupType", ug.GroupType,
 	)
+	defer r.invalidate(ctx, "usergroups")
 
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("/api/v2.0/usergroups/%d", ug.Id)
@@ -305,12 +311,15 @@ func (r *registry) updateIDOfUserGroup(ctx context.Context, ug *userGroup) (bool
 	return false, nil
 }
 
-type harborRegistryCapabilities struct{}
-
-func (cap harborRegistryCapabilities) CanPull() bool {
-	return true
-}
-
-func (cap harborRegistryCapabilities) CanPush() bool {
-	return true
+// harborRegistryCapabilities is the capability descriptor of the harbor
+// provider. The provider uses the v2.0 API of Harbor.
+var harborRegistryCapabilities = globalregistry.ProviderCapabilities{
+	globalregistry.FeatureProjects:                {Read: true, Write: true, MinVersion: "2.0"},
+	globalregistry.FeatureProjectDeletion:         {Write: true, MinVersion: "2.0"},
+	globalregistry.FeatureProjectMembers:          {Read: true, Write: true, MinVersion: "2.0"},
+	globalregistry.FeatureProjectScanners:         {Read: true, Write: true, MinVersion: "2.0"},
+	globalregistry.FeatureProjectReplicationRules: {Read: true, Write: true, MinVersion: "2.0"},
+	globalregistry.FeatureProjectStorageReport:    {Read: true, MinVersion: "2.0"},
+	globalregistry.FeaturePullReplication:         {Write: true, MinVersion: "2.0"},
+	globalregistry.FeaturePushReplication:         {Write: true, MinVersion: "2.0"},
 }
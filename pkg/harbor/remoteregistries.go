@@ -50,7 +50,7 @@ type remoteRegistryStatus struct {
 	Description  string             `json:"description"`
 }
 
-func remoteRegistryStatusFromRegistry(reg globalregistry.Registry) *remoteRegistryStatus {
+func remoteRegistryStatusFromRegistry(reg globalregistry.Registry) (*remoteRegistryStatus, error) {
 	var regType string
 	insecure := false
 	switch reg.GetProvider() {
@@ -64,7 +64,9 @@ func remoteRegistryStatusFromRegistry(reg globalregistry.Registry) *remoteRegist
 		regType = "jfrog-artifactory"
 		insecure = true
 	default:
-		panic(fmt.Sprintf("provider %s not implemented", reg.GetProvider()))
+		return nil, wrapError("create remote registry", 0, fmt.Errorf(
+			"remote registry of provider %s: %w",
+			reg.GetProvider(), globalregistry.ErrNotImplemented))
 	}
 	return &remoteRegistryStatus{
 		CreationTime: time.Time{}.Format(time.RFC3339),
@@ -82,7 +84,7 @@ func remoteRegistryStatusFromRegistry(reg globalregistry.Registry) *remoteRegist
 			reg.GetName(),
 			reg.GetProvider(),
 		),
-	}
+	}, nil
 }
 
 func (reg *remoteRegistryStatus) ProjectAPI() globalregistry.RegistryWithProjects {
@@ -187,9 +189,13 @@ func (r *registry) getRemoteRegistryByName(ctx context.Context, name string) (*r
 func (r *registry) createRemoteRegistry(ctx context.Context, reg globalregistry.Registry) (*remoteRegistryStatus, error) {
 	r.logger.V(1).Info("createRemoteRegistry invoked",
 		"reg-name", reg.GetName())
-	regStatus := remoteRegistryStatusFromRegistry(reg)
+	regStatus, err := remoteRegistryStatusFromRegistry(reg)
+	if err != nil {
+		return nil, err
+	}
+	defer r.invalidate(ctx, "registries")
 	reqBodyBuf := bytes.NewBuffer(nil)
-	err := json.NewEncoder(reqBodyBuf).Encode(regStatus)
+	err = json.NewEncoder(reqBodyBuf).Encode(regStatus)
 	if err != nil {
 		return nil, err
 	}
@@ -225,6 +231,14 @@ func (r *registry) createRemoteRegistry(ctx context.Context, reg globalregistry.
 }
 
 func (r *registry) listRemoteRegistries(ctx context.Context) ([]*remoteRegistryStatus, error) {
+	registries, err := r.cached(ctx, func() (interface{}, error) {
+		return r.fetchRemoteRegistries(ctx)
+	}, "registries")
+	regs, _ := registries.([]*remoteRegistryStatus)
+	return regs, err
+}
+
+func (r *registry) fetchRemoteRegistries(ctx context.Context) ([]*remoteRegistryStatus, error) {
 	url := *r.parsedUrl
 	url.Path = registriesPath
 	r.logger.V(1).Info("creating new request", "url", url.String())
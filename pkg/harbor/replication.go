@@ -40,6 +40,9 @@ type replicationTrigger struct {
 }
 
 func (rt replicationTrigger) TriggerType() api.ReplicationTriggerType {
+	if rt.Type == "scheduled" {
+		return api.CronReplicationTriggerType
+	}
 	var tt api.ReplicationTriggerType
 	err := tt.UnmarshalText([]byte(rt.Type))
 	if err != nil {
@@ -49,7 +52,8 @@ func (rt replicationTrigger) TriggerType() api.ReplicationTriggerType {
 }
 
 func (rt replicationTrigger) TriggerSchedule() string {
-	scheduleWords := strings.SplitN(rt.TriggerSettings.Cron, "", 2)
+	// the first element of the Harbor cron string is the seconds field
+	scheduleWords := strings.SplitN(rt.TriggerSettings.Cron, " ", 2)
 	if len(scheduleWords) != 2 {
 		return rt.TriggerSettings.Cron
 	}
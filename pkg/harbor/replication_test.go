@@ -0,0 +1,148 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package harbor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/go-logr/logr"
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// replicationHarbor serves a Harbor instance with a project that is pushed to
+// a remote registry and records the replication policy updates.
+type replicationHarbor struct {
+	mu      sync.Mutex
+	updates map[string]*replicationResponseBody
+}
+
+func (h *replicationHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == systemInfoPath:
+		w.Write([]byte(`{"harbor_version":"v2.3.0","auth_mode":"db_auth"}`))
+	case r.Method == http.MethodPut:
+		body := &replicationResponseBody{}
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		h.mu.Lock()
+		h.updates[r.URL.Path] = body
+		h.mu.Unlock()
+	case r.URL.Path == path:
+		w.Write([]byte(`[{"project_id":1,"name":"app"}]`))
+	case r.URL.Path == registriesPath:
+		w.Write([]byte(`[{"id":3,"name":"test","url":"https://remote.example.com","type":"harbor","credential":{"access_key":"admin"}}]`))
+	case r.URL.Path == replicationPolicyPath:
+		w.Write([]byte(`[{"id":9,"name":"push-app-to-test-on-manual-1","filters":[{"type":"name","value":"app/**"}],"src_registry":{"name":"Local"},"dest_registry":{"id":3,"name":"test"},"trigger":{"type":"manual"}}]`))
+	default:
+		w.Write([]byte(`[]`))
+	}
+}
+
+// pluginRemote is a remote registry of a provider that Harbor cannot
+// replicate with.
+type pluginRemote struct {
+	testConfig
+}
+
+func (c *pluginRemote) GetProvider() string { return "memory" }
+func (c *pluginRemote) GetName() string     { return "plugin" }
+
+var _ = Describe("Replication rule update", func() {
+	var fake *replicationHarbor
+	var server *httptest.Server
+	var proj globalregistry.ReplicationRuleManipulatorProject
+	remote := &testConfig{apiEndpoint: "https://remote.example.com"}
+
+	BeforeEach(func() {
+		fake = &replicationHarbor{
+			updates: map[string]*replicationResponseBody{},
+		}
+		server = httptest.NewServer(fake)
+		r, err := newRegistry(logr.Discard(), &testConfig{apiEndpoint: server.URL})
+		Expect(err).To(Succeed())
+		p, err := r.(*registry).GetProjectByName(context.Background(), "app")
+		Expect(err).To(Succeed())
+		proj = p.(globalregistry.ReplicationRuleManipulatorProject)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("replaces the trigger of the policy", func() {
+		rule, err := proj.UpdateReplicationRule(context.Background(), remote, api.ReplicationTrigger{
+			Type:     api.CronReplicationTriggerType,
+			Schedule: "*/5 * * * *",
+		}, "Push")
+		Expect(err).To(Succeed())
+		Expect(rule.GetName()).To(HavePrefix("push-app-to-test-on-scheduled-"))
+		Expect(rule.Trigger().TriggerType()).To(Equal(api.CronReplicationTriggerType))
+		Expect(rule.Trigger().TriggerSchedule()).To(Equal("*/5 * * * *"))
+
+		policy := fake.updates[replicationPolicyPath+"/9"]
+		Expect(policy).ToNot(BeNil())
+		Expect(policy.Id).To(Equal(9))
+		Expect(policy.Name).To(Equal(rule.GetName()))
+		Expect(policy.Trigger.Type).To(Equal("scheduled"))
+		Expect(policy.Trigger.TriggerSettings.Cron).To(Equal("0 */5 * * * *"))
+		Expect(policy.DestRegistry.Id).To(Equal(3))
+	})
+	It("does not update the policy of the other direction", func() {
+		_, err := proj.UpdateReplicationRule(context.Background(), remote, api.ReplicationTrigger{
+			Type: api.ManualReplicationTriggerType,
+		}, "Pull")
+		Expect(errors.Is(err, globalregistry.ErrNotFound)).To(BeTrue())
+		Expect(fake.updates).To(BeEmpty())
+	})
+	It("fails to replicate with a remote registry of an unsupported provider", func() {
+		_, err := proj.AssignReplicationRule(context.Background(), &pluginRemote{}, api.ReplicationTrigger{
+			Type: api.ManualReplicationTriggerType,
+		}, "Push")
+		Expect(errors.Is(err, globalregistry.ErrNotImplemented)).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("memory")))
+	})
+})
+
+var _ = Describe("Replication trigger", func() {
+	It("converts the manual trigger", func() {
+		trigger := replicationTrigger{Type: "manual"}
+		Expect(trigger.TriggerType()).To(Equal(api.ManualReplicationTriggerType))
+	})
+	It("converts the scheduled trigger", func() {
+		trigger := replicationTrigger{Type: "scheduled"}
+		trigger.TriggerSettings.Cron = "0 */5 * * * *"
+		Expect(trigger.TriggerType()).To(Equal(api.CronReplicationTriggerType))
+		Expect(trigger.TriggerSchedule()).To(Equal("*/5 * * * *"))
+	})
+	It("returns the schedule without seconds field as is", func() {
+		trigger := replicationTrigger{Type: "scheduled"}
+		trigger.TriggerSettings.Cron = "@hourly"
+		Expect(trigger.TriggerSchedule()).To(Equal("@hourly"))
+		trigger.TriggerSettings.Cron = ""
+		Expect(trigger.TriggerSchedule()).To(Equal(""))
+	})
+})
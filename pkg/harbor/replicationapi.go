@@ -34,6 +34,14 @@ import (
 const replicationPolicyPath = "/api/v2.0/replication/policies"
 
 func (r *registry) listReplicationRules(ctx context.Context) ([]globalregistry.ReplicationRule, error) {
+	rules, err := r.cached(ctx, func() (interface{}, error) {
+		return r.fetchReplicationRules(ctx)
+	}, "replication", "policies")
+	rs, _ := rules.([]globalregistry.ReplicationRule)
+	return rs, err
+}
+
+func (r *registry) fetchReplicationRules(ctx context.Context) ([]globalregistry.ReplicationRule, error) {
 	url := *r.parsedUrl
 	url.Path = replicationPolicyPath
 	r.logger.V(1).Info("creating new request", "url", url.String())
@@ -92,8 +100,11 @@ func (r *registry) listReplicationRules(ctx context.Context) ([]globalregistry.R
 	return replicationRules, err
 }
 
-func (r *registry) createReplicationRule(ctx context.Context, project globalregistry.Project, remoteReg globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
-	r.logger.V(1).Info("ReplicationAPI.Create invoked",
+// replicationPolicyOf returns the replication policy which replicates the
+// project with the remote registry. The remote registry is created in Harbor if
+// it does not exist yet.
+func (r *registry) replicationPolicyOf(ctx context.Context, project globalregistry.Project, remoteReg globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (*replicationResponseBody, error) {
+	r.logger.V(1).Info("building replication policy",
 		"project_name", project.GetName(),
 		"remoteReg_name", remoteReg.GetName(),
 		"trigger", trigger,
@@ -161,7 +172,6 @@ func (r *registry) createReplicationRule(ctx context.Context, project globalregi
 	if err != nil {
 		return nil, err
 	}
-	var name string
 	switch direction {
 	case "Push":
 		replicationPolicy.Description = fmt.Sprintf("Pushing %s project to %s on %s",
@@ -171,13 +181,12 @@ func (r *registry) createReplicationRule(ctx context.Context, project globalregi
 		)
 		replicationPolicy.SrcRegistry = local
 		replicationPolicy.DestRegistry = remoteRegistry
-		name = fmt.Sprintf("push-%s-to-%s-on-%s-%d",
+		replicationPolicy.Name = fmt.Sprintf("push-%s-to-%s-on-%s-%d",
 			project.GetName(),
 			remoteReg.GetName(),
 			replTrigger.Type,
 			nowStamp,
 		)
-		replicationPolicy.Name = name
 	case "Pull":
 		replicationPolicy.Description = fmt.Sprintf("Pulling %s project from %s on %s",
 			project.GetName(),
@@ -186,17 +195,24 @@ func (r *registry) createReplicationRule(ctx context.Context, project globalregi
 		)
 		replicationPolicy.DestRegistry = local
 		replicationPolicy.SrcRegistry = remoteRegistry
-		name = fmt.Sprintf("pull-%s-from-%s-on-%s-%d",
+		replicationPolicy.Name = fmt.Sprintf("pull-%s-from-%s-on-%s-%d",
 			project.GetName(),
 			remoteReg.GetName(),
 			replTrigger.Type,
 			nowStamp,
 		)
-		replicationPolicy.Name = name
 	default:
 		return nil, fmt.Errorf("unhandled replication direction: %s", direction)
 	}
+	return replicationPolicy, nil
+}
 
+func (r *registry) createReplicationRule(ctx context.Context, project globalregistry.Project, remoteReg globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	defer r.invalidate(ctx, "replication", "policies")
+	replicationPolicy, err := r.replicationPolicyOf(ctx, project, remoteReg, trigger, direction)
+	if err != nil {
+		return nil, err
+	}
 	reqBodyBuf := bytes.NewBuffer(nil)
 	err = json.NewEncoder(reqBodyBuf).Encode(replicationPolicy)
 	if err != nil {
@@ -227,18 +243,70 @@ func (r *registry) createReplicationRule(ctx context.Context, project globalregi
 		return nil, err
 	}
 
+	replicationPolicy.Id = replicationPolicyID
+	return r.replicationRuleOf(project, replicationPolicy)
+}
+
+// replicationRuleOf returns the replication rule corresponding to the
+// replication policy of the project.
+func (r *registry) replicationRuleOf(project globalregistry.Project, replicationPolicy *replicationResponseBody) (globalregistry.ReplicationRule, error) {
+	dir, err := replicationPolicy.direction()
+	if err != nil {
+		return nil, err
+	}
+	remote, err := replicationPolicy.remote()
+	if err != nil {
+		return nil, err
+	}
 	return &replicationRule{
-		ID:          replicationPolicyID,
+		ID:          replicationPolicy.Id,
 		registry:    r,
-		name:        name,
+		name:        replicationPolicy.Name,
 		projectName: project.GetName(),
-		Dir:         direction,
-		ReplTrigger: replTrigger,
-		Remote:      remoteRegistry,
+		Dir:         dir,
+		ReplTrigger: replicationPolicy.Trigger,
+		Remote:      remote,
 	}, nil
 }
 
+// updateReplicationRule replaces the replication policy of the rule, keeping
+// its ID, so that the execution history of the policy is preserved. The name
+// of the policy is regenerated, as it contains the trigger type.
+func (r *registry) updateReplicationRule(ctx context.Context, project globalregistry.Project, rule *replicationRule, remoteReg globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	defer r.invalidate(ctx, "replication", "policies")
+	replicationPolicy, err := r.replicationPolicyOf(ctx, project, remoteReg, trigger, direction)
+	if err != nil {
+		return nil, err
+	}
+	replicationPolicy.Id = rule.ID
+	reqBodyBuf := bytes.NewBuffer(nil)
+	err = json.NewEncoder(reqBodyBuf).Encode(replicationPolicy)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.V(1).Info(reqBodyBuf.String())
+	url := *r.parsedUrl
+	url.Path = fmt.Sprintf("%s/%d", replicationPolicyPath, rule.ID)
+	req, err := http.NewRequest(http.MethodPut, url.String(), reqBodyBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header["Content-Type"] = []string{"application/json"}
+	req.SetBasicAuth(r.GetUsername(), r.GetPassword())
+
+	resp, err := r.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	return r.replicationRuleOf(project, replicationPolicy)
+}
+
 func (r *registry) deleteReplicationRule(ctx context.Context, id int) error {
+	defer r.invalidate(ctx, "replication", "policies")
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("%s/%d", replicationPolicyPath, id)
 	r.logger.V(1).Info("creating new request", "url", url.String())
@@ -22,12 +22,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
 )
 
-// RobotV2
+// RobotV2, the Access field is set by the robot v1 API
 type robot struct {
 	UpdateTime   time.Time         `json:"update_time,omitempty"`
 	Description  string            `json:"description,omitempty"`
@@ -41,6 +43,7 @@ type robot struct {
 	Duration     int               `json:"duration,omitempty"`
 	Id           int               `json:"id,omitempty"`
 	Permissions  []robotPermission `json:"permissions,omitempty"`
+	Access       []access          `json:"access,omitempty"`
 }
 
 var _ globalregistry.ProjectMember = &robot{}
@@ -56,18 +59,21 @@ func (r *robot) GetType() string {
 func (r *robot) GetRole() string {
 	canPull := false
 	canPush := false
+	accesses := r.Access
 	for _, permission := range r.Permissions {
 		// TODO: robot accounts covering multiple projects are not
 		// supported yet
-		for _, access := range permission.Access {
-			if access.Action == "push" &&
-				access.Resource == "repository" {
-				canPush = true
-			}
-			if access.Action == "pull" &&
-				access.Resource == "repository" {
-				canPull = true
-			}
+		accesses = append(accesses, permission.Access...)
+	}
+	for _, access := range accesses {
+		// the robot v1 API uses /project/<id>/repository resources
+		if access.Action == "push" &&
+			strings.HasSuffix(access.Resource, "repository") {
+			canPush = true
+		}
+		if access.Action == "pull" &&
+			strings.HasSuffix(access.Resource, "repository") {
+			canPull = true
 		}
 	}
 	switch {
@@ -94,9 +100,10 @@ type access struct {
 	Effect   string `json:"effect,omitempty"`
 }
 
-// RobotCreated
+// RobotCreated, the Token field is set by the robot v1 API
 type robotCreated struct {
 	Secret       string    `json:"secret,omitempty"`
+	Token        string    `json:"token,omitempty"`
 	CreationTime time.Time `json:"creation_time,omitempty"`
 	Id           int       `json:"id,omitempty"`
 	ExpiresAt    int       `json:"expires_at,omitempty"`
@@ -104,6 +111,14 @@ type robotCreated struct {
 }
 
 func (r *registry) getRobotMembers(ctx context.Context, projectID int) ([]*robot, error) {
+	robots, err := r.cached(ctx, func() (interface{}, error) {
+		return r.fetchRobotMembers(ctx, projectID)
+	}, "projects", strconv.Itoa(projectID), "robots")
+	rs, _ := robots.([]*robot)
+	return rs, err
+}
+
+func (r *registry) fetchRobotMembers(ctx context.Context, projectID int) ([]*robot, error) {
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("%s/%d/robots", path, projectID)
 	r.logger.V(1).Info("creating new request", "url", url.String())
@@ -139,11 +154,66 @@ func (r *registry) getRobotMembers(ctx context.Context, projectID int) ([]*robot
 	return robotMembersResult, err
 }
 
-func (r *registry) createProjectRobotMember(ctx context.Context, robotMember *robot) (*robotCreated, error) {
+// robotV1 is the request body of the robot account creation in the robot v1
+// API.
+type robotV1 struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	ExpiresAt   int      `json:"expires_at,omitempty"`
+	Access      []access `json:"access"`
+}
+
+// hasRobotV2API checks whether the Harbor instance provides the robot account
+// v2 API.
+func (r *registry) hasRobotV2API(ctx context.Context) (bool, error) {
+	si, err := r.getSystemInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+	return si.atLeast(robotV2Version), nil
+}
+
+// robotName returns the name of the robot account as it is reported by Harbor.
+// The robot v2 API prefixes the name with the project name too.
+func robotName(robotV2 bool, projectName string, name string) string {
+	if robotV2 {
+		return fmt.Sprintf("robot$%s+%s", projectName, name)
+	}
+	return fmt.Sprintf("robot$%s", name)
+}
+
+// createProjectRobotMember creates the robot account. With the robot v1 API,
+// the project level robot account endpoint is used and the permissions of the
+// robot are converted to v1 accesses.
+func (r *registry) createProjectRobotMember(ctx context.Context, projectID int, robotMember *robot) (*robotCreated, error) {
+	defer r.invalidate(ctx, "projects", strconv.Itoa(projectID), "robots")
+	robotV2, err := r.hasRobotV2API(ctx)
+	if err != nil {
+		return nil, err
+	}
 	url := *r.parsedUrl
-	url.Path = "/api/v2.0/robots"
+	var reqBody interface{} = robotMember
+	if robotV2 {
+		url.Path = "/api/v2.0/robots"
+	} else {
+		url.Path = fmt.Sprintf("%s/%d/robots", path, projectID)
+		accesses := []access{}
+		for _, permission := range robotMember.Permissions {
+			for _, a := range permission.Access {
+				accesses = append(accesses, access{
+					Action:   a.Action,
+					Resource: fmt.Sprintf("/project/%d/%s", projectID, a.Resource),
+				})
+			}
+		}
+		reqBody = &robotV1{
+			Name:        robotMember.Name,
+			Description: robotMember.Description,
+			Access:      accesses,
+		}
+	}
 	reqBodyBuf := bytes.NewBuffer(nil)
-	err := json.NewEncoder(reqBodyBuf).Encode(robotMember)
+	err = json.NewEncoder(reqBodyBuf).Encode(reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -175,10 +245,14 @@ func (r *registry) createProjectRobotMember(ctx context.Context, robotMember *ro
 		}
 		r.logger.Info(b.String())
 	}
+	if robotResult.Secret == "" {
+		robotResult.Secret = robotResult.Token
+	}
 	return robotResult, err
 }
 
 func (r *registry) deleteProjectRobotMember(ctx context.Context, projectID int, robotMemberID int) error {
+	defer r.invalidate(ctx, "projects", strconv.Itoa(projectID), "robots")
 	url := *r.parsedUrl
 	url.Path = fmt.Sprintf("%s/%d/robots/%d", path, projectID, robotMemberID)
 	r.logger.V(1).Info("creating new request", "url", url.String())
@@ -199,3 +273,32 @@ func (r *registry) deleteProjectRobotMember(ctx context.Context, projectID int,
 	defer resp.Body.Close()
 	return nil
 }
+
+// updateProjectRobotMember updates the robot account with the robot v2 API,
+// keeping its secret.
+func (r *registry) updateProjectRobotMember(ctx context.Context, projectID int, robotMember *robot) error {
+	defer r.invalidate(ctx, "projects", strconv.Itoa(projectID), "robots")
+	url := *r.parsedUrl
+	url.Path = fmt.Sprintf("/api/v2.0/robots/%d", robotMember.Id)
+	reqBodyBuf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(reqBodyBuf).Encode(robotMember)
+	if err != nil {
+		return err
+	}
+	r.logger.V(1).Info("creating new request", "url", url.String())
+	req, err := http.NewRequest(http.MethodPut, url.String(), reqBodyBuf)
+	if err != nil {
+		return err
+	}
+
+	req.Header["Content-Type"] = []string{"application/json"}
+	req.SetBasicAuth(r.GetUsername(), r.GetPassword())
+
+	resp, err := r.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	return nil
+}
@@ -17,22 +17,26 @@
 package harbor
 
 import (
-	// "context"
-
+	"context"
 	"strings"
 
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
 )
 
 type scanner struct {
-	id        string
-	registry  *registry
-	name      string
-	url       string
-	isDefault bool
+	id               string
+	registry         *registry
+	name             string
+	url              string
+	isDefault        bool
+	auth             string
+	accessCredential string
+	useInternalAddr  bool
+	skipCertVerify   bool
 }
 
 var _ globalregistry.Scanner = &scanner{}
+var _ globalregistry.ScannerWithHealth = &scanner{}
 
 // func (s *scanner) Delete(ctx context.Context) error {
 // 	return s.registry.deleteScanner(ctx, s.id)
@@ -45,3 +49,8 @@ func (s *scanner) GetName() string {
 func (s *scanner) GetURL() string {
 	return s.url
 }
+
+// Ping implements the globalregistry.ScannerWithHealth interface.
+func (s *scanner) Ping(ctx context.Context) error {
+	return s.registry.pingScanner(ctx, s)
+}
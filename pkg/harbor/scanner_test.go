@@ -0,0 +1,94 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package harbor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// scannerHarbor serves two scanner registrations. The ping of the scanners
+// whose URL contains "down" fails.
+type scannerHarbor struct {
+	pinged []scannerRegistrationRequest
+}
+
+func (h *scannerHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == systemInfoPath:
+		w.Write([]byte(`{"harbor_version":"v2.3.0"}`))
+	case r.Method == http.MethodGet && r.URL.Path == scannersPath:
+		w.Write([]byte(`[
+			{"uuid":"1","name":"Trivy","url":"http://trivy:8080","auth":"Bearer","access_credential":"token"},
+			{"uuid":"2","name":"clair","url":"http://clair-down:8080"}
+		]`))
+	case r.Method == http.MethodPost && r.URL.Path == scannersPath+"/ping":
+		ping := scannerRegistrationRequest{}
+		json.NewDecoder(r.Body).Decode(&ping)
+		h.pinged = append(h.pinged, ping)
+		if strings.Contains(ping.Url, "down") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("Scanner registrations", func() {
+	var fake *scannerHarbor
+	var server *httptest.Server
+	var reg *registry
+
+	BeforeEach(func() {
+		fake = &scannerHarbor{}
+		server = httptest.NewServer(fake)
+		r, err := newRegistry(logr.Discard(), &testConfig{apiEndpoint: server.URL})
+		Expect(err).To(Succeed())
+		reg = r.(*registry)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists the registered scanners", func() {
+		scanners, err := reg.ListScanners(context.Background())
+		Expect(err).To(Succeed())
+		Expect(scanners).To(HaveLen(2))
+		Expect(scanners[0].GetName()).To(Equal("trivy"))
+		Expect(scanners[1].GetURL()).To(Equal("http://clair-down:8080"))
+	})
+	It("pings the registered scanners", func() {
+		scanners, err := reg.ListScanners(context.Background())
+		Expect(err).To(Succeed())
+		Expect(scanners[0].(globalregistry.ScannerWithHealth).Ping(context.Background())).To(Succeed())
+		Expect(scanners[1].(globalregistry.ScannerWithHealth).Ping(context.Background())).
+			To(MatchError(globalregistry.ErrTransient))
+		Expect(fake.pinged).To(HaveLen(2))
+		Expect(fake.pinged[0].Auth).To(Equal("Bearer"))
+		Expect(fake.pinged[0].AccessCredential).To(Equal("token"))
+	})
+})
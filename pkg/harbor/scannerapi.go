@@ -91,7 +91,7 @@ func (r *registry) createScanner(ctx context.Context, config globalregistry.Scan
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 201 {
-		return "", fmt.Errorf("scanner creation failed, %w", globalregistry.ErrRecoverableError)
+		return "", wrapError("create scanner", resp.StatusCode, fmt.Errorf("scanner creation failed, %w", globalregistry.ErrRecoverableError))
 	}
 
 	scannerID := strings.TrimPrefix(
@@ -188,16 +188,65 @@ func (r *registry) listScanners(ctx context.Context) ([]globalregistry.Scanner,
 
 	for _, scannerIterator := range scannerResult {
 		scanners = append(scanners, &scanner{
-			id:        scannerIterator.Uuid,
-			registry:  r,
-			name:      scannerIterator.Name,
-			url:       scannerIterator.Url,
-			isDefault: scannerIterator.IsDefault,
+			id:               scannerIterator.Uuid,
+			registry:         r,
+			name:             scannerIterator.Name,
+			url:              scannerIterator.Url,
+			isDefault:        scannerIterator.IsDefault,
+			auth:             scannerIterator.Auth,
+			accessCredential: scannerIterator.AccessCredential,
+			useInternalAddr:  scannerIterator.UseInternalAddr,
+			skipCertVerify:   scannerIterator.SkipCertVerify,
 		})
 	}
 	return scanners, err
 }
 
+// ListScanners implements the globalregistry.RegistryWithScanners interface.
+func (r *registry) ListScanners(ctx context.Context) ([]globalregistry.Scanner, error) {
+	return r.listScanners(ctx)
+}
+
+// pingScanner checks the connection between Harbor and the scanner adapter of
+// the registration.
+func (r *registry) pingScanner(ctx context.Context, s *scanner) error {
+	r.logger.V(1).Info("pingScanner invoked",
+		"scanner-name", s.name,
+	)
+	url := *r.parsedUrl
+	url.Path = scannersPath + "/ping"
+
+	reqBodyBuf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(reqBodyBuf).Encode(&scannerRegistrationRequest{
+		Name:             s.name,
+		Url:              s.url,
+		Auth:             s.auth,
+		AccessCredential: s.accessCredential,
+		UseInternalAddr:  s.useInternalAddr,
+		SkipCertVerify:   s.skipCertVerify,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url.String(), reqBodyBuf)
+	if err != nil {
+		return err
+	}
+
+	req.Header["Content-Type"] = []string{"application/json"}
+	req.SetBasicAuth(r.GetUsername(), r.GetPassword())
+	resp, err := r.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return wrapError("ping scanner", resp.StatusCode, fmt.Errorf("scanner %s is not healthy", s.name))
+	}
+	return nil
+}
+
 func (r *registry) setScannerForProject(ctx context.Context, projectID int, scannerID string) error {
 	r.logger.V(1).Info("setScannerOfProject invoked")
 	url := *r.parsedUrl
@@ -225,7 +274,7 @@ func (r *registry) setScannerForProject(ctx context.Context, projectID int, scan
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("failed to set scanner for project-id:%d, %w", projectID, globalregistry.ErrRecoverableError)
+		return wrapError("set project scanner", resp.StatusCode, fmt.Errorf("failed to set scanner for project-id:%d, %w", projectID, globalregistry.ErrRecoverableError))
 	}
 	return err
 }
@@ -300,7 +349,7 @@ func (r *registry) updateScanner(ctx context.Context, id string, targetScanner g
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("failed to update scanner, %w", globalregistry.ErrRecoverableError)
+		return wrapError("update scanner", resp.StatusCode, fmt.Errorf("failed to update scanner, %w", globalregistry.ErrRecoverableError))
 	}
 	return err
 }
@@ -0,0 +1,145 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+const systemInfoPath = "/api/v2.0/systeminfo"
+
+// The Harbor versions that change the behavior of the provider.
+const (
+	// minSupportedVersion is the first Harbor version providing the v2.0
+	// API.
+	minSupportedVersion = "2.0"
+
+	// oidcGroupVersion is the first Harbor version where OIDC groups can be
+	// added to the projects.
+	oidcGroupVersion = "2.1"
+
+	// robotV2Version is the first Harbor version providing the robot
+	// account v2 API. The earlier versions use the project level robot
+	// account (v1) API.
+	robotV2Version = "2.2"
+)
+
+// oidcAuthMode is the authentication mode of the Harbor instances that
+// authenticate the users with an OIDC provider.
+const oidcAuthMode = "oidc_auth"
+
+type systemInfo struct {
+	HarborVersion string `json:"harbor_version"`
+	AuthMode      string `json:"auth_mode"`
+}
+
+// version returns the Harbor version without the leading v and the build
+// suffix, e.g. v2.3.2-9d6b4f0 is returned as 2.3.2.
+func (si *systemInfo) version() string {
+	version := strings.TrimPrefix(si.HarborVersion, "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	return version
+}
+
+// atLeast checks whether the Harbor version is at least the given version. If
+// the version is not reported by Harbor, the latest version is assumed.
+func (si *systemInfo) atLeast(version string) bool {
+	return si.version() == "" ||
+		globalregistry.CompareVersions(si.version(), version) >= 0
+}
+
+// getSystemInfo returns the system information of the Harbor instance. The
+// system information is fetched with the first API call and then cached for
+// the lifetime of the registry. If Harbor does not provide the v2.0 API, an
+// error wrapping globalregistry.ErrUnsupportedVersion is returned.
+func (r *registry) getSystemInfo(ctx context.Context) (*systemInfo, error) {
+	r.systemInfoMu.Lock()
+	defer r.systemInfoMu.Unlock()
+	if r.systemInfo != nil {
+		return r.systemInfo, nil
+	}
+	url := *r.parsedUrl
+	url.Path = systemInfoPath
+	req, err := http.NewRequest(http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.SetBasicAuth(r.GetUsername(), r.GetPassword())
+
+	resp, err := r.do(ctx, req)
+	if errors.Is(err, globalregistry.ErrNotFound) {
+		return nil, wrapError("get system info", globalregistry.StatusCodeOf(err), fmt.Errorf(
+			"%s not found, Harbor %s or newer is required: %w",
+			systemInfoPath, minSupportedVersion, globalregistry.ErrUnsupportedVersion))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	si := &systemInfo{}
+	if err = json.NewDecoder(resp.Body).Decode(si); err != nil {
+		return nil, wrapError("get system info", resp.StatusCode, fmt.Errorf(
+			"cannot decode system info, Harbor %s or newer is required: %w",
+			minSupportedVersion, globalregistry.WithClass(err, globalregistry.ErrUnsupportedVersion)))
+	}
+	if !si.atLeast(minSupportedVersion) {
+		return nil, wrapError("get system info", resp.StatusCode, fmt.Errorf(
+			"Harbor version %s is not supported, Harbor %s or newer is required: %w",
+			si.HarborVersion, minSupportedVersion, globalregistry.ErrUnsupportedVersion))
+	}
+	r.logger.V(1).Info("Harbor version detected",
+		"version", si.HarborVersion,
+		"authMode", si.AuthMode,
+	)
+	r.systemInfo = si
+	return si, nil
+}
+
+// GetVersion implements the globalregistry.RegistryWithVersion interface.
+func (r *registry) GetVersion(ctx context.Context) (string, error) {
+	si, err := r.getSystemInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+	return si.version(), nil
+}
+
+// requireVersion returns an error wrapping globalregistry.ErrUnsupportedVersion
+// if the Harbor version is older than the given version.
+func (r *registry) requireVersion(ctx context.Context, version string, feature string) error {
+	si, err := r.getSystemInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if !si.atLeast(version) {
+		return wrapError(feature, 0, fmt.Errorf("%s requires Harbor %s or newer, found %s: %w",
+			feature, version, si.HarborVersion, globalregistry.ErrUnsupportedVersion))
+	}
+	return nil
+}
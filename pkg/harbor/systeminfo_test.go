@@ -0,0 +1,139 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package harbor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/transport"
+)
+
+type testConfig struct {
+	apiEndpoint string
+}
+
+var _ globalregistry.Registry = &testConfig{}
+
+func (c *testConfig) GetProvider() string                        { return "harbor" }
+func (c *testConfig) GetUsername() string                        { return "admin" }
+func (c *testConfig) GetPassword() string                        { return "admin" }
+func (c *testConfig) GetAPIEndpoint() string                     { return c.apiEndpoint }
+func (c *testConfig) GetName() string                            { return "test" }
+func (c *testConfig) GetOptions() globalregistry.RegistryOptions { return nil }
+func (c *testConfig) GetAnnotations() map[string]string {
+	return map[string]string{transport.RateLimitAnnotation: "0"}
+}
+func (c *testConfig) GetInsecureSkipTLSVerify() bool { return false }
+
+// fakeHarbor serves the systeminfo endpoint with the given version and records
+// the paths of the other requests.
+type fakeHarbor struct {
+	version  string
+	authMode string
+	requests []string
+}
+
+func (h *fakeHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == systemInfoPath && h.version == "":
+		w.WriteHeader(http.StatusNotFound)
+	case r.URL.Path == systemInfoPath:
+		json.NewEncoder(w).Encode(&systemInfo{
+			HarborVersion: h.version,
+			AuthMode:      h.authMode,
+		})
+	case r.Method == http.MethodPost:
+		h.requests = append(h.requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name":"robot$ci","token":"secret"}`))
+	default:
+		h.requests = append(h.requests, r.Method+" "+r.URL.Path)
+		w.Write([]byte("[]"))
+	}
+}
+
+var _ = Describe("Version detection", func() {
+	var fake *fakeHarbor
+	var server *httptest.Server
+	var reg *registry
+
+	BeforeEach(func() {
+		fake = &fakeHarbor{}
+		server = httptest.NewServer(fake)
+		r, err := newRegistry(logr.Discard(), &testConfig{apiEndpoint: server.URL})
+		Expect(err).To(Succeed())
+		reg = r.(*registry)
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("reports the Harbor version", func() {
+		fake.version = "v2.3.2-9d6b4f0"
+		Expect(reg.GetVersion(context.Background())).To(Equal("2.3.2"))
+	})
+	It("rejects Harbor versions older than 2.0", func() {
+		fake.version = "v1.10.2"
+		_, err := reg.ListProjects(context.Background())
+		Expect(err).To(MatchError(globalregistry.ErrUnsupportedVersion))
+		Expect(fake.requests).To(BeEmpty())
+	})
+	It("rejects Harbor instances without the v2.0 API", func() {
+		_, err := reg.ListProjects(context.Background())
+		Expect(err).To(MatchError(globalregistry.ErrUnsupportedVersion))
+	})
+	It("uses the robot v1 API before Harbor 2.2", func() {
+		fake.version = "v2.1.0"
+		p := &project{id: 1, Name: "app", registry: reg}
+		creds, err := p.AssignMember(context.Background(), &robot{
+			Name:        "ci",
+			Permissions: []robotPermission{{Access: robotRoleToAccess("PullOnly")}},
+		})
+		Expect(err).To(Succeed())
+		Expect(creds.Password).To(Equal("secret"))
+		Expect(fake.requests).To(Equal([]string{"POST " + path + "/1/robots"}))
+	})
+	It("uses the robot v2 API since Harbor 2.2", func() {
+		fake.version = "v2.2.0"
+		p := &project{id: 1, Name: "app", registry: reg}
+		_, err := p.AssignMember(context.Background(), &robot{
+			Name:        "ci",
+			Permissions: []robotPermission{{Access: robotRoleToAccess("PullOnly")}},
+		})
+		Expect(err).To(Succeed())
+		Expect(fake.requests).To(Equal([]string{"POST /api/v2.0/robots"}))
+	})
+	It("refuses OIDC groups before Harbor 2.1", func() {
+		fake.version = "v2.0.5"
+		fake.authMode = oidcAuthMode
+		_, err := reg.userGroupOf(context.Background(), &projectMember{EntityName: "devs"})
+		Expect(err).To(MatchError(globalregistry.ErrUnsupportedVersion))
+
+		reg.systemInfo.HarborVersion = "v2.1.0"
+		ug, err := reg.userGroupOf(context.Background(), &projectMember{EntityName: "devs"})
+		Expect(err).To(Succeed())
+		Expect(ug.GroupType).To(Equal(oidcGroupType))
+	})
+})
@@ -0,0 +1,106 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// health package implements the HTTP health and readiness probes. The probes
+// are composed of named checks provided by the subsystems.
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Check is a function reporting the state of a subsystem. It returns nil if
+// the subsystem is healthy (or ready).
+type Check func() error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checks is a set of named checks. It implements the http.Handler interface:
+// the response status code is 200 if all checks pass and 503 otherwise. The
+// response body lists the result of every check.
+type Checks struct {
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+var _ http.Handler = &Checks{}
+
+// Add adds a named check to the set.
+func (c *Checks) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{
+		name:  name,
+		check: check,
+	})
+}
+
+// Run runs the checks. It returns nil if all checks pass, otherwise the
+// returned error describes the first failing check.
+func (c *Checks) Run() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, nc := range c.checks {
+		if err := nc.check(); err != nil {
+			return fmt.Errorf("%s: %w", nc.name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Checks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status := http.StatusOK
+	body := ""
+	for _, nc := range c.checks {
+		if err := nc.check(); err != nil {
+			status = http.StatusServiceUnavailable
+			body += fmt.Sprintf("[-] %s failed: %s\n", nc.name, err)
+		} else {
+			body += fmt.Sprintf("[+] %s ok\n", nc.name)
+		}
+	}
+	if status == http.StatusOK {
+		body += "ok\n"
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+// Probes contains the liveness and readiness checks of a process.
+type Probes struct {
+	// Healthz contains the liveness checks. A failing liveness check
+	// indicates that the process shall be restarted.
+	Healthz Checks
+
+	// Readyz contains the readiness checks. A failing readiness check
+	// indicates that the process cannot serve yet.
+	Readyz Checks
+}
+
+// Register registers the /healthz and /readyz endpoints in the mux.
+func (p *Probes) Register(mux *http.ServeMux) {
+	mux.Handle("/healthz", &p.Healthz)
+	mux.Handle("/readyz", &p.Readyz)
+}
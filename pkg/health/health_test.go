@@ -0,0 +1,60 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbes(t *testing.T) {
+	probes := &Probes{}
+	var informersSynced bool
+	probes.Healthz.Add("loop", func() error { return nil })
+	probes.Readyz.Add("informers", func() error {
+		if !informersSynced {
+			return errors.New("not synced")
+		}
+		return nil
+	})
+	mux := http.NewServeMux()
+	probes.Register(mux)
+
+	get := func(path string) (int, string) {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code, recorder.Body.String()
+	}
+
+	if code, body := get("/healthz"); code != http.StatusOK || !strings.Contains(body, "[+] loop ok") {
+		t.Errorf("unexpected /healthz response: %d %q", code, body)
+	}
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable ||
+		!strings.Contains(body, "[-] informers failed: not synced") {
+		t.Errorf("unexpected /readyz response: %d %q", code, body)
+	}
+	if err := probes.Readyz.Run(); err == nil || err.Error() != "informers: not synced" {
+		t.Errorf("unexpected readiness error: %v", err)
+	}
+	informersSynced = true
+	if code, body := get("/readyz"); code != http.StatusOK || !strings.HasSuffix(body, "ok\n") {
+		t.Errorf("unexpected /readyz response: %d %q", code, body)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// metrics package defines the Prometheus metrics of registryman and the HTTP
+// handler exposing them.
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "registryman"
+
+// The values of the result label.
+const (
+	ResultSuccess = "success"
+	ResultSkipped = "skipped"
+	ResultFailure = "failure"
+)
+
+var (
+	// Registry is the Prometheus registry of the registryman metrics.
+	Registry = prometheus.NewRegistry()
+
+	// ActionsTotal counts the reconciliation actions performed on the
+	// registries by action type and result.
+	ActionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "actions_total",
+		Help:      "Number of reconciliation actions performed, by registry, action type and result.",
+	}, []string{"registry", "action", "result"})
+
+	// ReconcileDuration observes the duration of the registry
+	// reconciliations.
+	ReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of the registry reconciliations, by registry and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"registry", "result"})
+
+	// ProviderRequestDuration observes the duration of the HTTP requests
+	// sent to the registry APIs. The code label is the HTTP status code or
+	// "error" if no response was received.
+	ProviderRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Duration of the registry API requests, by provider, method, endpoint and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "method", "endpoint", "code"})
+
+	// PendingActions shows the number of actions needed to bring the
+	// registry to the expected state, as found by the last reconciliation.
+	PendingActions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_actions",
+		Help:      "Number of actions needed to reach the expected state of the registry.",
+	}, []string{"registry"})
+
+	// ProjectStorageUsed shows the storage used by the projects, as
+	// reported in the registry status.
+	ProjectStorageUsed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "project_storage_used_bytes",
+		Help:      "Storage used by the project.",
+	}, []string{"registry", "project"})
+
+	// AdmissionReviewsTotal counts the admission reviews of the validating
+	// webhook by operation and result.
+	AdmissionReviewsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "admission_reviews_total",
+		Help:      "Number of admission reviews, by operation and whether the request was allowed.",
+	}, []string{"operation", "allowed"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ActionsTotal,
+		ReconcileDuration,
+		ProviderRequestDuration,
+		PendingActions,
+		ProjectStorageUsed,
+		AdmissionReviewsTotal,
+	)
+}
+
+// Handler returns the HTTP handler serving the metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+var (
+	projectsMu sync.Mutex
+	projects   = map[string]map[string]bool{}
+)
+
+// SetProjectStorageUsed sets the storage used by the projects of the registry.
+// The series of the projects that are no longer present are removed.
+func SetProjectStorageUsed(registry string, storageUsed map[string]int) {
+	projectsMu.Lock()
+	defer projectsMu.Unlock()
+	for project := range projects[registry] {
+		if _, found := storageUsed[project]; !found {
+			ProjectStorageUsed.DeleteLabelValues(registry, project)
+		}
+	}
+	known := make(map[string]bool, len(storageUsed))
+	for project, used := range storageUsed {
+		ProjectStorageUsed.WithLabelValues(registry, project).Set(float64(used))
+		known[project] = true
+	}
+	projects[registry] = known
+}
+
+// endpointSegments are the static path segments of the provider APIs. The
+// other segments are names or IDs of the remote resources.
+var endpointSegments = map[string]bool{
+	"_catalog":     true,
+	"access":       true,
+	"acr":          true,
+	"api":          true,
+	"artifactory":  true,
+	"groups":       true,
+	"ldap":         true,
+	"members":      true,
+	"permissions":  true,
+	"ping":         true,
+	"policies":     true,
+	"projects":     true,
+	"registries":   true,
+	"replication":  true,
+	"repositories": true,
+	"robots":       true,
+	"scanner":      true,
+	"scanners":     true,
+	"search":       true,
+	"security":     true,
+	"storage":      true,
+	"summary":      true,
+	"systeminfo":   true,
+	"usergroups":   true,
+	"users":        true,
+	"v1":           true,
+	"v2":           true,
+	"v2.0":         true,
+}
+
+// EndpointOf returns the endpoint label of an API request path. To limit the
+// number of series, the numeric path segments (e.g. the project IDs) are
+// replaced with {id} and the other segments that are not static parts of the
+// provider APIs (e.g. the project, repository and scanner names) with {name}.
+// Consecutive names (e.g. a folder path) are collapsed into one {name}.
+func EndpointOf(path string) string {
+	segments := strings.Split(path, "/")
+	endpoint := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		switch {
+		case segment == "" || endpointSegments[segment]:
+			endpoint = append(endpoint, segment)
+		case strings.Trim(segment, "0123456789") == "":
+			endpoint = append(endpoint, "{id}")
+		case len(endpoint) > 0 && endpoint[len(endpoint)-1] == "{name}":
+		default:
+			endpoint = append(endpoint, "{name}")
+		}
+	}
+	return strings.Join(endpoint, "/")
+}
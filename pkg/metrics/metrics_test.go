@@ -0,0 +1,58 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEndpointOf(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/api/v2.0/projects":                               "/api/v2.0/projects",
+		"/api/v2.0/projects/12/members/3":                  "/api/v2.0/projects/{id}/members/{id}",
+		"/api/v2.0/projects/app/repositories":              "/api/v2.0/projects/{name}/repositories",
+		"/api/v2.0/scanners/3f1e-9a2b":                     "/api/v2.0/scanners/{name}",
+		"/artifactory/api/repositories/app-docker":         "/artifactory/api/repositories/{name}",
+		"/artifactory/api/security/permissions/docker_app": "/artifactory/api/security/permissions/{name}",
+		"/artifactory/api/storage/docker/app/os/ubuntu":    "/artifactory/api/storage/{name}",
+		"/access/api/v1/projects/app/users":                "/access/api/v1/projects/{name}/users",
+		"/acr/v1/os-images/ubuntu":                         "/acr/v1/{name}",
+		"/v2/_catalog":                                     "/v2/_catalog",
+		"":                                                 "",
+	} {
+		if endpoint := EndpointOf(path); endpoint != expected {
+			t.Errorf("EndpointOf(%q) = %q, expected %q", path, endpoint, expected)
+		}
+	}
+}
+
+func TestSetProjectStorageUsed(t *testing.T) {
+	SetProjectStorageUsed("harbor", map[string]int{"app": 100, "os": 200})
+	SetProjectStorageUsed("acr", map[string]int{"app": 300})
+	if value := testutil.ToFloat64(ProjectStorageUsed.WithLabelValues("harbor", "os")); value != 200 {
+		t.Errorf("storage used by harbor/os is %f, expected 200", value)
+	}
+	SetProjectStorageUsed("harbor", map[string]int{"app": 150})
+	if count := testutil.CollectAndCount(ProjectStorageUsed); count != 2 {
+		t.Errorf("%d series are collected, expected 2", count)
+	}
+	if value := testutil.ToFloat64(ProjectStorageUsed.WithLabelValues("harbor", "app")); value != 150 {
+		t.Errorf("storage used by harbor/app is %f, expected 150", value)
+	}
+}
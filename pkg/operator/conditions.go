@@ -0,0 +1,157 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// The reasons of the Registry conditions.
+const (
+	reasonConnected           = "Connected"
+	reasonConnectionFailed    = "ConnectionFailed"
+	reasonCredentialsAccepted = "CredentialsAccepted"
+	reasonCredentialsRejected = "CredentialsRejected"
+	reasonSyncSucceeded       = "SyncSucceeded"
+	reasonSyncFailed          = "SyncFailed"
+	reasonInSync              = "InSync"
+	reasonActionsPending      = "ActionsPending"
+	reasonNotCompared         = "NotCompared"
+	reasonPaused              = "Paused"
+	reasonActive              = "Active"
+)
+
+// registryStatusWriter is implemented by the stores that can persist the
+// status of the Registry resources.
+type registryStatusWriter interface {
+	registry.ApiObjectProvider
+
+	// UpdateRegistryStatus persists the registry status of the given
+	// Registry resource.
+	UpdateRegistryStatus(context.Context, *api.Registry) error
+}
+
+// updateRegistryStatus modifies the status of the registry with mutate and
+// persists it. If the Registry resource has been modified in the meantime, the
+// latest version is read and mutate is applied again, so the fields not
+// touched by mutate are preserved.
+func updateRegistryStatus(ctx context.Context, store registryStatusWriter, reg *api.Registry, mutate func(*api.RegistryStatus)) error {
+	reg = reg.DeepCopy()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if reg.Status == nil {
+			reg.Status = &api.RegistryStatus{}
+		}
+		if reg.Status.Projects == nil {
+			reg.Status.Projects = []api.ProjectStatus{}
+		}
+		mutate(reg.Status)
+		err := store.UpdateRegistryStatus(ctx, reg)
+		if apierrors.IsConflict(err) {
+			latest := getRegistry(ctx, store, reg.GetName())
+			if latest == nil {
+				return fmt.Errorf("registry %s not found", reg.GetName())
+			}
+			reg = latest.DeepCopy()
+		}
+		return err
+	})
+}
+
+func setCondition(status *api.RegistryStatus, generation int64, conditionType string, conditionStatus metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// setConnectionConditions sets the Reachable and Authenticated conditions
+// according to the outcome of the registry API calls. The errors that tell
+// nothing about the connection leave the conditions intact.
+func setConnectionConditions(status *api.RegistryStatus, generation int64, err error) {
+	switch {
+	case err == nil:
+		setCondition(status, generation, api.RegistryReachable, metav1.ConditionTrue,
+			reasonConnected, "The registry API is reachable")
+		setCondition(status, generation, api.RegistryAuthenticated, metav1.ConditionTrue,
+			reasonCredentialsAccepted, "The registry accepts the credentials")
+	case errors.Is(err, globalregistry.ErrUnauthorized), errors.Is(err, globalregistry.ErrForbidden):
+		setCondition(status, generation, api.RegistryReachable, metav1.ConditionTrue,
+			reasonConnected, "The registry API is reachable")
+		setCondition(status, generation, api.RegistryAuthenticated, metav1.ConditionFalse,
+			reasonCredentialsRejected, err.Error())
+	case errors.Is(err, globalregistry.ErrTransient):
+		setCondition(status, generation, api.RegistryReachable, metav1.ConditionFalse,
+			reasonConnectionFailed, err.Error())
+		setCondition(status, generation, api.RegistryAuthenticated, metav1.ConditionUnknown,
+			reasonConnectionFailed, "The registry API is not reachable")
+	}
+}
+
+// setSyncConditions records the outcome of a reconciliation in the Synced,
+// Drifted and Paused conditions, in LastSyncTime, ObservedGeneration and
+// LastError. The reconciliation of a paused registry is not recorded as a
+// sync.
+func setSyncConditions(status *api.RegistryStatus, generation int64, result syncResult, err error) {
+	if result.paused {
+		setCondition(status, generation, api.RegistryPaused, metav1.ConditionTrue,
+			reasonPaused, "The reconciliation of the registry is paused")
+	} else {
+		setCondition(status, generation, api.RegistryPaused, metav1.ConditionFalse,
+			reasonActive, "The registry is reconciled")
+	}
+	switch {
+	case err == nil && result.paused:
+		status.LastError = ""
+		setCondition(status, generation, api.RegistrySynced, metav1.ConditionUnknown,
+			reasonPaused, "The reconciliation of the registry is paused")
+	case err == nil:
+		now := metav1.Now()
+		status.LastSyncTime = &now
+		status.ObservedGeneration = generation
+		status.LastError = ""
+		setCondition(status, generation, api.RegistrySynced, metav1.ConditionTrue,
+			reasonSyncSucceeded, "The registry is reconciled")
+	default:
+		status.LastError = err.Error()
+		setCondition(status, generation, api.RegistrySynced, metav1.ConditionFalse,
+			reasonSyncFailed, err.Error())
+	}
+	switch {
+	case !result.compared:
+		setCondition(status, generation, api.RegistryDrifted, metav1.ConditionUnknown,
+			reasonNotCompared, "The actual state of the registry could not be compared")
+	case result.pending == 0:
+		setCondition(status, generation, api.RegistryDrifted, metav1.ConditionFalse,
+			reasonInSync, "The registry is in the expected state")
+	default:
+		setCondition(status, generation, api.RegistryDrifted, metav1.ConditionTrue,
+			reasonActionsPending, fmt.Sprintf("%d actions are not performed", result.pending))
+	}
+}
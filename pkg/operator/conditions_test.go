@@ -0,0 +1,169 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// statusTestResources persists the registry statuses. The first conflicts
+// updates fail with a conflict error.
+type statusTestResources struct {
+	*syncTestResources
+
+	statusMu         sync.Mutex
+	conflicts        int
+	statuses         map[string]*api.RegistryStatus
+	projectStatuses  map[string]*api.ProjectProvisioningStatus
+	projectConflicts int
+	scannerStatuses  map[string]*api.ScannerProvisioningStatus
+}
+
+var _ registryStatusWriter = &statusTestResources{}
+var _ projectStatusWriter = &statusTestResources{}
+var _ scannerStatusWriter = &statusTestResources{}
+
+func (sr *statusTestResources) UpdateRegistryStatus(_ context.Context, reg *api.Registry) error {
+	sr.statusMu.Lock()
+	defer sr.statusMu.Unlock()
+	if sr.conflicts > 0 {
+		sr.conflicts--
+		return apierrors.NewConflict(schema.GroupResource{
+			Group:    api.SchemeGroupVersion.Group,
+			Resource: "registries",
+		}, reg.GetName(), errors.New("the object has been modified"))
+	}
+	sr.statuses[reg.GetName()] = reg.Status.DeepCopy()
+	return nil
+}
+
+func (sr *statusTestResources) UpdateProjectStatus(_ context.Context, project *api.Project) error {
+	sr.statusMu.Lock()
+	defer sr.statusMu.Unlock()
+	if sr.projectConflicts > 0 {
+		sr.projectConflicts--
+		return apierrors.NewConflict(schema.GroupResource{
+			Group:    api.SchemeGroupVersion.Group,
+			Resource: "projects",
+		}, project.GetName(), errors.New("the object has been modified"))
+	}
+	sr.projectStatuses[project.GetName()] = project.Status.DeepCopy()
+	return nil
+}
+
+func (sr *statusTestResources) UpdateScannerStatus(_ context.Context, scanner *api.Scanner) error {
+	sr.statusMu.Lock()
+	defer sr.statusMu.Unlock()
+	sr.scannerStatuses[scanner.GetName()] = scanner.Status.DeepCopy()
+	return nil
+}
+
+func conditionStatus(status *api.RegistryStatus, conditionType string) metav1.ConditionStatus {
+	condition := meta.FindStatusCondition(status.Conditions, conditionType)
+	if condition == nil {
+		return ""
+	}
+	return condition.Status
+}
+
+func TestSyncRegistryConditions(t *testing.T) {
+	recorder.reset()
+	healthy := newSyncTestRegistry("healthy", "GlobalHub")
+	healthy.Generation = 3
+	resources := &statusTestResources{
+		syncTestResources: &syncTestResources{
+			registries: []*api.Registry{
+				healthy,
+				newSyncTestRegistry("broken-local", "Local"),
+			},
+			events: map[string][]string{},
+		},
+		conflicts:       1,
+		statuses:        map[string]*api.RegistryStatus{},
+		projectStatuses: map[string]*api.ProjectProvisioningStatus{},
+	}
+	if err := FullResync(context.Background(), resources, false); err == nil {
+		t.Fatal("the error of the broken registry is not returned")
+	}
+	if resources.conflicts != 0 {
+		t.Error("the conflicting status update is not retried")
+	}
+
+	status, ok := resources.statuses["healthy"]
+	if !ok {
+		t.Fatal("the status of the healthy registry is not updated")
+	}
+	for conditionType, expected := range map[string]metav1.ConditionStatus{
+		api.RegistryReachable:     metav1.ConditionTrue,
+		api.RegistryAuthenticated: metav1.ConditionTrue,
+		api.RegistrySynced:        metav1.ConditionTrue,
+		api.RegistryDrifted:       metav1.ConditionFalse,
+	} {
+		if actual := conditionStatus(status, conditionType); actual != expected {
+			t.Errorf("healthy registry: condition %s is %q, expected %q", conditionType, actual, expected)
+		}
+	}
+	if status.LastSyncTime == nil || status.ObservedGeneration != 3 || status.LastError != "" {
+		t.Errorf("healthy registry: unexpected sync fields: %+v", status)
+	}
+
+	status, ok = resources.statuses["broken-local"]
+	if !ok {
+		t.Fatal("the status of the broken registry is not updated")
+	}
+	for conditionType, expected := range map[string]metav1.ConditionStatus{
+		api.RegistryReachable:     metav1.ConditionFalse,
+		api.RegistryAuthenticated: metav1.ConditionUnknown,
+		api.RegistrySynced:        metav1.ConditionFalse,
+		api.RegistryDrifted:       metav1.ConditionUnknown,
+	} {
+		if actual := conditionStatus(status, conditionType); actual != expected {
+			t.Errorf("broken registry: condition %s is %q, expected %q", conditionType, actual, expected)
+		}
+	}
+	if status.LastSyncTime != nil || !strings.Contains(status.LastError, "connection refused") {
+		t.Errorf("broken registry: unexpected sync fields: %+v", status)
+	}
+}
+
+func TestSetConnectionConditions(t *testing.T) {
+	status := &api.RegistryStatus{}
+	setConnectionConditions(status, 1, nil)
+	unauthorized := globalregistry.WrapError(syncTestProvider, "list projects", 401,
+		globalregistry.ErrUnauthorized)
+	setConnectionConditions(status, 1, unauthorized)
+	if conditionStatus(status, api.RegistryReachable) != metav1.ConditionTrue ||
+		conditionStatus(status, api.RegistryAuthenticated) != metav1.ConditionFalse {
+		t.Errorf("unexpected conditions of a rejected credential: %+v", status.Conditions)
+	}
+	// unclassified errors leave the conditions intact
+	setConnectionConditions(status, 1, errors.New("invalid configuration"))
+	if conditionStatus(status, api.RegistryAuthenticated) != metav1.ConditionFalse {
+		t.Errorf("conditions are changed by an unclassified error: %+v", status.Conditions)
+	}
+}
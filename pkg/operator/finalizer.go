@@ -0,0 +1,287 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+)
+
+// finalizerWriter is implemented by the stores that can persist the
+// finalizers of the Registry and Project resources.
+type finalizerWriter interface {
+	registry.ApiObjectProvider
+	reconciler.SideEffectPerformer
+
+	// UpdateRegistry persists the metadata and the spec of the given
+	// Registry resource.
+	UpdateRegistry(context.Context, *api.Registry) error
+
+	// UpdateProject persists the metadata and the spec of the given
+	// Project resource.
+	UpdateProject(context.Context, *api.Project) error
+}
+
+func hasFinalizer(obj metav1.Object) bool {
+	for _, finalizer := range obj.GetFinalizers() {
+		if finalizer == api.CleanupFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// setFinalizer adds (present is true) or removes the cleanup finalizer of a
+// Registry or Project resource. If the resource has been modified in the
+// meantime, the latest version is read and the update is repeated.
+func setFinalizer(ctx context.Context, store finalizerWriter, obj runtime.Object, present bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj = obj.DeepCopyObject()
+		metaObj := obj.(metav1.Object)
+		if hasFinalizer(metaObj) == present {
+			return nil
+		}
+		finalizers := []string{}
+		for _, finalizer := range metaObj.GetFinalizers() {
+			if finalizer != api.CleanupFinalizer {
+				finalizers = append(finalizers, finalizer)
+			}
+		}
+		if present {
+			finalizers = append(finalizers, api.CleanupFinalizer)
+		}
+		metaObj.SetFinalizers(finalizers)
+		var err error
+		var latest runtime.Object
+		switch o := obj.(type) {
+		case *api.Registry:
+			err = store.UpdateRegistry(ctx, o)
+			if apierrors.IsConflict(err) {
+				if reg := getRegistry(ctx, store, o.GetName()); reg != nil {
+					latest = reg
+				}
+			}
+		case *api.Project:
+			err = store.UpdateProject(ctx, o)
+			if apierrors.IsConflict(err) {
+				if project := getProject(ctx, store, o.GetName()); project != nil {
+					latest = project
+				}
+			}
+		default:
+			return fmt.Errorf("resource of type %T has no cleanup finalizer", obj)
+		}
+		if apierrors.IsConflict(err) {
+			if latest == nil {
+				return fmt.Errorf("%s not found", metaObj.GetName())
+			}
+			obj = latest
+		}
+		return err
+	})
+}
+
+// ensureFinalizers adds the cleanup finalizer to the registry and to the
+// projects expected at the registry, unless they are being deleted.
+func ensureFinalizers(ctx context.Context, store finalizerWriter, apiRegistry *api.Registry) error {
+	if apiRegistry.GetDeletionTimestamp() == nil {
+		if err := setFinalizer(ctx, store, apiRegistry, true); err != nil {
+			return err
+		}
+	}
+	registries := store.GetRegistries(ctx)
+	for _, project := range store.GetProjects(ctx) {
+		if project.GetDeletionTimestamp() != nil {
+			continue
+		}
+		for _, reg := range registriesOfProject(project, registries) {
+			if reg.GetName() == apiRegistry.GetName() {
+				if err := setFinalizer(ctx, store, project, true); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// projectRemovedFrom checks whether the project has been removed from the
+// registry. The registries that cannot delete projects and the projects that
+// are left in place by the project deletion policy of the registry are
+// considered to be cleaned up.
+func projectRemovedFrom(ctx context.Context, store finalizerWriter, apiRegistry *api.Registry, projectName string) (bool, error) {
+	expectedRegistry := registry.New(apiRegistry, store)
+	ownership, err := ownershipOf(ctx, store, expectedRegistry)
+	if err != nil {
+		return false, err
+	}
+	if ownership.NeedsSeeding() {
+		// the registry has not been reconciled since the upgrade, the
+		// project may be owned
+		return false, nil
+	}
+	if !ownership.CanRemoveProject(projectName) {
+		return true, nil
+	}
+	realRegistry, err := expectedRegistry.ToReal()
+	if err != nil {
+		return false, err
+	}
+	version := ""
+	if regWithVersion, ok := realRegistry.(globalregistry.RegistryWithVersion); ok {
+		if version, err = regWithVersion.GetVersion(ctx); err != nil {
+			return false, err
+		}
+	}
+	capabilities, err := globalregistry.GetCapabilities(realRegistry)
+	if err != nil {
+		return false, err
+	}
+	if !capabilities.RegistryCapabilities(version).CanDeleteProject {
+		return true, nil
+	}
+	regWithProjects, ok := realRegistry.(globalregistry.RegistryWithProjects)
+	if !ok {
+		return true, nil
+	}
+	project, err := regWithProjects.GetProjectByName(ctx, projectName)
+	if errors.Is(err, globalregistry.ErrNotFound) {
+		return true, nil
+	}
+	return project == nil, err
+}
+
+// releaseProjects removes the cleanup finalizer of the deleted projects of the
+// registry, if they have been removed from all of their registries. It is
+// invoked after the registry has reached its expected state, i.e. the projects
+// have been removed from it. The paused projects are left untouched by the
+// reconciliation, so they keep their finalizer until they are resumed.
+func releaseProjects(ctx context.Context, store finalizerWriter, apiRegistry *api.Registry, paused pauseState) error {
+	eventRecorder, canRecordEvent := store.(EventRecorder)
+	registries := store.GetRegistries(ctx)
+ProjectLoop:
+	for _, project := range store.GetProjects(ctx) {
+		if project.GetDeletionTimestamp() == nil || !hasFinalizer(project) {
+			continue
+		}
+		if paused.projectPaused(project.GetName()) {
+			logger.V(1).Info("deleted project is paused, it is not finalized",
+				"project", project.GetName(),
+			)
+			continue
+		}
+		targets := registriesOfProject(project, registries)
+		isTarget := api.IsOrphaned(project) || len(targets) == 0
+		for _, target := range targets {
+			isTarget = isTarget || target.GetName() == apiRegistry.GetName()
+		}
+		if !isTarget {
+			continue
+		}
+		if api.IsOrphaned(project) {
+			if err := disownProject(ctx, store, targets, project.GetName()); err != nil {
+				return err
+			}
+		} else {
+			for _, target := range targets {
+				if target.GetName() == apiRegistry.GetName() {
+					// it has just been reconciled
+					continue
+				}
+				removed, err := projectRemovedFrom(ctx, store, target, project.GetName())
+				if err != nil || !removed {
+					logger.V(1).Info("project is not yet removed from registry",
+						"project", project.GetName(),
+						"registry_name", target.GetName(),
+						"error", err,
+					)
+					continue ProjectLoop
+				}
+			}
+		}
+		if err := setFinalizer(ctx, store, project, false); err != nil {
+			return err
+		}
+		logger.Info("project finalized",
+			"project", project.GetName(),
+			"orphaned", api.IsOrphaned(project),
+		)
+		if !canRecordEvent {
+			continue
+		}
+		if api.IsOrphaned(project) {
+			eventRecorder.RecordEventNormal(project, "Orphaned",
+				"Project released, the remote resources are left intact")
+		} else {
+			eventRecorder.RecordEventNormal(project, "CleanedUp",
+				"Project removed from the registries")
+		}
+	}
+	return nil
+}
+
+// disownProject removes the project from the ownership records of the
+// registries, so that it is not removed from them when the Project resource is
+// gone.
+func disownProject(ctx context.Context, store finalizerWriter, registries []*api.Registry, projectName string) error {
+	for _, reg := range registries {
+		ownership, err := ownershipOf(ctx, store, registry.New(reg, store))
+		if err != nil {
+			return err
+		}
+		if err = ownership.Disown(ctx, store, projectName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseRegistry removes the cleanup finalizer of a deleted registry, so that
+// the Registry resource can be removed.
+func releaseRegistry(ctx context.Context, store finalizerWriter, apiRegistry *api.Registry) error {
+	if !hasFinalizer(apiRegistry) {
+		return nil
+	}
+	if err := setFinalizer(ctx, store, apiRegistry, false); err != nil {
+		return err
+	}
+	logger.Info("registry finalized",
+		"registry_name", apiRegistry.GetName(),
+		"orphaned", api.IsOrphaned(apiRegistry),
+	)
+	if eventRecorder, ok := store.(EventRecorder); ok {
+		if api.IsOrphaned(apiRegistry) {
+			eventRecorder.RecordEventNormal(apiRegistry, "Orphaned",
+				"Registry released, the remote resources are left intact")
+		} else {
+			eventRecorder.RecordEventNormal(apiRegistry, "CleanedUp",
+				"Projects removed from the registry")
+		}
+	}
+	return nil
+}
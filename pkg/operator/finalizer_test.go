@@ -0,0 +1,209 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// finalizerTestResources persists the updated Registry and Project resources.
+// The first conflicts updates fail with a conflict error.
+type finalizerTestResources struct {
+	*syncTestResources
+
+	conflicts int
+}
+
+var _ finalizerWriter = &finalizerTestResources{}
+
+func (fr *finalizerTestResources) conflict(resource, name string) error {
+	if fr.conflicts == 0 {
+		return nil
+	}
+	fr.conflicts--
+	return apierrors.NewConflict(schema.GroupResource{
+		Group:    api.SchemeGroupVersion.Group,
+		Resource: resource,
+	}, name, errors.New("the object has been modified"))
+}
+
+func (fr *finalizerTestResources) UpdateRegistry(_ context.Context, reg *api.Registry) error {
+	if err := fr.conflict("registries", reg.GetName()); err != nil {
+		return err
+	}
+	for i, r := range fr.registries {
+		if r.GetName() == reg.GetName() {
+			fr.registries[i] = reg.DeepCopy()
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{Resource: "registries"}, reg.GetName())
+}
+
+func (fr *finalizerTestResources) UpdateProject(_ context.Context, project *api.Project) error {
+	if err := fr.conflict("projects", project.GetName()); err != nil {
+		return err
+	}
+	for i, p := range fr.projects {
+		if p.GetName() == project.GetName() {
+			fr.projects[i] = project.DeepCopy()
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{Resource: "projects"}, project.GetName())
+}
+
+func newFinalizerTestProject(name string, localRegistries ...string) *api.Project {
+	return &api.Project{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: &api.ProjectSpec{
+			Type:            api.LocalProjectType,
+			LocalRegistries: localRegistries,
+		},
+	}
+}
+
+// deleted marks the resource as being deleted.
+func deleted(obj metav1.Object, orphan bool) {
+	now := metav1.Now()
+	obj.SetDeletionTimestamp(&now)
+	obj.SetFinalizers([]string{"other", api.CleanupFinalizer})
+	if orphan {
+		obj.SetAnnotations(map[string]string{api.OrphanAnnotation: "true"})
+	}
+}
+
+func TestEnsureFinalizers(t *testing.T) {
+	terminating := newFinalizerTestProject("terminating", "local")
+	deleted(terminating, false)
+	resources := &finalizerTestResources{
+		syncTestResources: &syncTestResources{
+			registries: []*api.Registry{
+				newSyncTestRegistry("local", "Local"),
+				newSyncTestRegistry("other", "Local"),
+			},
+			projects: []*api.Project{
+				newFinalizerTestProject("app", "local", "other"),
+				newFinalizerTestProject("unrelated", "other"),
+				terminating,
+			},
+		},
+		conflicts: 1,
+	}
+	if err := ensureFinalizers(context.Background(), resources, resources.registries[0]); err != nil {
+		t.Fatal(err)
+	}
+	if resources.conflicts != 0 {
+		t.Error("the conflicting update is not retried")
+	}
+	for _, obj := range []metav1.Object{resources.registries[0], resources.projects[0]} {
+		if !hasFinalizer(obj) {
+			t.Errorf("finalizer is not added to %s", obj.GetName())
+		}
+	}
+	for _, obj := range []metav1.Object{resources.registries[1], resources.projects[1]} {
+		if hasFinalizer(obj) {
+			t.Errorf("finalizer is added to the unrelated resource %s", obj.GetName())
+		}
+	}
+	if finalizers := resources.projects[2].GetFinalizers(); len(finalizers) != 2 {
+		t.Errorf("finalizers of the deleted project are changed: %v", finalizers)
+	}
+}
+
+func TestSyncRegistryFinalizers(t *testing.T) {
+	recorder.reset()
+	old := newSyncTestRegistry("old", "Local")
+	deleted(old, false)
+	orphaned := newSyncTestRegistry("orphaned", "Local")
+	deleted(orphaned, true)
+	gone := newFinalizerTestProject("gone", "old", "other")
+	deleted(gone, false)
+	kept := newFinalizerTestProject("kept", "orphaned")
+	deleted(kept, true)
+	resources := &finalizerTestResources{
+		syncTestResources: &syncTestResources{
+			registries: []*api.Registry{
+				old,
+				orphaned,
+				newSyncTestRegistry("other", "Local"),
+				newSyncTestRegistry("broken-local", "Local"),
+			},
+			projects: []*api.Project{
+				gone,
+				kept,
+				newFinalizerTestProject("waiting", "broken-local"),
+			},
+			events: map[string][]string{},
+		},
+	}
+	deleted(resources.projects[2], false)
+	resources.projects[2].Spec.LocalRegistries = []string{"old", "broken-local"}
+	// the projects not created by registryman are removed from broken-local,
+	// too
+	resources.registries[3].Spec.ProjectDeletion = &api.ProjectDeletionPolicy{
+		Policy: api.DeleteAll,
+	}
+	ctx := context.Background()
+	expectedProvider := config.NewExpectedProvider(resources)
+	if err := syncRegistry(ctx, resources, expectedProvider, old, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncRegistry(ctx, resources, expectedProvider, orphaned, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := recorder.started["orphaned"]; ok {
+		t.Error("the orphaned registry is cleaned up")
+	}
+	for _, obj := range []metav1.Object{
+		resources.registries[0],
+		resources.registries[1],
+		resources.projects[0],
+		resources.projects[1],
+	} {
+		if hasFinalizer(obj) {
+			t.Errorf("the cleanup finalizer of %s is not removed", obj.GetName())
+		}
+		if finalizers := obj.GetFinalizers(); len(finalizers) != 1 || finalizers[0] != "other" {
+			t.Errorf("the other finalizers of %s are changed: %v", obj.GetName(), finalizers)
+		}
+	}
+	if !hasFinalizer(resources.projects[2]) {
+		t.Error("the finalizer of the project is removed before it is removed from all registries")
+	}
+	for name, expected := range map[string]string{
+		"old":      "CleanedUp",
+		"orphaned": "Orphaned",
+		"gone":     "CleanedUp",
+		"kept":     "Orphaned",
+	} {
+		events := resources.events[name]
+		if len(events) == 0 || events[len(events)-1] != expected {
+			t.Errorf("unexpected events of %s: %v", name, events)
+		}
+	}
+}
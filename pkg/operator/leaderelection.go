@@ -0,0 +1,185 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// The timing parameters of the leader election.
+var (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// LeaseLockProvider interface is implemented by the ApiObjectStores that can
+// create a Lease based lock for the leader election.
+type LeaseLockProvider interface {
+	// LeaseLock returns a lock of the Lease resource with the given name.
+	// The identity distinguishes the competing processes.
+	LeaseLock(name, identity string) resourcelock.Interface
+}
+
+// LeaderElection elects a leader among the operator instances using a
+// resource lock.
+type LeaderElection struct {
+	lock resourcelock.Interface
+
+	mu      sync.Mutex
+	leader  string
+	leading bool
+}
+
+// NewLeaderElection creates a LeaderElection using the lock.
+func NewLeaderElection(lock resourcelock.Interface) *LeaderElection {
+	return &LeaderElection{
+		lock: lock,
+	}
+}
+
+// Ready returns an error until the identity of the leader is known.
+func (le *LeaderElection) Ready() error {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	if le.leader == "" {
+		return errors.New("the leader is not known yet")
+	}
+	return nil
+}
+
+// IsLeader returns true while this process is the leader.
+func (le *LeaderElection) IsLeader() bool {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.leading
+}
+
+func (le *LeaderElection) setLeading(leading bool) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	le.leading = leading
+}
+
+// leadershipLostKey is the context key of the function reporting the loss of
+// the leadership.
+type leadershipLostKey struct{}
+
+// leadershipLost returns true if the leader lease has been lost while running
+// ctx, which is the context passed to run by LeaderElection.Run.
+func leadershipLost(ctx context.Context) bool {
+	lost, ok := ctx.Value(leadershipLostKey{}).(func() bool)
+	return ok && lost()
+}
+
+// Run calls run when the process acquires the lease of the lock. The context
+// passed to run is cancelled when ctx is cancelled or when the leadership is
+// lost. If ctx is cancelled, the lease is released only after run has returned,
+// so that the next leader does not start before the in-flight actions are
+// finished. If the leadership is lost, the next leader may start as soon as the
+// lease expires, so the in-flight actions shall be stopped immediately; in this
+// case leadershipLost reports true for the context of run. Run returns when ctx
+// is cancelled and run (if started) has returned.
+func (le *LeaderElection) Run(ctx context.Context, run func(context.Context)) {
+	lock := le.lock
+	// electionCtx is not derived from ctx, it is cancelled only after run
+	// has returned.
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+
+	var mu sync.Mutex
+	leading := false
+	stopping := false
+	finished := make(chan struct{})
+
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		stopping = true
+		wasLeading := leading
+		mu.Unlock()
+		if wasLeading {
+			<-finished
+		}
+		cancelElection()
+	}()
+
+	leaderelection.RunOrDie(electionCtx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            lock.Describe(),
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				mu.Lock()
+				if stopping {
+					mu.Unlock()
+					return
+				}
+				leading = true
+				mu.Unlock()
+				defer close(finished)
+				logger.Info("leader lease acquired", "lock", lock.Describe())
+				runCtx, cancel := context.WithCancel(
+					context.WithValue(leaderCtx, leadershipLostKey{}, func() bool {
+						// leaderCtx is cancelled when the lease
+						// cannot be renewed
+						return leaderCtx.Err() != nil
+					}))
+				defer cancel()
+				go func() {
+					select {
+					case <-ctx.Done():
+						cancel()
+					case <-runCtx.Done():
+					}
+				}()
+				le.setLeading(true)
+				defer le.setLeading(false)
+				run(runCtx)
+			},
+			OnStoppedLeading: func() {
+				logger.Info("leader lease released", "lock", lock.Describe())
+			},
+			OnNewLeader: func(identity string) {
+				le.mu.Lock()
+				le.leader = identity
+				le.mu.Unlock()
+				if identity != lock.Identity() {
+					logger.Info("new leader elected", "identity", identity)
+				}
+			},
+		},
+	})
+
+	mu.Lock()
+	stopping = true
+	wasLeading := leading
+	mu.Unlock()
+	if wasLeading {
+		// the leadership was lost, wait for the running actions
+		<-finished
+	}
+}
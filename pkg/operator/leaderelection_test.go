@@ -0,0 +1,200 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// memoryLock is a resourcelock.Interface storing the election record in
+// memory.
+type memoryLock struct {
+	identity string
+	// lost makes the updates fail, so that the lease cannot be renewed
+	lost bool
+
+	mu      sync.Mutex
+	record  *resourcelock.LeaderElectionRecord
+	history []string
+}
+
+var _ resourcelock.Interface = &memoryLock{}
+
+func (ml *memoryLock) Get(context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.record == nil {
+		return nil, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, "test")
+	}
+	record := *ml.record
+	raw, err := json.Marshal(record)
+	return &record, raw, err
+}
+
+func (ml *memoryLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return ml.Update(ctx, ler)
+}
+
+func (ml *memoryLock) Update(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.lost {
+		return errors.New("the lease cannot be updated")
+	}
+	ml.record = &ler
+	if len(ml.history) == 0 || ml.history[len(ml.history)-1] != ler.HolderIdentity {
+		ml.history = append(ml.history, ler.HolderIdentity)
+	}
+	return nil
+}
+
+func (ml *memoryLock) holder() string {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.record == nil {
+		return ""
+	}
+	return ml.record.HolderIdentity
+}
+
+func (ml *memoryLock) RecordEvent(string) {}
+func (ml *memoryLock) Identity() string   { return ml.identity }
+func (ml *memoryLock) Describe() string   { return "memory/test" }
+
+func TestLeaderElection(t *testing.T) {
+	lock := &memoryLock{identity: "operator-1"}
+	election := NewLeaderElection(lock)
+	isLeader := false
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	holderWhileDraining := ""
+	lost := false
+	returned := make(chan struct{})
+	go func() {
+		election.Run(ctx, func(runCtx context.Context) {
+			close(started)
+			isLeader = election.IsLeader()
+			<-runCtx.Done()
+			lost = leadershipLost(runCtx)
+			// the in-flight operations are drained
+			time.Sleep(50 * time.Millisecond)
+			holderWhileDraining = lock.holder()
+		})
+		close(returned)
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the leader lease is not acquired")
+	}
+	if lock.holder() != "operator-1" {
+		t.Errorf("the lease is held by %q", lock.holder())
+	}
+	if err := election.Ready(); err != nil {
+		t.Errorf("the leader election is not ready: %v", err)
+	}
+	cancel()
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run does not return after the cancellation")
+	}
+	if !isLeader || election.IsLeader() {
+		t.Error("the leadership is not reported")
+	}
+	if lost {
+		t.Error("the cancellation is reported as the loss of the leadership")
+	}
+	if holderWhileDraining != "operator-1" {
+		t.Errorf("the lease is released before the operations are drained")
+	}
+	if lock.holder() != "" {
+		t.Errorf("the lease is not released, it is held by %q", lock.holder())
+	}
+}
+
+func TestLeaderElectionLost(t *testing.T) {
+	defer func(lease, renew, retry time.Duration) {
+		leaseDuration, renewDeadline, retryPeriod = lease, renew, retry
+	}(leaseDuration, renewDeadline, retryPeriod)
+	leaseDuration = 300 * time.Millisecond
+	renewDeadline = 200 * time.Millisecond
+	retryPeriod = 20 * time.Millisecond
+	lock := &memoryLock{identity: "operator-1"}
+	election := NewLeaderElection(lock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lost := make(chan bool, 1)
+	returned := make(chan struct{})
+	go func() {
+		election.Run(ctx, func(runCtx context.Context) {
+			lock.mu.Lock()
+			lock.lost = true
+			lock.mu.Unlock()
+			<-runCtx.Done()
+			lost <- leadershipLost(runCtx)
+		})
+		close(returned)
+	}()
+	select {
+	case isLost := <-lost:
+		if !isLost {
+			t.Error("the loss of the leadership is not reported")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context of run is not cancelled when the lease is lost")
+	}
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run does not return after the leadership is lost")
+	}
+}
+
+func TestLeaderElectionNotLeading(t *testing.T) {
+	lock := &memoryLock{
+		identity: "operator-2",
+		record: &resourcelock.LeaderElectionRecord{
+			HolderIdentity:       "operator-1",
+			LeaseDurationSeconds: 60,
+			AcquireTime:          metav1.Now(),
+			RenewTime:            metav1.Now(),
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	election := NewLeaderElection(lock)
+	election.Run(ctx, func(context.Context) {
+		t.Error("run is called without holding the lease")
+	})
+	if err := election.Ready(); err != nil {
+		t.Errorf("the other leader is not known: %v", err)
+	}
+	if lock.holder() != "operator-1" {
+		t.Errorf("the lease of the other leader is modified, it is held by %q", lock.holder())
+	}
+}
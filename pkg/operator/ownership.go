@@ -0,0 +1,78 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+)
+
+// ownershipOf reads the ownership record of the registry from aop, if aop can
+// read it, and sets the member management modes of the registry and of the
+// projects.
+func ownershipOf(ctx context.Context, aop registry.ApiObjectProvider, reg *registry.Registry) (*reconciler.Ownership, error) {
+	reader, _ := aop.(reconciler.OwnershipReader)
+	ownership, err := reconciler.GetOwnership(ctx, reader, reg.GetName(), reg.GetProjectDeletionPolicy())
+	if err != nil {
+		return nil, err
+	}
+	projectModes := map[string]api.MemberManagementMode{}
+	for _, project := range aop.GetProjects(ctx) {
+		if project.Spec.MemberManagement != "" {
+			projectModes[project.GetName()] = project.Spec.MemberManagement
+		}
+	}
+	return ownership.WithMemberManagement(reg.GetMemberManagement(), projectModes), nil
+}
+
+// seedOwnership seeds the missing ownership record of the registry (e.g. after
+// an upgrade from a version without ownership records) with the actual projects
+// that are described by a Project resource, including the ones being deleted.
+// The record is not persisted in dry-run mode.
+func seedOwnership(ctx context.Context, sres SyncableResources, ownership *reconciler.Ownership, registryName string, actual *api.RegistryStatus, dryRun bool) error {
+	if !ownership.NeedsSeeding() {
+		return nil
+	}
+	declared := map[string]bool{}
+	registries := sres.GetRegistries(ctx)
+	for _, project := range sres.GetProjects(ctx) {
+		for _, reg := range registriesOfProject(project, registries) {
+			if reg.GetName() == registryName {
+				declared[project.GetName()] = true
+				break
+			}
+		}
+	}
+	projectNames := []string{}
+	for _, project := range actual.Projects {
+		if declared[project.Name] {
+			projectNames = append(projectNames, project.Name)
+		}
+	}
+	var performer reconciler.SideEffectPerformer
+	if !dryRun {
+		performer = sres
+	}
+	logger.Info("seeding ownership record",
+		"registry_name", registryName,
+		"projects", projectNames,
+	)
+	return ownership.Seed(ctx, performer, projectNames)
+}
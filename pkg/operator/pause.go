@@ -0,0 +1,107 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// pauseState describes which parts of a registry are paused, either by the
+// PausedAnnotation of the resources or by the CanPause options.
+type pauseState struct {
+	// registry is true if the whole registry is paused.
+	registry bool
+
+	// projects contains the names of the paused projects.
+	projects map[string]bool
+}
+
+// pauseStateOf collects the paused resources of the registry.
+func pauseStateOf(ctx context.Context, aop registry.ApiObjectProvider, registryName string) pauseState {
+	paused := pauseState{
+		projects: map[string]bool{},
+	}
+	if apiRegistry := getRegistry(ctx, aop, registryName); apiRegistry != nil {
+		paused.registry = api.IsPaused(apiRegistry)
+	}
+	for _, project := range aop.GetProjects(ctx) {
+		if api.IsPaused(project) {
+			paused.projects[project.GetName()] = true
+		}
+	}
+	if options, ok := aop.GetGlobalRegistryOptions().(globalregistry.CanPause); ok {
+		for _, name := range options.PausedRegistries() {
+			if name == registryName {
+				paused.registry = true
+			}
+		}
+		for _, name := range options.PausedProjects() {
+			paused.projects[name] = true
+		}
+	}
+	return paused
+}
+
+// projectPaused returns true if the project is paused at the registry.
+func (ps pauseState) projectPaused(name string) bool {
+	return ps.registry || ps.projects[name]
+}
+
+// withoutPausedProjects returns a copy of the registry status without the
+// paused projects, so that no actions are planned for them.
+func (ps pauseState) withoutPausedProjects(status *api.RegistryStatus) *api.RegistryStatus {
+	if len(ps.projects) == 0 {
+		return status
+	}
+	result := *status
+	result.Projects = []api.ProjectStatus{}
+	for _, project := range status.Projects {
+		if !ps.projects[project.Name] {
+			result.Projects = append(result.Projects, project)
+		}
+	}
+	return &result
+}
+
+// reconciledStatus returns the status of a registry which has been reconciled
+// successfully: the paused projects are in their actual state, the others are
+// in the expected state.
+func (ps pauseState) reconciledStatus(actual, expected *api.RegistryStatus) *api.RegistryStatus {
+	switch {
+	case ps.registry:
+		return actual
+	case len(ps.projects) == 0 || actual == nil || expected == nil:
+		return expected
+	}
+	result := *expected
+	result.Projects = []api.ProjectStatus{}
+	for _, project := range expected.Projects {
+		if !ps.projects[project.Name] {
+			result.Projects = append(result.Projects, project)
+		}
+	}
+	for _, project := range actual.Projects {
+		if ps.projects[project.Name] {
+			result.Projects = append(result.Projects, project)
+		}
+	}
+	return &result
+}
@@ -0,0 +1,152 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type pauseTestOptions struct {
+	registries []string
+	projects   []string
+}
+
+func (o *pauseTestOptions) PausedRegistries() []string { return o.registries }
+func (o *pauseTestOptions) PausedProjects() []string   { return o.projects }
+
+func TestSyncRegistryPaused(t *testing.T) {
+	recorder.reset()
+	maintained := newSyncTestRegistry("maintained", "Local")
+	maintained.SetAnnotations(map[string]string{api.PausedAnnotation: "true"})
+	resources := &statusTestResources{
+		syncTestResources: &syncTestResources{
+			registries: []*api.Registry{
+				maintained,
+				newSyncTestRegistry("local", "Local"),
+			},
+			projects: []*api.Project{
+				// the project is missing from both registries
+				newFinalizerTestProject("app", "maintained", "local"),
+			},
+			events: map[string][]string{},
+		},
+		statuses:        map[string]*api.RegistryStatus{},
+		projectStatuses: map[string]*api.ProjectProvisioningStatus{},
+	}
+	ctx := context.Background()
+	expectedProvider := config.NewExpectedProvider(resources)
+	if err := syncRegistry(ctx, resources, expectedProvider, maintained, false); err != nil {
+		t.Fatalf("paused registry: %v", err)
+	}
+	status := resources.statuses["maintained"]
+	for conditionType, expected := range map[string]metav1.ConditionStatus{
+		api.RegistryPaused:  metav1.ConditionTrue,
+		api.RegistrySynced:  metav1.ConditionUnknown,
+		api.RegistryDrifted: metav1.ConditionTrue,
+	} {
+		if actual := conditionStatus(status, conditionType); actual != expected {
+			t.Errorf("paused registry: condition %s is %q, expected %q", conditionType, actual, expected)
+		}
+	}
+	if status.LastSyncTime != nil {
+		t.Errorf("paused registry: the reconciliation is recorded as a sync: %+v", status)
+	}
+	if projectStatus := resources.projectStatuses["app"]; projectStatus == nil ||
+		len(projectStatus.Registries) != 1 || !projectStatus.Registries[0].Paused ||
+		projectStatus.Registries[0].Members != api.Synced {
+		t.Errorf("paused registry: unexpected project status: %+v", projectStatus)
+	}
+
+	// the project is paused by the options
+	resources.options = &pauseTestOptions{
+		projects: []string{"app"},
+	}
+	if err := syncRegistry(ctx, resources, expectedProvider, resources.registries[1], false); err != nil {
+		t.Fatalf("paused project: %v", err)
+	}
+	status = resources.statuses["local"]
+	if conditionStatus(status, api.RegistryPaused) != metav1.ConditionFalse ||
+		conditionStatus(status, api.RegistryDrifted) != metav1.ConditionFalse {
+		t.Errorf("paused project: unexpected conditions: %+v", status.Conditions)
+	}
+	if projectStatus := resources.projectStatuses["app"]; projectStatus == nil ||
+		len(projectStatus.Registries) != 1 || !projectStatus.Registries[0].Paused ||
+		projectStatus.Registries[0].Exists {
+		t.Errorf("paused project: unexpected project status: %+v", projectStatus)
+	}
+	if len(resources.events["maintained"]) != 0 || len(resources.events["local"]) != 0 {
+		t.Errorf("actions are performed on paused resources: %v", resources.events)
+	}
+}
+
+func TestSyncRegistryPausedDeletedProject(t *testing.T) {
+	recorder.reset()
+	local := newSyncTestRegistry("local", "Local")
+	app := newFinalizerTestProject("app", "local")
+	deleted(app, false)
+	app.SetAnnotations(map[string]string{api.PausedAnnotation: "true"})
+	resources := &finalizerTestResources{
+		syncTestResources: &syncTestResources{
+			registries: []*api.Registry{local},
+			projects:   []*api.Project{app},
+			events:     map[string][]string{},
+		},
+	}
+	ctx := context.Background()
+	expectedProvider := config.NewExpectedProvider(resources)
+	if err := syncRegistry(ctx, resources, expectedProvider, local, false); err != nil {
+		t.Fatalf("paused project: %v", err)
+	}
+	if !hasFinalizer(resources.projects[0]) {
+		t.Error("the finalizer of the paused project is removed")
+	}
+
+	// the project is resumed
+	resources.projects[0].SetAnnotations(nil)
+	if err := syncRegistry(ctx, resources, expectedProvider, local, false); err != nil {
+		t.Fatalf("resumed project: %v", err)
+	}
+	if hasFinalizer(resources.projects[0]) {
+		t.Error("the finalizer of the resumed project is not removed")
+	}
+}
+
+func TestReconciledStatus(t *testing.T) {
+	actual := &api.RegistryStatus{
+		Projects: []api.ProjectStatus{{Name: "paused"}, {Name: "removed"}},
+	}
+	expected := &api.RegistryStatus{
+		Projects: []api.ProjectStatus{{Name: "created"}},
+	}
+	paused := pauseState{projects: map[string]bool{"paused": true}}
+	status := paused.reconciledStatus(actual, expected)
+	if len(status.Projects) != 2 || status.Projects[0].Name != "created" || status.Projects[1].Name != "paused" {
+		t.Errorf("unexpected reconciled status: %+v", status.Projects)
+	}
+	if len(expected.Projects) != 1 {
+		t.Errorf("the expected status is modified: %+v", expected.Projects)
+	}
+	paused.registry = true
+	if status = paused.reconciledStatus(actual, expected); status != actual {
+		t.Errorf("the paused registry is not left in its actual state: %+v", status.Projects)
+	}
+}
@@ -18,61 +18,63 @@ package operator
 
 import (
 	"context"
-	"fmt"
+	"reflect"
 
-	"k8s.io/apimachinery/pkg/runtime"
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 )
 
+// projectEventHandler enqueues the registries where the changed project is
+// expected to exist.
 type projectEventHandler struct {
-	ctx    context.Context
-	aop    SyncableResources
-	events EventRecorder
+	ctx   context.Context
+	aop   SyncableResources
+	queue workqueue.RateLimitingInterface
 }
 
 var _ cache.ResourceEventHandler = &projectEventHandler{}
 
-func (reh *projectEventHandler) OnAdd(obj interface{}) {
-	logger.V(1).Info("projectEventHandler.OnAdd")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Project",
-			"event", "OnAdd",
-		)
-		reh.events.RecordEventWarning(obj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
+func (peh *projectEventHandler) enqueue(projects ...interface{}) {
+	registries := peh.aop.GetRegistries(peh.ctx)
+	for _, obj := range projects {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		project, ok := obj.(*api.Project)
+		if !ok {
+			continue
+		}
+		enqueueRegistries(peh.queue, registriesOfProject(project, registries)...)
 	}
 }
 
-func (reh *projectEventHandler) OnUpdate(oldObj, newObj interface{}) {
+func (peh *projectEventHandler) OnAdd(obj interface{}) {
+	logger.V(1).Info("projectEventHandler.OnAdd")
+	peh.enqueue(obj)
+}
+
+func (peh *projectEventHandler) OnUpdate(oldObj, newObj interface{}) {
 	logger.V(1).Info("projectEventHandler.OnUpdate")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Project",
-			"event", "OnUpdate",
-		)
-		reh.events.RecordEventWarning(oldObj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
+	oldProject, oldOk := oldObj.(*api.Project)
+	newProject, newOk := newObj.(*api.Project)
+	if oldOk && newOk {
+		switch {
+		case oldProject.GetResourceVersion() == newProject.GetResourceVersion():
+			// periodic resync, the registries are resynced by their own events
+			return
+		case oldProject.GetGeneration() == newProject.GetGeneration() &&
+			reflect.DeepEqual(oldProject.GetAnnotations(), newProject.GetAnnotations()) &&
+			(oldProject.GetDeletionTimestamp() == nil) == (newProject.GetDeletionTimestamp() == nil):
+			// only the status has changed
+			return
+		}
 	}
+	// the project may have been moved between registries
+	peh.enqueue(oldObj, newObj)
 }
 
-func (reh *projectEventHandler) OnDelete(obj interface{}) {
+func (peh *projectEventHandler) OnDelete(obj interface{}) {
 	logger.V(1).Info("projectEventHandler.OnDelete")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Project",
-			"event", "OnDelete",
-		)
-		reh.events.RecordEventWarning(obj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
-	}
+	peh.enqueue(obj)
 }
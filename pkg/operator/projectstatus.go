@@ -0,0 +1,187 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+)
+
+// projectStatusWriter is implemented by the stores that can persist the status
+// of the Project resources.
+type projectStatusWriter interface {
+	registry.ApiObjectProvider
+
+	// UpdateProjectStatus persists the status of the given Project
+	// resource.
+	UpdateProjectStatus(context.Context, *api.Project) error
+}
+
+// getProject returns the Project resource with the given name, or nil if it
+// does not exist.
+func getProject(ctx context.Context, aop registry.ApiObjectProvider, name string) *api.Project {
+	for _, project := range aop.GetProjects(ctx) {
+		if project.GetName() == name {
+			return project
+		}
+	}
+	return nil
+}
+
+// updateProjectStatus modifies the status of the project with mutate and
+// persists it if it has changed. If the Project resource has been modified in
+// the meantime, the latest version is read and mutate is applied again.
+func updateProjectStatus(ctx context.Context, store projectStatusWriter, project *api.Project, mutate func(*api.ProjectProvisioningStatus)) error {
+	project = project.DeepCopy()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if project.Status == nil {
+			project.Status = &api.ProjectProvisioningStatus{}
+		}
+		original := project.Status.DeepCopy()
+		mutate(project.Status)
+		if equality.Semantic.DeepEqual(original, project.Status) {
+			return nil
+		}
+		err := store.UpdateProjectStatus(ctx, project)
+		if apierrors.IsConflict(err) {
+			latest := getProject(ctx, store, project.GetName())
+			if latest == nil {
+				return fmt.Errorf("project %s not found", project.GetName())
+			}
+			project = latest.DeepCopy()
+		}
+		return err
+	})
+}
+
+func syncStateOf(actions []reconciler.Action) api.SyncState {
+	if len(actions) == 0 {
+		return api.Synced
+	}
+	return api.OutOfSync
+}
+
+// projectRegistryStatuses returns the state of the expected projects at the
+// registry. If the actual status of the registry is nil, i.e. it could not be
+// inspected, the states are unknown. The members left in place by the member
+// management mode of the project are not considered.
+func projectRegistryStatuses(expectedProvider *config.ExpectedProvider, registryName string, actual, expected *api.RegistryStatus, ownership *reconciler.Ownership) map[string]api.ProjectRegistryStatus {
+	statuses := make(map[string]api.ProjectRegistryStatus, len(expected.Projects))
+	for _, exp := range expected.Projects {
+		status := api.ProjectRegistryStatus{
+			Registry:         registryName,
+			Members:          api.SyncUnknown,
+			ReplicationRules: api.SyncUnknown,
+			Scanner:          api.SyncUnknown,
+		}
+		if actual != nil {
+			act := api.ProjectStatus{Name: exp.Name}
+			for _, project := range actual.Projects {
+				if project.Name == exp.Name {
+					act = project
+					status.Exists = true
+					break
+				}
+			}
+			status.Members = syncStateOf(reconciler.CompareMemberStatuses(
+				exp.Name, ownership.ManagedMembers(exp.Name, act.Members, exp.Members),
+				exp.Members, actual.Capabilities))
+			status.ReplicationRules = syncStateOf(reconciler.CompareReplicationRuleStatus(
+				expectedProvider, exp.Name, act.ReplicationRules, exp.ReplicationRules, actual.Capabilities))
+			status.Scanner = syncStateOf(reconciler.CompareScannerStatuses(
+				exp.Name, act.ScannerStatus, exp.ScannerStatus, actual.Capabilities))
+		}
+		statuses[exp.Name] = status
+	}
+	return statuses
+}
+
+// updateProjectStatuses records the state of the projects at the registry in
+// the status of the Project resources. The entries of the projects that are
+// not expected at the registry any more and the entries of the removed
+// registries are dropped.
+//
+// If err is not nil, it is recorded as the last error of the projects. A nil
+// err clears the last error only if the registry has been reconciled.
+// The entries of the paused projects and of the paused registry are marked
+// as paused.
+func updateProjectStatuses(ctx context.Context, store projectStatusWriter, expectedProvider *config.ExpectedProvider, registryName string, actual, expected *api.RegistryStatus, err error, reconciled bool) error {
+	if expected == nil {
+		// the expected projects of the registry are not known
+		return nil
+	}
+	var ownership *reconciler.Ownership
+	if apiRegistry := getRegistry(ctx, store, registryName); apiRegistry != nil {
+		var err error
+		if ownership, err = ownershipOf(ctx, store, registry.New(apiRegistry, store)); err != nil {
+			return err
+		}
+	}
+	statuses := projectRegistryStatuses(expectedProvider, registryName, actual, expected, ownership)
+	paused := pauseStateOf(ctx, store, registryName)
+	registries := map[string]bool{}
+	for _, reg := range store.GetRegistries(ctx) {
+		registries[reg.GetName()] = true
+	}
+	errs := []error{}
+	for _, project := range store.GetProjects(ctx) {
+		status, found := statuses[project.GetName()]
+		errs = append(errs, updateProjectStatus(ctx, store, project, func(projectStatus *api.ProjectProvisioningStatus) {
+			entries := []api.ProjectRegistryStatus{}
+			var previous *api.ProjectRegistryStatus
+			for i, entry := range projectStatus.Registries {
+				switch {
+				case entry.Registry == registryName:
+					previous = &projectStatus.Registries[i]
+				case registries[entry.Registry]:
+					entries = append(entries, entry)
+				}
+			}
+			if found {
+				entry := status
+				entry.Paused = paused.projectPaused(project.GetName())
+				if previous != nil {
+					if actual == nil {
+						entry.Exists = previous.Exists
+					}
+					if err == nil && !reconciled {
+						entry.LastError = previous.LastError
+					}
+				}
+				if err != nil {
+					entry.LastError = err.Error()
+				}
+				entries = append(entries, entry)
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].Registry < entries[j].Registry
+			})
+			projectStatus.Registries = entries
+		}))
+	}
+	return utilerrors.NewAggregate(errs)
+}
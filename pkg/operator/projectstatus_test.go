@@ -0,0 +1,161 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+)
+
+func TestProjectRegistryStatuses(t *testing.T) {
+	capabilities := api.RegistryCapabilities{
+		CanManipulateProjectMembers:  true,
+		CanManipulateProjectScanners: true,
+	}
+	alice := api.MemberStatus{Name: "alice", Type: "User", Role: "Developer"}
+	trivy := api.ScannerStatus{Name: "trivy", URL: "http://trivy"}
+	expected := &api.RegistryStatus{
+		Projects: []api.ProjectStatus{
+			{Name: "app", Members: []api.MemberStatus{alice}, ScannerStatus: trivy},
+			{Name: "missing"},
+		},
+	}
+	actual := &api.RegistryStatus{
+		Projects: []api.ProjectStatus{
+			{Name: "app", ScannerStatus: trivy},
+			{Name: "unexpected"},
+		},
+		Capabilities: capabilities,
+	}
+	statuses := projectRegistryStatuses(nil, "local", actual, expected, nil)
+	if len(statuses) != 2 {
+		t.Fatalf("unexpected project statuses: %+v", statuses)
+	}
+	if app := statuses["app"]; !app.Exists || app.Registry != "local" ||
+		app.Members != api.OutOfSync || app.Scanner != api.Synced || app.ReplicationRules != api.Synced {
+		t.Errorf("unexpected status of project app: %+v", app)
+	}
+	if missing := statuses["missing"]; missing.Exists || missing.Members != api.Synced {
+		t.Errorf("unexpected status of project missing: %+v", missing)
+	}
+
+	statuses = projectRegistryStatuses(nil, "local", nil, expected, nil)
+	if app := statuses["app"]; app.Exists || app.Members != api.SyncUnknown || app.Scanner != api.SyncUnknown {
+		t.Errorf("unexpected status of an uninspected project: %+v", app)
+	}
+
+	// the members added by hand are left in place in additive mode
+	actual.Projects[0].Members = []api.MemberStatus{
+		alice,
+		{Name: "bob", Type: "User", Role: "Maintainer"},
+	}
+	statuses = projectRegistryStatuses(nil, "local", actual, expected, nil)
+	if app := statuses["app"]; app.Members != api.OutOfSync {
+		t.Errorf("unexpected status of project app in authoritative mode: %+v", app)
+	}
+	ownership, err := reconciler.GetOwnership(context.Background(), nil, "local", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownership = ownership.WithMemberManagement("", map[string]api.MemberManagementMode{
+		"app": api.AdditiveMembers,
+	})
+	statuses = projectRegistryStatuses(nil, "local", actual, expected, ownership)
+	if app := statuses["app"]; app.Members != api.Synced {
+		t.Errorf("unexpected status of project app in additive mode: %+v", app)
+	}
+}
+
+func TestUpdateProjectStatuses(t *testing.T) {
+	project := func(name string, entries ...api.ProjectRegistryStatus) *api.Project {
+		p := newQueueTestProject(name, api.GlobalProjectType, "")
+		p.Status = &api.ProjectProvisioningStatus{Registries: entries}
+		return p
+	}
+	resources := &statusTestResources{
+		syncTestResources: &syncTestResources{
+			registries: []*api.Registry{
+				newSyncTestRegistry("global", "GlobalHub"),
+				newSyncTestRegistry("local", "Local"),
+			},
+			projects: []*api.Project{
+				project("app",
+					api.ProjectRegistryStatus{Registry: "local", Exists: true, LastError: "boom"},
+					api.ProjectRegistryStatus{Registry: "global", Exists: true, Members: api.Synced},
+				),
+				project("moved",
+					api.ProjectRegistryStatus{Registry: "local", Exists: true},
+					api.ProjectRegistryStatus{Registry: "removed", Exists: true},
+				),
+				project("unchanged"),
+			},
+		},
+		projectConflicts: 1,
+		projectStatuses:  map[string]*api.ProjectProvisioningStatus{},
+	}
+	expected := &api.RegistryStatus{
+		Projects: []api.ProjectStatus{{Name: "app"}},
+	}
+	ctx := context.Background()
+
+	// the status updater keeps the error of the last reconciliation
+	err := updateProjectStatuses(ctx, resources, nil, "local", expected, expected, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources.projectConflicts != 0 {
+		t.Error("the conflicting status update is not retried")
+	}
+	app := resources.projectStatuses["app"]
+	if app == nil || len(app.Registries) != 2 {
+		t.Fatalf("unexpected status of project app: %+v", app)
+	}
+	if entry := app.Registries[1]; entry.Registry != "local" || entry.Members != api.Synced || entry.LastError != "boom" {
+		t.Errorf("unexpected entry of registry local: %+v", entry)
+	}
+	if moved := resources.projectStatuses["moved"]; moved == nil || len(moved.Registries) != 0 {
+		t.Errorf("the stale entries of project moved are not removed: %+v", moved)
+	}
+	if _, updated := resources.projectStatuses["unchanged"]; updated {
+		t.Error("the status of project unchanged is updated, although it has not changed")
+	}
+
+	// a failed inspection keeps the existence of the project
+	resources.projects[0].Status = app
+	err = updateProjectStatuses(ctx, resources, nil, "local", nil, expected, errors.New("connection refused"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := resources.projectStatuses["app"].Registries[1]; !entry.Exists ||
+		entry.Members != api.SyncUnknown || entry.LastError != "connection refused" {
+		t.Errorf("unexpected entry of registry local: %+v", entry)
+	}
+
+	// a successful reconciliation clears the error
+	resources.projects[0].Status = resources.projectStatuses["app"]
+	err = updateProjectStatuses(ctx, resources, nil, "local", expected, expected, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := resources.projectStatuses["app"].Registries[1]; entry.LastError != "" {
+		t.Errorf("the last error is not cleared: %+v", entry)
+	}
+}
@@ -0,0 +1,257 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// The parameters of the exponential backoff applied to the registries whose
+// reconciliation failed.
+var (
+	minRetryDelay = time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+// localRegistryDelay is the delay after which a Local registry waiting for
+// the GlobalHub registries is processed again.
+var localRegistryDelay = time.Second
+
+// registryQueue is a rate limited work queue. The items of the queue are the
+// names of the registries to be reconciled. A registry that is added several
+// times before it is processed is reconciled only once.
+//
+// The queue records the registries added to it which have not been processed
+// since, so that the Local registries can wait for the GlobalHub registries:
+// the pull replication rules of the Local registries refer to the projects of
+// the GlobalHub registry.
+type registryQueue struct {
+	workqueue.RateLimitingInterface
+
+	mu sync.Mutex
+	// seq is incremented by each Add
+	seq uint64
+	// pending maps the names of the added registries to the seq of their
+	// last Add
+	pending map[string]uint64
+}
+
+func newRegistryQueue() *registryQueue {
+	return &registryQueue{
+		RateLimitingInterface: workqueue.NewNamedRateLimitingQueue(
+			workqueue.NewMaxOfRateLimiter(
+				workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay),
+				&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+			),
+			"registries",
+		),
+		pending: map[string]uint64{},
+	}
+}
+
+// Add adds the registry to the queue and records it as pending.
+func (q *registryQueue) Add(item interface{}) {
+	q.mu.Lock()
+	q.seq++
+	q.pending[item.(string)] = q.seq
+	q.mu.Unlock()
+	q.RateLimitingInterface.Add(item)
+}
+
+// start returns the sequence number to be passed to processed when the
+// processing of a registry is finished.
+func (q *registryQueue) start() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seq
+}
+
+// processed records that the registry has been processed (successfully or
+// not). The registry remains pending if it has been added again after the
+// processing was started.
+func (q *registryQueue) processed(name string, seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[name] <= seq {
+		delete(q.pending, name)
+	}
+}
+
+// isPending checks whether any of the registries has been added to the queue
+// and it has not been processed since.
+func (q *registryQueue) isPending(registries []*api.Registry) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, reg := range registries {
+		if _, ok := q.pending[reg.GetName()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueRegistries adds the registries to the queue.
+func enqueueRegistries(queue workqueue.RateLimitingInterface, registries ...*api.Registry) {
+	for _, reg := range registries {
+		queue.Add(reg.GetName())
+	}
+}
+
+// registriesOfProject returns the registries where the project is expected
+// to exist: every registry for a global project, the listed local registries
+// for a local project.
+func registriesOfProject(project *api.Project, registries []*api.Registry) []*api.Registry {
+	if project.Spec.Type == api.GlobalProjectType {
+		return registries
+	}
+	result := []*api.Registry{}
+	for _, reg := range registries {
+		for _, localRegistry := range project.Spec.LocalRegistries {
+			if localRegistry == reg.GetName() {
+				result = append(result, reg)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// globalHubRegistries returns the registries with GlobalHub role.
+func globalHubRegistries(registries []*api.Registry) []*api.Registry {
+	result := []*api.Registry{}
+	for _, reg := range registries {
+		if reg.Spec.Role == "GlobalHub" {
+			result = append(result, reg)
+		}
+	}
+	return result
+}
+
+// getRegistry returns the Registry resource with the given name, or nil if it
+// does not exist.
+func getRegistry(ctx context.Context, aop registry.ApiObjectProvider, name string) *api.Registry {
+	for _, reg := range aop.GetRegistries(ctx) {
+		if reg.GetName() == name {
+			return reg
+		}
+	}
+	return nil
+}
+
+// activity records the start time of the running reconciliations. A nil
+// *activity records nothing.
+type activity struct {
+	mu      sync.Mutex
+	running map[string]time.Time
+}
+
+func newActivity() *activity {
+	return &activity{
+		running: map[string]time.Time{},
+	}
+}
+
+func (a *activity) start(name string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running[name] = time.Now()
+}
+
+func (a *activity) finish(name string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.running, name)
+}
+
+// longest returns the name and the duration of the longest running
+// reconciliation.
+func (a *activity) longest() (string, time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	name := ""
+	var longest time.Duration
+	for registry, start := range a.running {
+		if duration := time.Since(start); duration > longest {
+			name, longest = registry, duration
+		}
+	}
+	return name, longest
+}
+
+// processNextItem reconciles the next registry of the queue. It returns false
+// when the queue is shut down or the stop channel is closed; in the latter case
+// the remaining items of the queue are dropped.
+//
+// Like in FullResync, the GlobalHub registries are reconciled before the Local
+// ones: a Local registry is added back to the queue after localRegistryDelay
+// while a GlobalHub registry is queued or being reconciled. A failed GlobalHub
+// registry does not hold back the Local registries during its backoff.
+func processNextItem(ctx context.Context, stop <-chan struct{}, aop SyncableResources, queue *registryQueue, act *activity) bool {
+	item, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(item)
+	select {
+	case <-stop:
+		return false
+	default:
+	}
+	name := item.(string)
+	seq := queue.start()
+	apiRegistry := getRegistry(ctx, aop, name)
+	if apiRegistry == nil {
+		logger.V(1).Info("registry no longer exists", "registry_name", name)
+		queue.processed(name, seq)
+		queue.Forget(item)
+		return true
+	}
+	if apiRegistry.Spec.Role != "GlobalHub" &&
+		queue.isPending(globalHubRegistries(aop.GetRegistries(ctx))) {
+		logger.V(1).Info("waiting for the GlobalHub registries", "registry_name", name)
+		queue.AddAfter(item, localRegistryDelay)
+		return true
+	}
+	act.start(name)
+	err := syncRegistry(ctx, aop, config.NewExpectedProvider(aop), apiRegistry, false)
+	act.finish(name)
+	queue.processed(name, seq)
+	if err != nil {
+		logger.Error(err, "failed to synchronize registry, retrying",
+			"registry_name", name,
+			"retries", queue.NumRequeues(item),
+		)
+		queue.AddRateLimited(item)
+		return true
+	}
+	queue.Forget(item)
+	return true
+}
@@ -0,0 +1,312 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// drain returns the sorted items of the queue.
+func drain(queue workqueue.RateLimitingInterface) []string {
+	items := []string{}
+	for queue.Len() > 0 {
+		item, _ := queue.Get()
+		items = append(items, item.(string))
+		queue.Done(item)
+		queue.Forget(item)
+	}
+	sort.Strings(items)
+	return items
+}
+
+func expectItems(t *testing.T, event string, items []string, expected ...string) {
+	t.Helper()
+	if len(items) != len(expected) {
+		t.Errorf("%s: enqueued registries are %v, expected %v", event, items, expected)
+		return
+	}
+	for i := range items {
+		if items[i] != expected[i] {
+			t.Errorf("%s: enqueued registries are %v, expected %v", event, items, expected)
+			return
+		}
+	}
+}
+
+func newQueueTestProject(name string, projectType api.ProjectType, scanner string, localRegistries ...string) *api.Project {
+	return &api.Project{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			ResourceVersion: "1",
+		},
+		Spec: &api.ProjectSpec{
+			Type:            projectType,
+			LocalRegistries: localRegistries,
+			Scanner:         scanner,
+		},
+	}
+}
+
+func TestEventHandlers(t *testing.T) {
+	ctx := context.Background()
+	global := newSyncTestRegistry("global", "GlobalHub")
+	local1 := newSyncTestRegistry("local-1", "Local")
+	local2 := newSyncTestRegistry("local-2", "Local")
+	localProject := newQueueTestProject("local-project", api.LocalProjectType, "trivy", "local-1")
+	resources := &syncTestResources{
+		registries: []*api.Registry{global, local1, local2},
+		projects: []*api.Project{
+			localProject,
+			newQueueTestProject("global-project", api.GlobalProjectType, ""),
+		},
+	}
+	queue := newRegistryQueue()
+	defer queue.ShutDown()
+
+	peh := &projectEventHandler{ctx: ctx, aop: resources, queue: queue}
+	peh.OnAdd(localProject)
+	peh.OnAdd(localProject)
+	expectItems(t, "local project added", drain(queue), "local-1")
+
+	movedProject := localProject.DeepCopy()
+	movedProject.ResourceVersion = "2"
+	movedProject.Generation = 2
+	movedProject.Spec.LocalRegistries = []string{"local-2"}
+	peh.OnUpdate(localProject, movedProject)
+	expectItems(t, "local project moved", drain(queue), "local-1", "local-2")
+
+	projectStatusUpdated := movedProject.DeepCopy()
+	projectStatusUpdated.ResourceVersion = "3"
+	projectStatusUpdated.Status = &api.ProjectProvisioningStatus{}
+	peh.OnUpdate(movedProject, projectStatusUpdated)
+	expectItems(t, "project status updated", drain(queue))
+
+	peh.OnUpdate(localProject, localProject)
+	expectItems(t, "project resynced", drain(queue))
+
+	peh.OnDelete(cache.DeletedFinalStateUnknown{Obj: resources.projects[1]})
+	expectItems(t, "global project deleted", drain(queue), "global", "local-1", "local-2")
+
+	seh := &scannerEventHandler{ctx: ctx, aop: resources, queue: queue}
+	trivy := &api.Scanner{ObjectMeta: metav1.ObjectMeta{Name: "trivy", ResourceVersion: "1"}}
+	seh.OnAdd(trivy)
+	expectItems(t, "scanner added", drain(queue), "local-1")
+
+	scannerStatusUpdated := trivy.DeepCopy()
+	scannerStatusUpdated.ResourceVersion = "2"
+	scannerStatusUpdated.Status = &api.ScannerProvisioningStatus{}
+	seh.OnUpdate(trivy, scannerStatusUpdated)
+	expectItems(t, "scanner status updated", drain(queue))
+
+	reh := &registryEventHandler{ctx: ctx, aop: resources, queue: queue}
+	reh.OnUpdate(local2, local2)
+	expectItems(t, "registry resynced", drain(queue), "local-2")
+
+	statusUpdated := local2.DeepCopy()
+	statusUpdated.ResourceVersion = "2"
+	reh.OnUpdate(local2, statusUpdated)
+	expectItems(t, "registry status updated", drain(queue))
+
+	specUpdated := statusUpdated.DeepCopy()
+	specUpdated.ResourceVersion = "3"
+	specUpdated.Generation = 2
+	reh.OnUpdate(statusUpdated, specUpdated)
+	expectItems(t, "registry spec updated", drain(queue), "global", "local-1", "local-2")
+}
+
+func TestSecretInformers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	reg := newSyncTestRegistry("harbor", "GlobalHub")
+	reg.Namespace = "registryman"
+	reg.Spec.CredentialsRef = &api.CredentialsReference{
+		SecretRef: &api.SecretCredentialsReference{Name: "creds"},
+	}
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "registryman"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "kube-system"}},
+	)
+	queue := newRegistryQueue()
+	defer queue.ShutDown()
+	watched := []string{}
+	secrets := &secretInformers{
+		ctx: ctx,
+		wg:  &wg,
+		factory: func(defaultResync time.Duration, namespace string) informers.SharedInformerFactory {
+			watched = append(watched, namespace)
+			return informers.NewSharedInformerFactoryWithOptions(client, defaultResync,
+				informers.WithNamespace(namespace))
+		},
+		handler: &secretEventHandler{
+			ctx:   ctx,
+			aop:   &syncTestResources{registries: []*api.Registry{reg}},
+			queue: queue,
+		},
+		informers: map[string]cache.SharedIndexInformer{},
+	}
+	// the registry events are queued separately to observe the secret
+	// events only
+	registryQueue := newRegistryQueue()
+	defer registryQueue.ShutDown()
+	reh := &registryEventHandler{ctx: ctx, aop: secrets.handler.(*secretEventHandler).aop, queue: registryQueue, secrets: secrets}
+	reh.OnAdd(reg)
+	reh.OnUpdate(reg, reg)
+	if len(watched) != 1 || watched[0] != "registryman" {
+		t.Errorf("unexpected watched namespaces: %v", watched)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), secrets.HasSynced) {
+		t.Fatal("secret informers are not synchronized")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for queue.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	expectItems(t, "secret added", drain(queue), "harbor")
+}
+
+func TestProcessNextItem(t *testing.T) {
+	defaultMinRetryDelay := minRetryDelay
+	defer func() {
+		minRetryDelay = defaultMinRetryDelay
+	}()
+	minRetryDelay = time.Millisecond
+	recorder.reset()
+	resources := &syncTestResources{
+		registries: []*api.Registry{
+			newSyncTestRegistry("local", "Local"),
+			newSyncTestRegistry("broken-local", "Local"),
+		},
+		events: map[string][]string{},
+	}
+	queue := newRegistryQueue()
+	defer queue.ShutDown()
+	ctx := context.Background()
+
+	queue.Add("local")
+	queue.Add("deleted")
+	queue.Add("broken-local")
+	for i := 0; i < 3; i++ {
+		if !processNextItem(ctx, nil, resources, queue, nil) {
+			t.Fatal("queue is shut down")
+		}
+	}
+	if queue.NumRequeues("local") != 0 || queue.NumRequeues("deleted") != 0 {
+		t.Error("successfully processed registries are requeued")
+	}
+	if queue.NumRequeues("broken-local") != 1 {
+		t.Errorf("failed registry is requeued %d times, expected 1",
+			queue.NumRequeues("broken-local"))
+	}
+	// the failed registry is retried after the backoff
+	processNextItem(ctx, nil, resources, queue, nil)
+	if queue.NumRequeues("broken-local") != 2 {
+		t.Errorf("failed registry is requeued %d times, expected 2",
+			queue.NumRequeues("broken-local"))
+	}
+}
+
+func TestProcessNextItemWaitsForGlobalHub(t *testing.T) {
+	defaultDelay := localRegistryDelay
+	defer func() {
+		localRegistryDelay = defaultDelay
+	}()
+	localRegistryDelay = 10 * time.Millisecond
+	recorder.reset()
+	resources := &syncTestResources{
+		registries: []*api.Registry{
+			newSyncTestRegistry("local", "Local"),
+			newSyncTestRegistry("global", "GlobalHub"),
+		},
+		events: map[string][]string{},
+	}
+	queue := newRegistryQueue()
+	defer queue.ShutDown()
+	ctx := context.Background()
+
+	// the Local registry is queued before the GlobalHub registry
+	queue.Add("local")
+	queue.Add("global")
+	for i := 0; i < 3; i++ {
+		if !processNextItem(ctx, nil, resources, queue, nil) {
+			t.Fatal("queue is shut down")
+		}
+	}
+	if _, ok := recorder.finished["global"]; !ok {
+		t.Fatal("GlobalHub registry is not reconciled")
+	}
+	started, ok := recorder.started["local"]
+	if !ok {
+		t.Fatal("Local registry is not reconciled")
+	}
+	if started.Before(recorder.finished["global"]) {
+		t.Error("Local registry is reconciled before the GlobalHub registry")
+	}
+	if queue.Len() != 0 {
+		t.Errorf("%d registries are left in the queue", queue.Len())
+	}
+}
+
+func TestReconcilerHealth(t *testing.T) {
+	rec := NewReconciler(nil, 0)
+	if err := rec.Synced(); err == nil {
+		t.Error("reconciler is synced before it is started")
+	}
+	if err := rec.Healthy(); err != nil {
+		t.Errorf("idle reconciler is unhealthy: %v", err)
+	}
+	rec.activity.start("harbor")
+	if err := rec.Healthy(); err != nil {
+		t.Errorf("running reconciliation makes the reconciler unhealthy: %v", err)
+	}
+	rec.activity.running["harbor"] = time.Now().Add(-2 * StuckTimeout)
+	if err := rec.Healthy(); err == nil {
+		t.Error("stuck reconciliation is not detected")
+	}
+	rec.activity.finish("harbor")
+	if err := rec.Healthy(); err != nil {
+		t.Errorf("finished reconciliation makes the reconciler unhealthy: %v", err)
+	}
+}
+
+func TestStatusUpdaterHealth(t *testing.T) {
+	sup := NewStatusUpdater(time.Second, nil)
+	if err := sup.Healthy(); err != nil {
+		t.Errorf("status updater is unhealthy before it is started: %v", err)
+	}
+	sup.tick()
+	if err := sup.Healthy(); err != nil {
+		t.Errorf("status updater is unhealthy after a tick: %v", err)
+	}
+	sup.lastTick = time.Now().Add(-4 * time.Second)
+	if err := sup.Healthy(); err == nil {
+		t.Error("stuck status updater is not detected")
+	}
+}
@@ -18,14 +18,27 @@ package operator
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	regmaninformer "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1/informers/externalversions"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
 )
 
-var defaultResync = 30 * time.Second
+// DefaultResyncPeriod is the default period of the informer resyncs. Every
+// registry is reconciled at least once in a resync period.
+const DefaultResyncPeriod = 30 * time.Second
+
+// ShutdownTimeout is the time the running reconciliations are given to finish
+// when the Reconciler is stopped. After the timeout, their context is
+// cancelled. If the Reconciler is stopped because the leader lease has been
+// lost, the running reconciliations are cancelled immediately.
+var ShutdownTimeout = 30 * time.Second
 
 type EventRecorder interface {
 	RecordEventNormal(obj runtime.Object, reason, message string)
@@ -38,48 +51,153 @@ type AOSWithSharedInformerFactory interface {
 
 	// SharedInformerFactory returns a SharedInformerFactory.
 	SharedInformerFactory(defaultResync time.Duration) regmaninformer.SharedInformerFactory
+
+	// KubeSharedInformerFactory returns a SharedInformerFactory for the
+	// core Kubernetes resources of the given namespace.
+	KubeSharedInformerFactory(defaultResync time.Duration, namespace string) informers.SharedInformerFactory
 }
 
 //Reconciler type is responsible for the registryman reconciliation loop.
+//
+// The informer events are mapped to the affected registries, which are added to
+// a rate limited work queue. The registries are reconciled by ResyncWorkers
+// worker goroutines; a failed reconciliation is retried with exponential
+// backoff.
 type Reconciler struct {
-	aos AOSWithSharedInformerFactory
+	aos          AOSWithSharedInformerFactory
+	resyncPeriod time.Duration
+	queue        *registryQueue
+	done         chan struct{}
+	activity     *activity
+
+	mu     sync.Mutex
+	synced bool
+	err    error
 }
 
-func NewReconciler(aos AOSWithSharedInformerFactory) *Reconciler {
+// NewReconciler creates a Reconciler. The informers are resynced (and so every
+// registry is reconciled) in every resyncPeriod. If resyncPeriod is 0,
+// DefaultResyncPeriod is used.
+func NewReconciler(aos AOSWithSharedInformerFactory, resyncPeriod time.Duration) *Reconciler {
+	if resyncPeriod == 0 {
+		resyncPeriod = DefaultResyncPeriod
+	}
 	return &Reconciler{
-		aos: aos,
+		aos:          aos,
+		resyncPeriod: resyncPeriod,
+		queue:        newRegistryQueue(),
+		done:         make(chan struct{}),
+		activity:     newActivity(),
 	}
 }
 
+// Start starts the reconciliation loop in the background. The loop stops when
+// ctx is cancelled: the informers are stopped, the queued registries are
+// dropped and the running reconciliations are drained (see ShutdownTimeout).
 func (rec *Reconciler) Start(ctx context.Context) {
 	logger.V(1).Info("starting reconciler")
 	go rec.loop(ctx)
 }
 
+// Done returns a channel that is closed when the reconciliation loop has
+// stopped.
+func (rec *Reconciler) Done() <-chan struct{} {
+	return rec.done
+}
+
+// StuckTimeout is the duration after which a running reconciliation is
+// considered to be stuck, making the Reconciler unhealthy.
+var StuckTimeout = 15 * time.Minute
+
+// Synced returns an error until the informer caches are synchronized and the
+// workers are started.
+func (rec *Reconciler) Synced() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.err != nil {
+		return rec.err
+	}
+	if !rec.synced {
+		return errors.New("informer caches are not synchronized")
+	}
+	return nil
+}
+
+// Healthy returns an error if the reconciliation loop has failed or if a
+// reconciliation is running for longer than StuckTimeout.
+func (rec *Reconciler) Healthy() error {
+	rec.mu.Lock()
+	err := rec.err
+	rec.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if name, duration := rec.activity.longest(); duration > StuckTimeout {
+		return fmt.Errorf("reconciliation of registry %s is running for %s", name, duration.Round(time.Second))
+	}
+	return nil
+}
+
+// fail records the error that stopped the reconciliation loop.
+func (rec *Reconciler) fail(err error, msg string) {
+	logger.Error(err, msg)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.err = fmt.Errorf("%s: %w", msg, err)
+}
+
+// runInformer runs the informer in the background until ctx is cancelled.
+func runInformer(ctx context.Context, wg *sync.WaitGroup, name string, informer cache.SharedIndexInformer) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		informer.Run(ctx.Done())
+		logger.V(1).Info("informer stopped", "informer", name)
+	}()
+}
+
 func (rec *Reconciler) loop(ctx context.Context) {
+	defer close(rec.done)
+	var informers sync.WaitGroup
+	defer informers.Wait()
+	defer rec.queue.ShutDown()
+	// The reconciliations are not interrupted when ctx is cancelled, they
+	// are drained instead. The event handlers use the same context, since
+	// the registries are listed by them during the shutdown, too.
+	actionCtx, cancelActions := context.WithCancel(context.Background())
+	defer cancelActions()
 	logger.V(1).Info("creating shared informer factory",
-		"defaultResync", defaultResync)
-	siFactory := rec.aos.SharedInformerFactory(defaultResync)
+		"resyncPeriod", rec.resyncPeriod)
+	siFactory := rec.aos.SharedInformerFactory(rec.resyncPeriod)
+	secrets := &secretInformers{
+		ctx:          ctx,
+		wg:           &informers,
+		resyncPeriod: rec.resyncPeriod,
+		factory:      rec.aos.KubeSharedInformerFactory,
+		handler: &secretEventHandler{
+			ctx:   actionCtx,
+			aop:   rec.aos,
+			queue: rec.queue,
+		},
+		informers: map[string]cache.SharedIndexInformer{},
+	}
 	registryInformer, err := siFactory.ForResource(schema.GroupVersionResource{
 		Group:    "registryman.kubermatic.com",
 		Version:  "v1alpha1",
 		Resource: "registries",
 	})
 	if err != nil {
-		logger.Error(err, "cannot create registryInformer")
+		rec.fail(err, "cannot create registryInformer")
 		return
 	}
 	registryInformer.Informer().AddEventHandler(
 		&registryEventHandler{
-			ctx:    ctx,
-			aop:    rec.aos,
-			events: rec.aos,
+			ctx:     actionCtx,
+			aop:     rec.aos,
+			queue:   rec.queue,
+			secrets: secrets,
 		})
-	go func() {
-		registryInformer.Informer().Run(ctx.Done())
-		logger.V(-1).Info("registry informer stopped")
-		panic("registry informer stopped")
-	}()
+	runInformer(ctx, &informers, "registry", registryInformer.Informer())
 
 	projectInformer, err := siFactory.ForResource(schema.GroupVersionResource{
 		Group:    "registryman.kubermatic.com",
@@ -87,20 +205,16 @@ func (rec *Reconciler) loop(ctx context.Context) {
 		Resource: "projects",
 	})
 	if err != nil {
-		logger.Error(err, "cannot create projectInformer")
+		rec.fail(err, "cannot create projectInformer")
 		return
 	}
 	projectInformer.Informer().AddEventHandler(
 		&projectEventHandler{
-			ctx:    ctx,
-			aop:    rec.aos,
-			events: rec.aos,
+			ctx:   actionCtx,
+			aop:   rec.aos,
+			queue: rec.queue,
 		})
-	go func() {
-		projectInformer.Informer().Run(ctx.Done())
-		logger.V(-1).Info("project informer stopped")
-		panic("project informer stopped")
-	}()
+	runInformer(ctx, &informers, "project", projectInformer.Informer())
 
 	scannerInformer, err := siFactory.ForResource(schema.GroupVersionResource{
 		Group:    "registryman.kubermatic.com",
@@ -108,20 +222,65 @@ func (rec *Reconciler) loop(ctx context.Context) {
 		Resource: "scanners",
 	})
 	if err != nil {
-		logger.Error(err, "cannot create scannerInformer")
+		rec.fail(err, "cannot create scannerInformer")
 		return
 	}
 	scannerInformer.Informer().AddEventHandler(
 		&scannerEventHandler{
-			ctx:    ctx,
-			aop:    rec.aos,
-			events: rec.aos,
+			ctx:   actionCtx,
+			aop:   rec.aos,
+			queue: rec.queue,
 		})
-	go func() {
-		scannerInformer.Informer().Run(ctx.Done())
-		logger.V(-1).Info("scanner informer stopped")
-		panic("scanner informer stopped")
-	}()
+	runInformer(ctx, &informers, "scanner", scannerInformer.Informer())
+
+	if !cache.WaitForCacheSync(ctx.Done(),
+		registryInformer.Informer().HasSynced,
+		projectInformer.Informer().HasSynced,
+		scannerInformer.Informer().HasSynced,
+		secrets.HasSynced,
+	) {
+		if ctx.Err() == nil {
+			rec.fail(errors.New("informers stopped"), "informer caches are not synchronized")
+		}
+		return
+	}
+	rec.mu.Lock()
+	rec.synced = true
+	rec.mu.Unlock()
+	workers := ResyncWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	var running sync.WaitGroup
+	logger.V(1).Info("starting workers", "workers", workers)
+	for i := 0; i < workers; i++ {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			for processNextItem(actionCtx, ctx.Done(), rec.aos, rec.queue, rec.activity) {
+			}
+		}()
+	}
 	<-ctx.Done()
 	logger.V(1).Info("stopping reconciler loop")
+	rec.queue.ShutDown()
+	drained := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(drained)
+	}()
+	if leadershipLost(ctx) {
+		// the next leader may already be running
+		logger.V(-1).Info("leader lease lost, cancelling the running reconciliations")
+		cancelActions()
+	}
+	select {
+	case <-drained:
+	case <-time.After(ShutdownTimeout):
+		logger.V(-1).Info("shutdown timeout exceeded, cancelling the running reconciliations",
+			"timeout", ShutdownTimeout)
+		cancelActions()
+		<-drained
+	}
+	logger.V(1).Info("reconciler loop stopped")
 }
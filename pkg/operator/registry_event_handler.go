@@ -18,61 +18,56 @@ package operator
 
 import (
 	"context"
-	"fmt"
+	"reflect"
 
-	"k8s.io/apimachinery/pkg/runtime"
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 )
 
+// registryEventHandler enqueues the registries affected by the change of a
+// Registry resource. Since the replication rules of a registry refer to the
+// other registries, a change of the registry set or of a registry spec
+// enqueues every registry.
 type registryEventHandler struct {
-	ctx    context.Context
-	aop    SyncableResources
-	events EventRecorder
+	ctx     context.Context
+	aop     SyncableResources
+	queue   workqueue.RateLimitingInterface
+	secrets *secretInformers
 }
 
 var _ cache.ResourceEventHandler = &registryEventHandler{}
 
 func (reh *registryEventHandler) OnAdd(obj interface{}) {
 	logger.V(1).Info("registryEventHander.OnAdd")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Registry",
-			"event", "OnAdd",
-		)
-		reh.events.RecordEventWarning(obj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
+	if registry, ok := obj.(*api.Registry); ok {
+		reh.secrets.watchRegistry(registry)
 	}
+	enqueueRegistries(reh.queue, reh.aop.GetRegistries(reh.ctx)...)
 }
 
 func (reh *registryEventHandler) OnUpdate(oldObj, newObj interface{}) {
 	logger.V(1).Info("registryEventHander.OnUpdate")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Registry",
-			"event", "OnUpdate",
-		)
-		reh.events.RecordEventWarning(oldObj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
+	oldRegistry, oldOk := oldObj.(*api.Registry)
+	newRegistry, newOk := newObj.(*api.Registry)
+	if !oldOk || !newOk {
+		return
+	}
+	reh.secrets.watchRegistry(newRegistry)
+	switch {
+	case oldRegistry.GetResourceVersion() == newRegistry.GetResourceVersion():
+		// periodic resync, only the registry itself is reconciled
+		enqueueRegistries(reh.queue, newRegistry)
+	case oldRegistry.GetGeneration() == newRegistry.GetGeneration() &&
+		reflect.DeepEqual(oldRegistry.GetAnnotations(), newRegistry.GetAnnotations()) &&
+		(oldRegistry.GetDeletionTimestamp() == nil) == (newRegistry.GetDeletionTimestamp() == nil):
+		// only the status has changed
+	default:
+		enqueueRegistries(reh.queue, reh.aop.GetRegistries(reh.ctx)...)
 	}
 }
 
 func (reh *registryEventHandler) OnDelete(obj interface{}) {
 	logger.V(1).Info("registryEventHander.OnDelete")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Registry",
-			"event", "OnDelete",
-		)
-		reh.events.RecordEventWarning(obj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
-	}
+	enqueueRegistries(reh.queue, reh.aop.GetRegistries(reh.ctx)...)
 }
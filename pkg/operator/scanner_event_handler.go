@@ -18,61 +18,63 @@ package operator
 
 import (
 	"context"
-	"fmt"
+	"reflect"
 
-	"k8s.io/apimachinery/pkg/runtime"
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 )
 
+// scannerEventHandler enqueues the registries of the projects that use the
+// changed scanner.
 type scannerEventHandler struct {
-	ctx    context.Context
-	aop    SyncableResources
-	events EventRecorder
+	ctx   context.Context
+	aop   SyncableResources
+	queue workqueue.RateLimitingInterface
 }
 
 var _ cache.ResourceEventHandler = &scannerEventHandler{}
 
-func (reh *scannerEventHandler) OnAdd(obj interface{}) {
-	logger.V(1).Info("scannerEventHander.OnAdd")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Scanner",
-			"event", "OnAdd",
-		)
-		reh.events.RecordEventWarning(obj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
+func (seh *scannerEventHandler) enqueue(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	scanner, ok := obj.(*api.Scanner)
+	if !ok {
+		return
+	}
+	registries := seh.aop.GetRegistries(seh.ctx)
+	for _, project := range seh.aop.GetProjects(seh.ctx) {
+		if project.Spec.Scanner == scanner.GetName() {
+			enqueueRegistries(seh.queue, registriesOfProject(project, registries)...)
+		}
 	}
 }
 
-func (reh *scannerEventHandler) OnUpdate(oldObj, newObj interface{}) {
+func (seh *scannerEventHandler) OnAdd(obj interface{}) {
+	logger.V(1).Info("scannerEventHander.OnAdd")
+	seh.enqueue(obj)
+}
+
+func (seh *scannerEventHandler) OnUpdate(oldObj, newObj interface{}) {
 	logger.V(1).Info("scannerEventHander.OnUpdate")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Scanner",
-			"event", "OnUpdate",
-		)
-		reh.events.RecordEventWarning(oldObj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
+	oldScanner, oldOk := oldObj.(*api.Scanner)
+	newScanner, newOk := newObj.(*api.Scanner)
+	if oldOk && newOk {
+		switch {
+		case oldScanner.GetResourceVersion() == newScanner.GetResourceVersion():
+			// periodic resync, the registries are resynced by their own events
+			return
+		case oldScanner.GetGeneration() == newScanner.GetGeneration() &&
+			reflect.DeepEqual(oldScanner.GetAnnotations(), newScanner.GetAnnotations()):
+			// only the status has changed
+			return
+		}
 	}
+	seh.enqueue(newObj)
 }
 
-func (reh *scannerEventHandler) OnDelete(obj interface{}) {
+func (seh *scannerEventHandler) OnDelete(obj interface{}) {
 	logger.V(1).Info("scannerEventHander.OnDelete")
-	err := FullResync(reh.ctx, reh.aop, false)
-	if err != nil {
-		logger.Error(err, "failed to synchronize states",
-			"kind", "Scanner",
-			"event", "OnDelete",
-		)
-		reh.events.RecordEventWarning(obj.(runtime.Object),
-			"RegistryUpdateFailed",
-			fmt.Sprintf("Failed to synchronize states: %s", err.Error()),
-		)
-	}
+	seh.enqueue(obj)
 }
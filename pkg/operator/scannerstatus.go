@@ -0,0 +1,181 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+)
+
+// scannerStatusWriter is implemented by the stores that can persist the status
+// of the Scanner resources.
+type scannerStatusWriter interface {
+	registry.ApiObjectProvider
+
+	// UpdateScannerStatus persists the status of the given Scanner
+	// resource.
+	UpdateScannerStatus(context.Context, *api.Scanner) error
+}
+
+// getScanner returns the Scanner resource with the given name, or nil if it
+// does not exist.
+func getScanner(ctx context.Context, aop registry.ApiObjectProvider, name string) *api.Scanner {
+	for _, scanner := range aop.GetScanners(ctx) {
+		if scanner.GetName() == name {
+			return scanner
+		}
+	}
+	return nil
+}
+
+// updateScannerStatus modifies the status of the scanner with mutate and
+// persists it if it has changed. If the Scanner resource has been modified in
+// the meantime, the latest version is read and mutate is applied again.
+func updateScannerStatus(ctx context.Context, store scannerStatusWriter, scanner *api.Scanner, mutate func(*api.ScannerProvisioningStatus)) error {
+	scanner = scanner.DeepCopy()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if scanner.Status == nil {
+			scanner.Status = &api.ScannerProvisioningStatus{}
+		}
+		original := scanner.Status.DeepCopy()
+		mutate(scanner.Status)
+		if equality.Semantic.DeepEqual(original, scanner.Status) {
+			return nil
+		}
+		err := store.UpdateScannerStatus(ctx, scanner)
+		if apierrors.IsConflict(err) {
+			latest := getScanner(ctx, store, scanner.GetName())
+			if latest == nil {
+				return fmt.Errorf("scanner %s not found", scanner.GetName())
+			}
+			scanner = latest.DeepCopy()
+		}
+		return err
+	})
+}
+
+// scannerRegistryStatuses inspects the scanners registered at the registry. The
+// registered scanners are pinged if the registry supports it. The projects
+// using the scanners are taken from the actual registry status; if it is nil,
+// the projects are not known.
+//
+// If the registry does not manage the scanners at the registry level, nil is
+// returned. If the registered scanners cannot be listed, the error is returned
+// besides the statuses.
+func scannerRegistryStatuses(ctx context.Context, reg globalregistry.Registry, scanners []*api.Scanner, registryStatus *api.RegistryStatus) (map[string]*api.ScannerRegistryStatus, error) {
+	regWithScanners, ok := reg.(globalregistry.RegistryWithScanners)
+	if !ok {
+		return nil, nil
+	}
+	registered, listErr := regWithScanners.ListScanners(ctx)
+	statuses := make(map[string]*api.ScannerRegistryStatus, len(scanners))
+	for _, scanner := range scanners {
+		status := &api.ScannerRegistryStatus{
+			Registry: reg.GetName(),
+			Health:   api.ScannerHealthUnknown,
+		}
+		statuses[scanner.GetName()] = status
+		if registryStatus != nil {
+			for _, project := range registryStatus.Projects {
+				if strings.EqualFold(project.ScannerStatus.Name, scanner.GetName()) {
+					status.Projects = append(status.Projects, project.Name)
+				}
+			}
+			sort.Strings(status.Projects)
+		}
+		if listErr != nil {
+			status.LastError = listErr.Error()
+			continue
+		}
+		for _, registeredScanner := range registered {
+			if !strings.EqualFold(registeredScanner.GetName(), scanner.GetName()) {
+				continue
+			}
+			status.Registered = true
+			if pingable, ok := registeredScanner.(globalregistry.ScannerWithHealth); ok {
+				if err := pingable.Ping(ctx); err != nil {
+					status.Health = api.ScannerUnhealthy
+					status.LastError = err.Error()
+				} else {
+					status.Health = api.ScannerHealthy
+				}
+			}
+			break
+		}
+	}
+	return statuses, listErr
+}
+
+// updateScannerStatuses records the state of the scanners at the registry in
+// the status of the Scanner resources. The entries of the registries that do
+// not manage scanners or that have been removed are dropped. If the scanner
+// registrations could not be listed, the previous registration state is kept;
+// if the registry status is nil, the previous project list is kept.
+func updateScannerStatuses(ctx context.Context, store scannerStatusWriter, reg globalregistry.Registry, registryStatus *api.RegistryStatus) error {
+	scanners := store.GetScanners(ctx)
+	if len(scanners) == 0 {
+		return nil
+	}
+	statuses, listErr := scannerRegistryStatuses(ctx, reg, scanners, registryStatus)
+	registries := map[string]bool{}
+	for _, apiRegistry := range store.GetRegistries(ctx) {
+		registries[apiRegistry.GetName()] = true
+	}
+	errs := []error{}
+	for _, scanner := range scanners {
+		status, found := statuses[scanner.GetName()]
+		errs = append(errs, updateScannerStatus(ctx, store, scanner, func(scannerStatus *api.ScannerProvisioningStatus) {
+			entries := []api.ScannerRegistryStatus{}
+			var previous *api.ScannerRegistryStatus
+			for i, entry := range scannerStatus.Registries {
+				switch {
+				case entry.Registry == reg.GetName():
+					previous = &scannerStatus.Registries[i]
+				case registries[entry.Registry]:
+					entries = append(entries, entry)
+				}
+			}
+			if found {
+				entry := *status
+				if previous != nil {
+					if listErr != nil {
+						entry.Registered = previous.Registered
+					}
+					if registryStatus == nil {
+						entry.Projects = previous.Projects
+					}
+				}
+				entries = append(entries, entry)
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].Registry < entries[j].Registry
+			})
+			scannerStatus.Registries = entries
+		}))
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,125 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type pingTestScanner struct {
+	name    string
+	healthy bool
+}
+
+var _ globalregistry.ScannerWithHealth = &pingTestScanner{}
+
+func (s *pingTestScanner) GetName() string { return s.name }
+func (s *pingTestScanner) GetURL() string  { return "http://" + s.name }
+func (s *pingTestScanner) Ping(context.Context) error {
+	if !s.healthy {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+// scannerTestRegistry is a registry where the scanners are registered at the
+// registry level.
+type scannerTestRegistry struct {
+	globalregistry.Registry
+	scanners []globalregistry.Scanner
+	err      error
+}
+
+var _ globalregistry.RegistryWithScanners = &scannerTestRegistry{}
+
+func (r *scannerTestRegistry) GetName() string { return "local" }
+func (r *scannerTestRegistry) ListScanners(context.Context) ([]globalregistry.Scanner, error) {
+	return r.scanners, r.err
+}
+
+func TestUpdateScannerStatuses(t *testing.T) {
+	newScanner := func(name string) *api.Scanner {
+		return &api.Scanner{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: &api.ScannerProvisioningStatus{
+				Registries: []api.ScannerRegistryStatus{
+					{Registry: "removed", Registered: true},
+				},
+			},
+		}
+	}
+	resources := &statusTestResources{
+		syncTestResources: &syncTestResources{
+			registries: []*api.Registry{newSyncTestRegistry("local", "Local")},
+			scanners:   []*api.Scanner{newScanner("trivy"), newScanner("clair"), newScanner("anchore")},
+		},
+		scannerStatuses: map[string]*api.ScannerProvisioningStatus{},
+	}
+	reg := &scannerTestRegistry{
+		scanners: []globalregistry.Scanner{
+			&pingTestScanner{name: "trivy", healthy: true},
+			&pingTestScanner{name: "clair"},
+		},
+	}
+	registryStatus := &api.RegistryStatus{
+		Projects: []api.ProjectStatus{
+			{Name: "b", ScannerStatus: api.ScannerStatus{Name: "trivy"}},
+			{Name: "a", ScannerStatus: api.ScannerStatus{Name: "trivy"}},
+			{Name: "c", ScannerStatus: api.ScannerStatus{Name: "clair"}},
+		},
+	}
+	ctx := context.Background()
+	if err := updateScannerStatuses(ctx, resources, reg, registryStatus); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]api.ScannerRegistryStatus{
+		"trivy":   {Registry: "local", Registered: true, Health: api.ScannerHealthy, Projects: []string{"a", "b"}},
+		"clair":   {Registry: "local", Registered: true, Health: api.ScannerUnhealthy, Projects: []string{"c"}, LastError: "connection refused"},
+		"anchore": {Registry: "local", Health: api.ScannerHealthUnknown},
+	} {
+		status := resources.scannerStatuses[name]
+		if status == nil || len(status.Registries) != 1 || !reflect.DeepEqual(status.Registries[0], expected) {
+			t.Errorf("unexpected status of scanner %s: %+v", name, status)
+		}
+	}
+
+	// a failed listing keeps the previous registration state and projects
+	for _, scanner := range resources.scanners {
+		scanner.Status = resources.scannerStatuses[scanner.GetName()]
+	}
+	reg.err = errors.New("service unavailable")
+	if err := updateScannerStatuses(ctx, resources, reg, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := api.ScannerRegistryStatus{
+		Registry:   "local",
+		Registered: true,
+		Health:     api.ScannerHealthUnknown,
+		Projects:   []string{"a", "b"},
+		LastError:  "service unavailable",
+	}
+	if status := resources.scannerStatuses["trivy"]; !reflect.DeepEqual(status.Registries[0], expected) {
+		t.Errorf("unexpected status of scanner trivy: %+v", status)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// secretEventHandler enqueues the registries whose credentialsRef or TLS
+// settings refer to the changed Secret.
+type secretEventHandler struct {
+	ctx   context.Context
+	aop   SyncableResources
+	queue workqueue.RateLimitingInterface
+}
+
+var _ cache.ResourceEventHandler = &secretEventHandler{}
+
+// referencingRegistries returns the registries whose credentials or TLS
+// settings are stored in the given Secret.
+func (seh *secretEventHandler) referencingRegistries(obj interface{}) []*api.Registry {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return nil
+	}
+	registries := []*api.Registry{}
+	for _, reg := range seh.aop.GetRegistries(seh.ctx) {
+		if registry.ReferencesSecret(reg, secret.GetNamespace(), secret.GetName()) {
+			registry.InvalidateCredentials(reg)
+			registries = append(registries, reg)
+		}
+	}
+	return registries
+}
+
+func (seh *secretEventHandler) resync(obj interface{}) {
+	enqueueRegistries(seh.queue, seh.referencingRegistries(obj)...)
+}
+
+func (seh *secretEventHandler) OnAdd(obj interface{}) {
+	logger.V(1).Info("secretEventHandler.OnAdd")
+	seh.resync(obj)
+}
+
+func (seh *secretEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	logger.V(1).Info("secretEventHandler.OnUpdate")
+	oldSecret, oldOk := oldObj.(*corev1.Secret)
+	newSecret, newOk := newObj.(*corev1.Secret)
+	if oldOk && newOk && oldSecret.GetResourceVersion() == newSecret.GetResourceVersion() {
+		// periodic resync, the Secret has not changed
+		return
+	}
+	seh.resync(newObj)
+}
+
+func (seh *secretEventHandler) OnDelete(obj interface{}) {
+	logger.V(1).Info("secretEventHandler.OnDelete")
+	seh.resync(obj)
+}
+
+// secretInformers watches the Secrets of the namespaces referenced by the
+// registries. Instead of caching every Secret of the cluster, an informer is
+// started for each referenced namespace when it is first seen.
+type secretInformers struct {
+	ctx          context.Context
+	wg           *sync.WaitGroup
+	resyncPeriod time.Duration
+	factory      func(defaultResync time.Duration, namespace string) informers.SharedInformerFactory
+	handler      cache.ResourceEventHandler
+
+	mu        sync.Mutex
+	informers map[string]cache.SharedIndexInformer
+}
+
+// watchRegistry starts the informers of the namespaces of the Secrets
+// referenced by the registry. It is safe to call on a nil receiver.
+func (si *secretInformers) watchRegistry(reg *api.Registry) {
+	if si == nil || reg == nil {
+		return
+	}
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	for _, namespace := range registry.SecretNamespaces(reg) {
+		if _, found := si.informers[namespace]; found {
+			continue
+		}
+		logger.V(1).Info("watching secrets", "namespace", namespace)
+		informer := si.factory(si.resyncPeriod, namespace).Core().V1().Secrets().Informer()
+		informer.AddEventHandler(si.handler)
+		si.informers[namespace] = informer
+		runInformer(si.ctx, si.wg, "secret/"+namespace, informer)
+	}
+}
+
+// HasSynced returns true if the caches of the started informers are
+// synchronized.
+func (si *secretInformers) HasSynced() bool {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	for _, informer := range si.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
@@ -18,12 +18,17 @@ package operator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config"
 	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+	"github.com/kubermatic-labs/registryman/pkg/metrics"
 )
 
 type RegistryStore interface {
@@ -39,6 +44,11 @@ type StatusUpdater struct {
 	interval time.Duration
 	store    RegistryStore
 	events   EventRecorder
+	running  sync.WaitGroup
+	done     chan struct{}
+
+	mu       sync.Mutex
+	lastTick time.Time
 }
 
 func NewStatusUpdater(interval time.Duration, store RegistryStore) *StatusUpdater {
@@ -46,27 +56,67 @@ func NewStatusUpdater(interval time.Duration, store RegistryStore) *StatusUpdate
 		interval: interval,
 		store:    store,
 		events:   store,
+		done:     make(chan struct{}),
 	}
 }
 
+// Start starts the status update loop in the background. The loop stops when
+// ctx is cancelled.
 func (sup *StatusUpdater) Start(ctx context.Context) {
 	logger.V(1).Info("starting statusupdater")
 	go sup.loop(ctx)
 }
 
+// Done returns a channel that is closed when the status update loop and the
+// running status updates have stopped.
+func (sup *StatusUpdater) Done() <-chan struct{} {
+	return sup.done
+}
+
+// Healthy returns an error if the status update loop has not ticked for three
+// intervals. It returns nil if the loop has not been started.
+func (sup *StatusUpdater) Healthy() error {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	if sup.lastTick.IsZero() {
+		return nil
+	}
+	if since := time.Since(sup.lastTick); since > 3*sup.interval {
+		return fmt.Errorf("status update loop has not run for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+func (sup *StatusUpdater) tick() {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	sup.lastTick = time.Now()
+}
+
 func (sup *StatusUpdater) loop(ctx context.Context) {
+	defer close(sup.done)
+	sup.tick()
 	timer := time.NewTicker(sup.interval)
 	defer timer.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			logger.V(1).Info("stopping statusupdater loop")
+			sup.running.Wait()
 			return
 		case <-timer.C:
+			if ctx.Err() != nil {
+				continue
+			}
 			logger.V(1).Info("statusupdater tick")
+			sup.tick()
 			registries := sup.store.GetRegistries(ctx)
 			for _, registry := range registries {
-				go sup.updateRegistryStatus(ctx, registry)
+				sup.running.Add(1)
+				go func(registry *api.Registry) {
+					defer sup.running.Done()
+					sup.updateRegistryStatus(ctx, registry)
+				}(registry)
 			}
 		}
 	}
@@ -79,26 +129,80 @@ func (sup *StatusUpdater) updateRegistryStatus(ctx context.Context, reg *api.Reg
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithTimeout(ctx, sup.interval)
 	defer cancel()
-	realReg, err := registry.New(reg, sup.store).ToReal()
+	expectedReg := registry.New(reg, sup.store)
+	realReg, err := expectedReg.ToReal()
 	if err != nil {
 		logger.Error(err, "failed to create a real registry")
 		return
 	}
 	registryStatus, err := reconciler.GetRegistryStatus(ctx, realReg)
+	if errors.Is(err, globalregistry.ErrUnauthorized) {
+		if refreshed, refreshErr := expectedReg.RefreshCredentials(); refreshErr == nil && refreshed {
+			registryStatus, err = reconciler.GetRegistryStatus(ctx, realReg)
+		}
+	}
 	logger.V(1).Info("getting registrystatus",
 		"registry", reg.GetName(),
 		"status", registryStatus,
 	)
 	if err != nil {
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			logger.V(1).Info("registry status update interrupted",
+				"registry", reg.GetName(),
+			)
+			return
+		}
 		logger.Error(err, "failed getting registry status in statusupdater")
-		return
+	} else {
+		storageUsed := make(map[string]int, len(registryStatus.Projects))
+		for _, project := range registryStatus.Projects {
+			storageUsed[project.Name] = project.StorageUsed
+		}
+		metrics.SetProjectStorageUsed(reg.GetName(), storageUsed)
 	}
-	reg.Status = registryStatus
-	err = sup.store.UpdateRegistryStatus(ctx, reg)
-	if err != nil {
-		logger.Error(err, "failed updating registry status in statusupdater")
+	// The conditions and the sync related fields set by the reconciliation
+	// are preserved.
+	statusErr := updateRegistryStatus(ctx, sup.store, reg, func(status *api.RegistryStatus) {
+		if err == nil {
+			status.Projects = registryStatus.Projects
+			status.Capabilities = registryStatus.Capabilities
+			status.Version = registryStatus.Version
+		}
+		setConnectionConditions(status, reg.GetGeneration(), err)
+	})
+	if statusErr != nil {
+		logger.Error(statusErr, "failed updating registry status in statusupdater")
 		sup.events.RecordEventWarning(reg,
 			"StatusUpdateFailed",
-			fmt.Sprintf("failed updating registry status in statusupdater: %s", err.Error()))
+			fmt.Sprintf("failed updating registry status in statusupdater: %s", statusErr.Error()))
+	}
+	if err != nil {
+		registryStatus = nil
+	}
+	if ctx.Err() != nil {
+		// the resources are not listed any more, the scanner and
+		// project statuses cannot be computed
+		logger.V(1).Info("registry status update interrupted",
+			"registry", reg.GetName(),
+		)
+		return
+	}
+	if scannerStore, ok := sup.store.(scannerStatusWriter); ok {
+		statusErr = updateScannerStatuses(ctx, scannerStore, realReg, registryStatus)
+		if statusErr != nil {
+			logger.Error(statusErr, "failed updating scanner statuses in statusupdater")
+		}
+	}
+	if projectStore, ok := sup.store.(projectStatusWriter); ok {
+		expectedStatus, expectedErr := reconciler.GetRegistryStatus(ctx, expectedReg)
+		if expectedErr != nil {
+			logger.Error(expectedErr, "failed getting expected registry status in statusupdater")
+			return
+		}
+		statusErr = updateProjectStatuses(ctx, projectStore, config.NewExpectedProvider(sup.store),
+			reg.GetName(), registryStatus, expectedStatus, err, false)
+		if statusErr != nil {
+			logger.Error(statusErr, "failed updating project statuses in statusupdater")
+		}
 	}
 }
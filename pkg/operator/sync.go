@@ -20,11 +20,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sync"
+	"time"
 
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
 	"github.com/kubermatic-labs/registryman/pkg/config"
 	"github.com/kubermatic-labs/registryman/pkg/config/registry"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
 	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+	"github.com/kubermatic-labs/registryman/pkg/metrics"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 type SyncableResources interface {
@@ -32,72 +38,296 @@ type SyncableResources interface {
 	reconciler.SideEffectPerformer
 }
 
-func resyncRegistry(ctx context.Context, sres SyncableResources, expectedProvider *config.ExpectedProvider, expectedRegistry *registry.Registry, dryRun bool) (bool, error) {
+// syncResult is the outcome of a registry resync.
+type syncResult struct {
+	// compared is true if the expected and the actual states of the
+	// registry have been compared.
+	compared bool
+
+	// changed is true if actions have been performed on the registry.
+	changed bool
+
+	// pending is the number of actions that have not been performed
+	// successfully.
+	pending int
+
+	// paused is true if the reconciliation of the registry is paused, so
+	// the planned actions are not performed.
+	paused bool
+
+	// expected and actual are the statuses of the registry before the
+	// actions were performed. They are nil if they could not be acquired.
+	expected *api.RegistryStatus
+	actual   *api.RegistryStatus
+}
+
+// resyncRegistry synchronizes the state of a registry. The registry API
+// lookups are memoised in a globalregistry.Cache for the duration of the
+// resync.
+//
+// The status of the paused projects is collected, but no actions are planned
+// for them. If the whole registry is paused, the planned actions are not
+// performed.
+func resyncRegistry(ctx context.Context, sres SyncableResources, expectedProvider *config.ExpectedProvider, expectedRegistry *registry.Registry, paused pauseState, dryRun bool) (syncResult, error) {
+	result := syncResult{
+		paused: paused.registry,
+	}
 	logger.Info("inspecting registry", "registry_name", expectedRegistry.GetName())
+	cache := globalregistry.NewCache()
+	ctx = globalregistry.WithCache(ctx, cache)
+	defer func() {
+		hits, misses := cache.Stats()
+		logger.V(1).Info("registry lookups",
+			"registry_name", expectedRegistry.GetName(),
+			"cache_hits", hits,
+			"cache_misses", misses,
+		)
+	}()
 	regStatusExpected, err := reconciler.GetRegistryStatus(ctx, expectedRegistry)
 	if err != nil {
-		return false, err
+		return result, err
 	}
 	logger.V(1).Info("expected registry status acquired", "status", regStatusExpected)
+	result.expected = regStatusExpected
 	actualRegistry, err := expectedRegistry.ToReal()
 	if err != nil {
-		return false, err
+		return result, err
 	}
 	regStatusActual, err := reconciler.GetRegistryStatus(ctx, actualRegistry)
 	if err != nil {
-		return false, err
+		return result, err
 	}
 	logger.V(1).Info("actual registry status acquired", "status", regStatusActual)
-	actions := reconciler.Compare(expectedProvider, regStatusActual, regStatusExpected)
-	logger.Info("ACTIONS:")
+	result.actual = regStatusActual
+	ownership, err := ownershipOf(ctx, sres, expectedRegistry)
+	if err != nil {
+		return result, err
+	}
+	if err = seedOwnership(ctx, sres, ownership, expectedRegistry.GetName(), regStatusActual, dryRun); err != nil {
+		return result, err
+	}
+	// The resources are not listed once ctx is cancelled, the actions are
+	// not planned from the partial listings.
+	if err = ctx.Err(); err != nil {
+		return result, err
+	}
+	actions := reconciler.Compare(expectedProvider,
+		paused.withoutPausedProjects(regStatusActual),
+		paused.withoutPausedProjects(regStatusExpected),
+		ownership)
+	logger.Info("ACTIONS:", "registry_name", expectedRegistry.GetName())
+	pendingActions := metrics.PendingActions.WithLabelValues(expectedRegistry.GetName())
+	pendingActions.Set(float64(len(actions)))
+	result.compared = true
+	result.pending = len(actions)
 	if len(actions) == 0 {
-		return false, nil
+		return result, nil
+	}
+	if paused.registry {
+		for _, action := range actions {
+			logger.Info(action.String(), "paused", true)
+		}
+		return result, nil
 	}
+	result.changed = !dryRun
 	for _, action := range actions {
 		if !dryRun {
 			logger.Info(action.String())
-			sideEffect, err := action.Perform(ctx, actualRegistry)
+			sideEffect, err := reconciler.PerformAction(ctx, action, actualRegistry)
 			if err != nil {
-				if errors.Is(err, globalregistry.ErrRecoverableError) {
-					logger.V(-1).Info(err.Error())
-				} else {
-					return false, err
+				// The side effect is returned only if it describes
+				// a partial change performed by the failed action.
+				if sideEffect != nil {
+					if seErr := sideEffect.Perform(ctx, sres); seErr != nil {
+						logger.Error(seErr, "side effect of the failed action failed",
+							"registry_name", expectedRegistry.GetName(),
+						)
+					}
 				}
+				if reconciler.ErrorHandlingOf(err) != reconciler.SkipOnError {
+					recordAction(expectedRegistry, action, metrics.ResultFailure)
+					return result, err
+				}
+				recordAction(expectedRegistry, action, metrics.ResultSkipped)
+				logger.V(-1).Info("action skipped",
+					"registry_name", expectedRegistry.GetName(),
+					"error", err.Error(),
+				)
+				continue
+			} else {
+				recordAction(expectedRegistry, action, metrics.ResultSuccess)
+				pendingActions.Dec()
+				result.pending--
 			}
 			if err = sideEffect.Perform(ctx, sres); err != nil {
-				return false, err
+				return result, err
 			}
 		} else {
 			logger.Info(action.String(), "dry-run", dryRun)
 		}
 	}
 
-	return !dryRun, nil
+	return result, nil
 }
 
-// FullResync performs a complete state synchronization over all provisioned
-// Registry resources.
-func FullResync(ctx context.Context, aop SyncableResources, dryRun bool) error {
-	expectedProvider := config.NewExpectedProvider(aop)
-	apiRegistries := aop.GetRegistries(ctx)
+// recordAction counts the performed action in the metrics. The action label is
+// the name of the action type, e.g. projectAddAction.
+func recordAction(reg globalregistry.Registry, action reconciler.Action, result string) {
+	metrics.ActionsTotal.WithLabelValues(
+		reg.GetName(),
+		reflect.Indirect(reflect.ValueOf(action)).Type().Name(),
+		result,
+	).Inc()
+}
+
+// ResyncWorkers is the maximum number of registries that are reconciled
+// concurrently by FullResync.
+var ResyncWorkers = 4
+
+// syncRegistry reconciles a registry. If the registry rejects the credentials,
+// they are refreshed and the reconciliation is retried once. The outcome is
+// recorded as an event of the Registry resource and, if aop can persist them,
+// in the conditions of the registry status and in the status of the projects.
+//
+// If aop can persist the finalizers, the cleanup finalizer is added to the
+// registry and to its projects. The finalizers of the deleted resources are
+// removed once they have been removed from the registries.
+func syncRegistry(ctx context.Context, aop SyncableResources, expectedProvider *config.ExpectedProvider, apiRegistry *api.Registry, dryRun bool) error {
 	eventRecorder, canRecordEvent := aop.(EventRecorder)
-	for _, apiRegistry := range apiRegistries {
-		expectedRegistry := registry.New(apiRegistry, aop)
-		changed, err := resyncRegistry(ctx, aop, expectedProvider, expectedRegistry, dryRun)
-		if err != nil {
-			if canRecordEvent {
-				eventRecorder.RecordEventWarning(apiRegistry,
-					"RegistryUpdateFailed",
-					fmt.Sprintf("Error updating registry: %s", err.Error()))
+	finalizers, canFinalize := aop.(finalizerWriter)
+	canFinalize = canFinalize && !dryRun
+	if canFinalize {
+		if apiRegistry.GetDeletionTimestamp() != nil &&
+			(api.IsOrphaned(apiRegistry) || !hasFinalizer(apiRegistry)) {
+			// the remote resources are not cleaned up
+			if err := releaseRegistry(ctx, finalizers, apiRegistry); err != nil {
+				return fmt.Errorf("registry %s: %w", apiRegistry.GetName(), err)
 			}
-			return err
+			return nil
 		}
-		if changed && canRecordEvent {
-			eventRecorder.RecordEventNormal(apiRegistry,
-				"RegistryUpdated",
-				"Registry successfully updated")
+		if err := ensureFinalizers(ctx, finalizers, apiRegistry); err != nil {
+			return fmt.Errorf("registry %s: %w", apiRegistry.GetName(), err)
+		}
+	}
+	expectedRegistry := registry.New(apiRegistry, aop)
+	paused := pauseStateOf(ctx, aop, apiRegistry.GetName())
+	start := time.Now()
+	outcome, err := resyncRegistry(ctx, aop, expectedProvider, expectedRegistry, paused, dryRun)
+	if errors.Is(err, globalregistry.ErrUnauthorized) {
+		// The credentials may have been rotated in the backend.
+		refreshed, refreshErr := expectedRegistry.RefreshCredentials()
+		if refreshErr != nil {
+			logger.Error(refreshErr, "cannot refresh registry credentials",
+				"registry_name", expectedRegistry.GetName())
+		} else if refreshed {
+			logger.Info("registry credentials refreshed, retrying",
+				"registry_name", expectedRegistry.GetName())
+			outcome, err = resyncRegistry(ctx, aop, expectedProvider, expectedRegistry, paused, dryRun)
+		}
+	}
+	result := metrics.ResultSuccess
+	if err != nil {
+		result = metrics.ResultFailure
+	}
+	metrics.ReconcileDuration.WithLabelValues(apiRegistry.GetName(), result).
+		Observe(time.Since(start).Seconds())
+	if statusWriter, ok := aop.(registryStatusWriter); ok && !dryRun && ctx.Err() == nil {
+		statusErr := updateRegistryStatus(ctx, statusWriter, apiRegistry, func(status *api.RegistryStatus) {
+			setConnectionConditions(status, apiRegistry.GetGeneration(), err)
+			setSyncConditions(status, apiRegistry.GetGeneration(), outcome, err)
+		})
+		if statusErr != nil {
+			logger.Error(statusErr, "failed updating registry status",
+				"registry_name", apiRegistry.GetName())
+		}
+	}
+	if statusWriter, ok := aop.(projectStatusWriter); ok && !dryRun && ctx.Err() == nil {
+		actual := outcome.actual
+		if err == nil && outcome.pending == 0 {
+			// the registry has reached the expected state
+			actual = paused.reconciledStatus(outcome.actual, outcome.expected)
+		}
+		statusErr := updateProjectStatuses(ctx, statusWriter, expectedProvider,
+			apiRegistry.GetName(), actual, outcome.expected, err, true)
+		if statusErr != nil {
+			logger.Error(statusErr, "failed updating project statuses",
+				"registry_name", apiRegistry.GetName())
+		}
+	}
+	if err != nil {
+		if canRecordEvent {
+			eventRecorder.RecordEventWarning(apiRegistry,
+				"RegistryUpdateFailed",
+				fmt.Sprintf("Error updating registry: %s", err.Error()))
+		}
+		return fmt.Errorf("registry %s: %w", apiRegistry.GetName(), err)
+	}
+	if outcome.changed && canRecordEvent {
+		eventRecorder.RecordEventNormal(apiRegistry,
+			"RegistryUpdated",
+			"Registry successfully updated")
+	}
+	if canFinalize && outcome.pending == 0 && !outcome.paused && ctx.Err() == nil {
+		// the registry has reached the expected state, the deleted
+		// resources have been removed from it
+		if err = releaseProjects(ctx, finalizers, apiRegistry, paused); err == nil &&
+			apiRegistry.GetDeletionTimestamp() != nil {
+			err = releaseRegistry(ctx, finalizers, apiRegistry)
+		}
+		if err != nil {
+			return fmt.Errorf("registry %s: %w", apiRegistry.GetName(), err)
 		}
 	}
-
 	return nil
 }
+
+// syncRegistries reconciles the registries concurrently, using at most
+// ResyncWorkers goroutines. A failing registry does not stop the
+// reconciliation of the others, the errors are returned in the order of the
+// registries.
+func syncRegistries(ctx context.Context, aop SyncableResources, expectedProvider *config.ExpectedProvider, apiRegistries []*api.Registry, dryRun bool) []error {
+	workers := ResyncWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	errs := make([]error, len(apiRegistries))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, apiRegistry := range apiRegistries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, apiRegistry *api.Registry) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = syncRegistry(ctx, aop, expectedProvider, apiRegistry, dryRun)
+		}(i, apiRegistry)
+	}
+	wg.Wait()
+	return errs
+}
+
+// FullResync performs a complete state synchronization over all provisioned
+// Registry resources. The registries are reconciled concurrently (see
+// ResyncWorkers) and a failing registry does not prevent the reconciliation of
+// the others. The GlobalHub registries are reconciled before the Local ones,
+// because the pull replication rules of the Local registries refer to the
+// projects of the GlobalHub registry.
+//
+// The returned error aggregates the errors of the failed registries.
+func FullResync(ctx context.Context, aop SyncableResources, dryRun bool) error {
+	expectedProvider := config.NewExpectedProvider(aop)
+	globalRegistries := []*api.Registry{}
+	localRegistries := []*api.Registry{}
+	for _, apiRegistry := range aop.GetRegistries(ctx) {
+		if apiRegistry.Spec.Role == "GlobalHub" {
+			globalRegistries = append(globalRegistries, apiRegistry)
+		} else {
+			localRegistries = append(localRegistries, apiRegistry)
+		}
+	}
+	errs := syncRegistries(ctx, aop, expectedProvider, globalRegistries, dryRun)
+	errs = append(errs, syncRegistries(ctx, aop, expectedProvider, localRegistries, dryRun)...)
+	return utilerrors.NewAggregate(errs)
+}
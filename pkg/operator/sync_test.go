@@ -0,0 +1,317 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/config/registry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry/reconciler"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const syncTestProvider = "sync-test"
+
+// syncRecorder records the order and the concurrency of the registry
+// reconciliations.
+type syncRecorder struct {
+	mu        sync.Mutex
+	running   int
+	maxActive int
+	started   map[string]time.Time
+	finished  map[string]time.Time
+}
+
+var recorder = &syncRecorder{}
+
+func (sr *syncRecorder) reset() {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.running = 0
+	sr.maxActive = 0
+	sr.started = map[string]time.Time{}
+	sr.finished = map[string]time.Time{}
+}
+
+// syncTestRegistry is a provider implementation which fails if the name of the
+// registry starts with "broken".
+type syncTestRegistry struct {
+	globalregistry.Registry
+}
+
+func (r *syncTestRegistry) ListProjects(context.Context) ([]globalregistry.Project, error) {
+	recorder.mu.Lock()
+	if _, ok := recorder.started[r.GetName()]; !ok {
+		recorder.started[r.GetName()] = time.Now()
+	}
+	recorder.running++
+	if recorder.running > recorder.maxActive {
+		recorder.maxActive = recorder.running
+	}
+	recorder.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	recorder.mu.Lock()
+	recorder.running--
+	recorder.finished[r.GetName()] = time.Now()
+	recorder.mu.Unlock()
+	if strings.HasPrefix(r.GetName(), "broken") {
+		return nil, globalregistry.WrapError(syncTestProvider, "list projects", 0,
+			globalregistry.WithClass(errors.New("connection refused"), globalregistry.ErrTransient))
+	}
+	return []globalregistry.Project{}, nil
+}
+
+func (r *syncTestRegistry) GetProjectByName(context.Context, string) (globalregistry.Project, error) {
+	if strings.HasPrefix(r.GetName(), "broken") {
+		return nil, globalregistry.WrapError(syncTestProvider, "get project", 0,
+			globalregistry.WithClass(errors.New("connection refused"), globalregistry.ErrTransient))
+	}
+	return nil, nil
+}
+
+func init() {
+	globalregistry.RegisterProviderImplementation(syncTestProvider,
+		func(_ logr.Logger, config globalregistry.Registry) (globalregistry.Registry, error) {
+			return &syncTestRegistry{config}, nil
+		},
+		globalregistry.ProviderCapabilities{
+			globalregistry.FeatureProjects:        {Read: true, Write: true},
+			globalregistry.FeatureProjectDeletion: {Write: true},
+		})
+}
+
+type syncTestResources struct {
+	registries []*api.Registry
+	projects   []*api.Project
+	scanners   []*api.Scanner
+	options    globalregistry.RegistryOptions
+
+	mu     sync.Mutex
+	events map[string][]string
+}
+
+var _ SyncableResources = &syncTestResources{}
+var _ EventRecorder = &syncTestResources{}
+
+func (sr *syncTestResources) GetProjects(context.Context) []*api.Project { return sr.projects }
+func (sr *syncTestResources) GetScanners(context.Context) []*api.Scanner { return sr.scanners }
+func (sr *syncTestResources) GetGlobalRegistryOptions() globalregistry.RegistryOptions {
+	return sr.options
+}
+func (sr *syncTestResources) GetLogger() logr.Logger { return logr.Discard() }
+func (sr *syncTestResources) GetRegistries(context.Context) []*api.Registry {
+	return sr.registries
+}
+func (sr *syncTestResources) WriteResource(context.Context, runtime.Object) error  { return nil }
+func (sr *syncTestResources) RemoveResource(context.Context, runtime.Object) error { return nil }
+
+func (sr *syncTestResources) recordEvent(obj runtime.Object, reason string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	name := obj.(metav1.Object).GetName()
+	sr.events[name] = append(sr.events[name], reason)
+}
+
+func (sr *syncTestResources) RecordEventNormal(obj runtime.Object, reason, _ string) {
+	sr.recordEvent(obj, reason)
+}
+
+func (sr *syncTestResources) RecordEventWarning(obj runtime.Object, reason, _ string) {
+	sr.recordEvent(obj, reason)
+}
+
+func newSyncTestRegistry(name, role string) *api.Registry {
+	return &api.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: &api.RegistrySpec{
+			Provider:    syncTestProvider,
+			APIEndpoint: fmt.Sprintf("https://%s.example.com", name),
+			Username:    "admin",
+			Password:    "admin",
+			Role:        role,
+		},
+	}
+}
+
+func TestFullResync(t *testing.T) {
+	defaultWorkers := ResyncWorkers
+	defer func() {
+		ResyncWorkers = defaultWorkers
+	}()
+	ResyncWorkers = 3
+	recorder.reset()
+	resources := &syncTestResources{
+		registries: []*api.Registry{
+			newSyncTestRegistry("local-1", "Local"),
+			newSyncTestRegistry("broken-local", "Local"),
+			newSyncTestRegistry("global", "GlobalHub"),
+			newSyncTestRegistry("local-2", "Local"),
+			newSyncTestRegistry("local-3", "Local"),
+			newSyncTestRegistry("local-4", "Local"),
+			newSyncTestRegistry("broken-local-2", "Local"),
+		},
+		events: map[string][]string{},
+	}
+	err := FullResync(context.Background(), resources, false)
+	if err == nil {
+		t.Fatal("the errors of the broken registries are not returned")
+	}
+	if !errors.Is(err, globalregistry.ErrTransient) {
+		t.Errorf("the error classes are not preserved: %v", err)
+	}
+	for _, name := range []string{"broken-local", "broken-local-2"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("the error of registry %s is missing: %v", name, err)
+		}
+	}
+	for _, reg := range resources.registries {
+		if _, ok := recorder.finished[reg.GetName()]; !ok {
+			t.Errorf("registry %s is not reconciled", reg.GetName())
+		}
+	}
+	for _, reg := range resources.registries {
+		if reg.GetName() != "global" &&
+			recorder.started[reg.GetName()].Before(recorder.finished["global"]) {
+			t.Errorf("registry %s is reconciled before the global registry", reg.GetName())
+		}
+	}
+	if recorder.maxActive < 2 || recorder.maxActive > ResyncWorkers {
+		t.Errorf("%d registries were reconciled concurrently, expected 2..%d",
+			recorder.maxActive, ResyncWorkers)
+	}
+	for _, name := range []string{"broken-local", "broken-local-2"} {
+		if events := resources.events[name]; len(events) != 1 || events[0] != "RegistryUpdateFailed" {
+			t.Errorf("unexpected events of registry %s: %v", name, events)
+		}
+	}
+}
+
+// ownershipTestResources keeps the written ConfigMaps in memory.
+type ownershipTestResources struct {
+	*syncTestResources
+
+	configMaps map[string]map[string]string
+}
+
+var _ reconciler.OwnershipReader = &ownershipTestResources{}
+
+func (or *ownershipTestResources) WriteResource(_ context.Context, obj runtime.Object) error {
+	configMap := obj.(*corev1.ConfigMap)
+	or.configMaps[configMap.GetName()] = configMap.Data
+	return nil
+}
+
+func (or *ownershipTestResources) GetConfigMapData(_ context.Context, name string) (map[string]string, error) {
+	data, ok := or.configMaps[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+	return data, nil
+}
+
+func TestSeedOwnership(t *testing.T) {
+	harbor := newSyncTestRegistry("harbor", "Local")
+	deleting := newFinalizerTestProject("deleting", "harbor")
+	deleted(deleting, false)
+	newResources := func() *ownershipTestResources {
+		return &ownershipTestResources{
+			syncTestResources: &syncTestResources{
+				registries: []*api.Registry{harbor, newSyncTestRegistry("other", "Local")},
+				projects: []*api.Project{
+					newFinalizerTestProject("declared", "harbor"),
+					deleting,
+					newFinalizerTestProject("elsewhere", "other"),
+				},
+			},
+			configMaps: map[string]map[string]string{},
+		}
+	}
+	// the projects of harbor before the upgrade
+	actual := &api.RegistryStatus{
+		Projects: []api.ProjectStatus{
+			{Name: "declared"},
+			{Name: "deleting"},
+			{Name: "elsewhere"},
+			{Name: "manual"},
+		},
+	}
+	ctx := context.Background()
+
+	resources := newResources()
+	ownership, err := ownershipOf(ctx, resources, registry.New(harbor, resources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ownership.NeedsSeeding() {
+		t.Fatal("the missing ownership record is not detected")
+	}
+	if err = seedOwnership(ctx, resources, ownership, "harbor", actual, false); err != nil {
+		t.Fatal(err)
+	}
+	if record := resources.configMaps["harbor---ownership"]; record["projects"] != "declared\ndeleting" {
+		t.Errorf("unexpected ownership record: %v", record)
+	}
+	for project, removable := range map[string]bool{
+		"declared":  true,
+		"deleting":  true,
+		"elsewhere": false,
+		"manual":    false,
+	} {
+		if ownership.CanRemoveProject(project) != removable {
+			t.Errorf("project %s can be removed: %t, expected %t", project, !removable, removable)
+		}
+	}
+	ownership, err = ownershipOf(ctx, resources, registry.New(harbor, resources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ownership.NeedsSeeding() || !ownership.CanRemoveProject("declared") {
+		t.Error("the seeded ownership record is not read back")
+	}
+
+	resources = newResources()
+	ownership, err = ownershipOf(ctx, resources, registry.New(harbor, resources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = seedOwnership(ctx, resources, ownership, "harbor", actual, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(resources.configMaps) != 0 {
+		t.Errorf("the ownership record is written in dry-run mode: %v", resources.configMaps)
+	}
+	if !ownership.CanRemoveProject("declared") {
+		t.Error("the ownership record is not seeded in dry-run mode")
+	}
+}
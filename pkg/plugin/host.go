@@ -0,0 +1,290 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+var logger logr.Logger = logr.Discard()
+
+// SetLogger sets the logger of the package.
+func SetLogger(l logr.Logger) {
+	logger = l.WithName("plugin")
+}
+
+// process is a running plugin executable.
+type process struct {
+	path string
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	encoder   *json.Encoder
+	decoder   *json.Decoder
+	lastID    uint64
+	handshake HandshakeResult
+}
+
+var (
+	processesMu sync.Mutex
+	processes   []*process
+)
+
+// start starts the plugin executable and performs the handshake. The caller
+// must hold the mutex of the process.
+func (p *process) start() error {
+	cmd := exec.Command(p.path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("cannot start plugin %s: %w", p.path, err)
+	}
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Info(scanner.Text(), "plugin", filepath.Base(p.path))
+		}
+	}()
+	p.cmd = cmd
+	p.stdin = stdin
+	p.encoder = json.NewEncoder(stdin)
+	p.decoder = json.NewDecoder(stdout)
+
+	handshake := HandshakeResult{}
+	if err = p.roundTrip(MethodHandshake, nil, nil, &handshake); err != nil {
+		p.stop()
+		return fmt.Errorf("handshake with plugin %s failed: %w", p.path, err)
+	}
+	if handshake.ProtocolVersion != ProtocolVersion {
+		p.stop()
+		return fmt.Errorf("plugin %s uses protocol version %d, expected %d",
+			p.path, handshake.ProtocolVersion, ProtocolVersion)
+	}
+	if p.handshake.Provider != "" && p.handshake.Provider != handshake.Provider {
+		p.stop()
+		return fmt.Errorf("plugin %s changed its provider name from %s to %s",
+			p.path, p.handshake.Provider, handshake.Provider)
+	}
+	p.handshake = handshake
+	return nil
+}
+
+// stop terminates the plugin executable. The caller must hold the mutex of the
+// process.
+func (p *process) stop() {
+	if p.cmd == nil {
+		return
+	}
+	// closing the standard input asks the plugin to exit
+	p.stdin.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	p.cmd.Wait()
+	p.cmd = nil
+}
+
+// roundTrip sends a request and waits for the response. The caller must hold
+// the mutex of the process.
+func (p *process) roundTrip(method string, reg *RegistryConfig, params interface{}, result interface{}) error {
+	p.lastID++
+	req := &Request{
+		ID:       p.lastID,
+		Method:   method,
+		Registry: reg,
+	}
+	if params != nil {
+		rawParams, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = rawParams
+	}
+	if err := p.encoder.Encode(req); err != nil {
+		return fmt.Errorf("cannot send request to plugin: %w", err)
+	}
+	resp := &Response{}
+	if err := p.decoder.Decode(resp); err != nil {
+		return fmt.Errorf("cannot read response of plugin: %w", err)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("plugin responded to request %d instead of %d", resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		return resp.Error.toError(p.handshake.Provider, method)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// call invokes a method of the plugin. The plugin is (re)started if it is not
+// running. If the context is cancelled before the response arrives, the plugin
+// is stopped, so that the next call does not read the stale response.
+func (p *process) call(ctx context.Context, method string, reg *RegistryConfig, params interface{}, result interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == nil {
+		if err := p.start(); err != nil {
+			return globalregistry.WrapError(p.handshake.Provider, method, 0,
+				globalregistry.WithClass(err, globalregistry.ErrTransient))
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- p.roundTrip(method, reg, params, result)
+	}()
+	select {
+	case err := <-done:
+		var providerErr *globalregistry.ProviderError
+		if err != nil && !errors.As(err, &providerErr) {
+			// protocol failure, the plugin must be restarted
+			p.stop()
+			return globalregistry.WrapError(p.handshake.Provider, method, 0,
+				globalregistry.WithClass(err, globalregistry.ErrTransient))
+		}
+		return err
+	case <-ctx.Done():
+		p.stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// DefaultDirectory returns the default plugin directory, i.e.
+// $HOME/.registryman/plugins.
+func DefaultDirectory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".registryman", "plugins")
+}
+
+// Discover starts the plugin executables found in the directory and registers
+// the providers they implement. Executables whose name does not start with
+// ExecutablePrefix are ignored. If the directory does not exist, no plugin is
+// registered and no error is returned. Failing plugins are logged and skipped;
+// the returned slice contains the names of the registered providers.
+func Discover(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	providers := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), ExecutablePrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+		provider, err := Load(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			logger.Error(err, "cannot load plugin", "path", filepath.Join(dir, entry.Name()))
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers, nil
+}
+
+// Load starts the plugin executable and registers the provider it implements.
+// The name of the provider is returned.
+func Load(path string) (string, error) {
+	p := &process{
+		path: path,
+	}
+	p.mu.Lock()
+	err := p.start()
+	p.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	provider := p.handshake.Provider
+	if provider == "" {
+		p.stop()
+		return "", fmt.Errorf("plugin %s did not report its provider name", path)
+	}
+	for _, registered := range globalregistry.RegisteredProviders() {
+		if registered == provider {
+			p.stop()
+			return "", fmt.Errorf("provider %s of plugin %s is already registered", provider, path)
+		}
+	}
+	processesMu.Lock()
+	processes = append(processes, p)
+	processesMu.Unlock()
+	globalregistry.RegisterProviderImplementation(
+		provider,
+		func(logger logr.Logger, config globalregistry.Registry) (globalregistry.Registry, error) {
+			reg, err := newRegistry(p, config)
+			if err != nil {
+				return nil, err
+			}
+			return reg, nil
+		},
+		p.handshake.Capabilities,
+	)
+	logger.V(1).Info("plugin loaded",
+		"provider", provider,
+		"path", path,
+	)
+	return provider, nil
+}
+
+// Shutdown stops the running plugin executables.
+func Shutdown() {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	for _, p := range processes {
+		p.mu.Lock()
+		p.stop()
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,239 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+var pluginDir string
+
+// TestMain builds the example memory plugin into a temporary plugin directory.
+func TestMain(m *testing.M) {
+	var err error
+	pluginDir, err = os.MkdirTemp("", "registryman-plugins")
+	if err != nil {
+		panic(err)
+	}
+	cmd := exec.Command("go", "build",
+		"-o", filepath.Join(pluginDir, ExecutablePrefix+"memory"),
+		"../../examples/plugins/memory")
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		panic(fmt.Sprintf("cannot build the example plugin: %v", err))
+	}
+	// files without the prefix must be ignored
+	if err = os.WriteFile(filepath.Join(pluginDir, "README"), []byte("plugins"), 0644); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	Shutdown()
+	os.RemoveAll(pluginDir)
+	os.Exit(code)
+}
+
+func loadMemoryPlugin(t *testing.T) {
+	t.Helper()
+	for _, provider := range globalregistry.RegisteredProviders() {
+		if provider == "memory" {
+			return
+		}
+	}
+	providers, err := Discover(pluginDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 1 || providers[0] != "memory" {
+		t.Fatalf("unexpected providers: %v", providers)
+	}
+}
+
+func newMemoryRegistry(t *testing.T, name string) globalregistry.Registry {
+	t.Helper()
+	loadMemoryPlugin(t)
+	reg, err := globalregistry.New(logr.Discard(), &RegistryConfig{
+		Name:        name,
+		Provider:    "memory",
+		APIEndpoint: "https://" + name + ".example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestDiscoverMissingDirectory(t *testing.T) {
+	providers, err := Discover(filepath.Join(pluginDir, "missing"))
+	if err != nil || len(providers) != 0 {
+		t.Errorf("unexpected result: %v, %v", providers, err)
+	}
+}
+
+func TestPluginCapabilities(t *testing.T) {
+	loadMemoryPlugin(t)
+	capabilities, err := globalregistry.GetProviderCapabilities("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !capabilities.CanWrite(globalregistry.FeatureProjectMembers, "") {
+		t.Errorf("capabilities are not transferred: %v", capabilities)
+	}
+	if !capabilities.CanPull() || !capabilities.CanPush() {
+		t.Errorf("replication capabilities are not transferred: %v", capabilities)
+	}
+}
+
+func TestPluginProjects(t *testing.T) {
+	ctx := context.Background()
+	reg := newMemoryRegistry(t, "projects")
+	_, err := reg.(globalregistry.ProjectCreator).CreateProject(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = reg.(globalregistry.ProjectCreator).CreateProject(ctx, "app")
+	if !errors.Is(err, globalregistry.ErrAlreadyExists) || !errors.Is(err, globalregistry.ErrConflict) {
+		t.Errorf("error class is not transferred: %v", err)
+	}
+	var providerErr *globalregistry.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Provider != "memory" || providerErr.Action != MethodCreateProject {
+		t.Errorf("provider error is not returned: %v", err)
+	}
+
+	project, err := reg.(globalregistry.RegistryWithProjects).GetProjectByName(ctx, "app")
+	if err != nil || project == nil {
+		t.Fatalf("project is not found: %v", err)
+	}
+	missing, err := reg.(globalregistry.RegistryWithProjects).GetProjectByName(ctx, "missing")
+	if err != nil || missing != nil {
+		t.Errorf("unexpected result for missing project: %v, %v", missing, err)
+	}
+
+	if err = project.(globalregistry.DestructibleProject).Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	projects, err := reg.(globalregistry.RegistryWithProjects).ListProjects(ctx)
+	if err != nil || len(projects) != 0 {
+		t.Errorf("project is not deleted: %v, %v", projects, err)
+	}
+	err = project.(globalregistry.DestructibleProject).Delete(ctx)
+	if !errors.Is(err, globalregistry.ErrNotFound) {
+		t.Errorf("deleting a missing project: %v", err)
+	}
+}
+
+type testMember struct {
+	name, memberType, role, dn string
+}
+
+func (m *testMember) GetName() string { return m.name }
+func (m *testMember) GetType() string { return m.memberType }
+func (m *testMember) GetRole() string { return m.role }
+func (m *testMember) GetDN() string   { return m.dn }
+
+func TestPluginMembers(t *testing.T) {
+	ctx := context.Background()
+	reg := newMemoryRegistry(t, "members")
+	project, err := reg.(globalregistry.ProjectCreator).CreateProject(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	memberManipulator := project.(globalregistry.MemberManipulatorProject)
+	creds, err := memberManipulator.AssignMember(ctx, &testMember{name: "ci", memberType: "Robot", role: "PushOnly"})
+	if err != nil || creds == nil || creds.Password == "" {
+		t.Errorf("robot credentials are not returned: %v, %v", creds, err)
+	}
+	if _, err = memberManipulator.AssignMember(ctx, &testMember{name: "devs", memberType: "Group", role: "Developer", dn: "cn=devs"}); err != nil {
+		t.Fatal(err)
+	}
+	members, err := project.(globalregistry.ProjectWithMembers).GetMembers(ctx)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("unexpected members: %v, %v", members, err)
+	}
+	ldapMember, ok := members[1].(globalregistry.LdapMember)
+	if !ok || ldapMember.GetDN() != "cn=devs" || ldapMember.GetRole() != "Developer" {
+		t.Errorf("LDAP member is not transferred: %+v", members[1])
+	}
+	if err = memberManipulator.UnassignMember(ctx, members[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = memberManipulator.UnassignMember(ctx, members[0]); !errors.Is(err, globalregistry.ErrNotFound) {
+		t.Errorf("unassigning a missing member: %v", err)
+	}
+}
+
+func TestPluginReplicationRules(t *testing.T) {
+	ctx := context.Background()
+	reg := newMemoryRegistry(t, "local")
+	remote := newMemoryRegistry(t, "global")
+	project, err := reg.(globalregistry.ProjectCreator).CreateProject(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule, err := project.(globalregistry.ReplicationRuleManipulatorProject).AssignReplicationRule(ctx, remote, api.ReplicationTrigger{Type: api.EventBasedReplicationTriggerType}, "Pull")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.RemoteRegistry().GetName() != "global" || rule.Direction() != "Pull" ||
+		rule.Trigger().TriggerType() != api.EventBasedReplicationTriggerType {
+		t.Errorf("unexpected replication rule: %+v", rule)
+	}
+	rule, err = project.(globalregistry.ReplicationRuleManipulatorProject).UpdateReplicationRule(ctx, remote, api.ReplicationTrigger{Type: api.CronReplicationTriggerType, Schedule: "0 * * * *"}, "Pull")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Trigger().TriggerType() != api.CronReplicationTriggerType || rule.Trigger().TriggerSchedule() != "0 * * * *" {
+		t.Errorf("replication rule is not updated: %+v", rule)
+	}
+	_, err = project.(globalregistry.ReplicationRuleManipulatorProject).UpdateReplicationRule(ctx, remote, api.ReplicationTrigger{Type: api.ManualReplicationTriggerType}, "Push")
+	if !errors.Is(err, globalregistry.ErrNotFound) {
+		t.Errorf("updating a missing replication rule: %v", err)
+	}
+	rules, err := project.(globalregistry.ProjectWithReplication).GetReplicationRules(ctx, nil, "Push")
+	if err != nil || len(rules) != 0 {
+		t.Errorf("direction filter is not applied: %v, %v", rules, err)
+	}
+	rules, err = project.(globalregistry.ProjectWithReplication).GetReplicationRules(ctx, nil, "")
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("unexpected replication rules: %v, %v", rules, err)
+	}
+	if err = rules[0].(globalregistry.DestructibleReplicationRule).Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rules, err = project.(globalregistry.ProjectWithReplication).GetReplicationRules(ctx, nil, "")
+	if err != nil || len(rules) != 0 {
+		t.Errorf("replication rule is not deleted: %v, %v", rules, err)
+	}
+}
+
+func TestPluginRestart(t *testing.T) {
+	ctx := context.Background()
+	reg := newMemoryRegistry(t, "restart")
+	Shutdown()
+	if _, err := reg.(globalregistry.RegistryWithProjects).ListProjects(ctx); err != nil {
+		t.Errorf("plugin is not restarted: %v", err)
+	}
+}
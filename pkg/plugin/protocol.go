@@ -0,0 +1,268 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// plugin package implements out-of-process registry provider plugins.
+//
+// A plugin is an executable named registryman-provider-<name> placed in the
+// plugin directory. Registryman starts the plugin once and communicates with it
+// using newline delimited JSON messages: the requests are written to the
+// standard input of the plugin, the responses are read from its standard
+// output. The standard error of the plugin is forwarded to the log.
+//
+// The first request is always a handshake. The plugin answers with the
+// protocol version, the name of the provider and its capability descriptor.
+// Afterwards, every request carries the configuration of the registry it
+// concerns, so the plugin can remain stateless.
+//
+// Plugin authors shall use the Serve function of this package, which accepts
+// the same globalregistry.RegistryCreator as the in-tree providers.
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// ProtocolVersion is the version of the plugin protocol implemented by this
+// package.
+const ProtocolVersion = 1
+
+// ExecutablePrefix is the file name prefix of the plugin executables.
+const ExecutablePrefix = "registryman-provider-"
+
+// The methods of the plugin protocol.
+const (
+	MethodHandshake             = "handshake"
+	MethodListProjects          = "listProjects"
+	MethodCreateProject         = "createProject"
+	MethodDeleteProject         = "deleteProject"
+	MethodGetRepositories       = "getRepositories"
+	MethodGetMembers            = "getMembers"
+	MethodAssignMember          = "assignMember"
+	MethodUnassignMember        = "unassignMember"
+	MethodGetScanner            = "getScanner"
+	MethodAssignScanner         = "assignScanner"
+	MethodUnassignScanner       = "unassignScanner"
+	MethodGetReplicationRules   = "getReplicationRules"
+	MethodAssignReplicationRule = "assignReplicationRule"
+	MethodUpdateReplicationRule = "updateReplicationRule"
+	MethodDeleteReplicationRule = "deleteReplicationRule"
+	MethodGetUsedStorage        = "getUsedStorage"
+)
+
+// Request is the message sent by registryman to the plugin.
+type Request struct {
+	ID       uint64          `json:"id"`
+	Method   string          `json:"method"`
+	Registry *RegistryConfig `json:"registry,omitempty"`
+	Params   json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the message sent by the plugin to registryman.
+type Response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// HandshakeResult is the result of the handshake method.
+type HandshakeResult struct {
+	ProtocolVersion int                                 `json:"protocolVersion"`
+	Provider        string                              `json:"provider"`
+	Capabilities    globalregistry.ProviderCapabilities `json:"capabilities"`
+}
+
+// RegistryConfig is the registry configuration sent with the requests. It
+// implements the globalregistry.Registry and globalregistry.RegistryWithTLS
+// interfaces, so it can be passed to the RegistryCreator of the plugin.
+type RegistryConfig struct {
+	Name                  string            `json:"name"`
+	Provider              string            `json:"provider"`
+	APIEndpoint           string            `json:"apiEndpoint"`
+	Username              string            `json:"username,omitempty"`
+	Password              string            `json:"password,omitempty"`
+	InsecureSkipTLSVerify bool              `json:"insecureSkipTlsVerify,omitempty"`
+	ForceDeleteProjects   bool              `json:"forceDeleteProjects,omitempty"`
+	Annotations           map[string]string `json:"annotations,omitempty"`
+	CABundle              []byte            `json:"caBundle,omitempty"`
+	ClientCertificate     []byte            `json:"clientCertificate,omitempty"`
+	ClientKey             []byte            `json:"clientKey,omitempty"`
+}
+
+var _ globalregistry.Registry = &RegistryConfig{}
+var _ globalregistry.RegistryWithTLS = &RegistryConfig{}
+
+func (rc *RegistryConfig) GetProvider() string               { return rc.Provider }
+func (rc *RegistryConfig) GetUsername() string               { return rc.Username }
+func (rc *RegistryConfig) GetPassword() string               { return rc.Password }
+func (rc *RegistryConfig) GetAPIEndpoint() string            { return rc.APIEndpoint }
+func (rc *RegistryConfig) GetName() string                   { return rc.Name }
+func (rc *RegistryConfig) GetAnnotations() map[string]string { return rc.Annotations }
+func (rc *RegistryConfig) GetInsecureSkipTLSVerify() bool    { return rc.InsecureSkipTLSVerify }
+
+// GetOptions implements the globalregistry.Registry interface. The returned
+// value implements the globalregistry.CanForceDelete interface.
+func (rc *RegistryConfig) GetOptions() globalregistry.RegistryOptions {
+	return registryOptions{forceDelete: rc.ForceDeleteProjects}
+}
+
+// GetCABundle implements the globalregistry.RegistryWithTLS interface.
+func (rc *RegistryConfig) GetCABundle() ([]byte, error) {
+	return rc.CABundle, nil
+}
+
+// GetClientCertificate implements the globalregistry.RegistryWithTLS
+// interface.
+func (rc *RegistryConfig) GetClientCertificate() ([]byte, []byte, error) {
+	return rc.ClientCertificate, rc.ClientKey, nil
+}
+
+type registryOptions struct {
+	forceDelete bool
+}
+
+var _ globalregistry.CanForceDelete = registryOptions{}
+
+func (ro registryOptions) ForceDeleteProjects() bool {
+	return ro.forceDelete
+}
+
+// ProjectParams is the parameter of the project related methods.
+type ProjectParams struct {
+	Project string `json:"project"`
+}
+
+// Member is the wire representation of a globalregistry.ProjectMember.
+type Member struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Role string `json:"role"`
+	DN   string `json:"dn,omitempty"`
+}
+
+// MemberParams is the parameter of the assignMember and unassignMember
+// methods.
+type MemberParams struct {
+	Project string `json:"project"`
+	Member  Member `json:"member"`
+}
+
+// Scanner is the wire representation of a globalregistry.Scanner.
+type Scanner struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// ScannerParams is the parameter of the assignScanner and unassignScanner
+// methods.
+type ScannerParams struct {
+	Project string  `json:"project"`
+	Scanner Scanner `json:"scanner"`
+}
+
+// RemoteRegistry identifies the remote registry of a replication rule.
+type RemoteRegistry struct {
+	Name        string `json:"name"`
+	Provider    string `json:"provider"`
+	APIEndpoint string `json:"apiEndpoint"`
+}
+
+// ReplicationRule is the wire representation of a
+// globalregistry.ReplicationRule.
+type ReplicationRule struct {
+	Name            string                     `json:"name"`
+	Project         string                     `json:"project"`
+	RemoteRegistry  RemoteRegistry             `json:"remoteRegistry"`
+	TriggerType     api.ReplicationTriggerType `json:"triggerType"`
+	TriggerSchedule string                     `json:"triggerSchedule,omitempty"`
+	Direction       string                     `json:"direction"`
+}
+
+// AssignReplicationRuleParams is the parameter of the assignReplicationRule and
+// the updateReplicationRule methods. The complete configuration of the remote registry is sent, since the
+// plugin may need its credentials.
+type AssignReplicationRuleParams struct {
+	Project         string                     `json:"project"`
+	Remote          *RegistryConfig            `json:"remote"`
+	TriggerType     api.ReplicationTriggerType `json:"triggerType"`
+	TriggerSchedule string                     `json:"triggerSchedule,omitempty"`
+	Direction       string                     `json:"direction"`
+}
+
+// DeleteReplicationRuleParams is the parameter of the deleteReplicationRule
+// method.
+type DeleteReplicationRuleParams struct {
+	Project string          `json:"project"`
+	Rule    ReplicationRule `json:"rule"`
+}
+
+// Error is the wire representation of an error. The class identifies the
+// globalregistry error value wrapped by the error.
+type Error struct {
+	Class      string `json:"class,omitempty"`
+	Message    string `json:"message"`
+	StatusCode int    `json:"statusCode,omitempty"`
+}
+
+// errorClasses contains the globalregistry error values that are transferred
+// via the protocol. The order matters, the more specific values come first.
+var errorClasses = []struct {
+	name string
+	err  error
+}{
+	{"alreadyExists", globalregistry.ErrAlreadyExists},
+	{"conflict", globalregistry.ErrConflict},
+	{"notFound", globalregistry.ErrNotFound},
+	{"unauthorized", globalregistry.ErrUnauthorized},
+	{"forbidden", globalregistry.ErrForbidden},
+	{"rateLimited", globalregistry.ErrRateLimited},
+	{"transient", globalregistry.ErrTransient},
+	{"validation", globalregistry.ErrValidation},
+	{"notImplemented", globalregistry.ErrNotImplemented},
+	{"unsupportedVersion", globalregistry.ErrUnsupportedVersion},
+	{"recoverable", globalregistry.ErrRecoverableError},
+}
+
+// newError converts err to its wire representation.
+func newError(err error) *Error {
+	wireErr := &Error{
+		Message:    err.Error(),
+		StatusCode: globalregistry.StatusCodeOf(err),
+	}
+	for _, class := range errorClasses {
+		if errors.Is(err, class.err) {
+			wireErr.Class = class.name
+			break
+		}
+	}
+	return wireErr
+}
+
+// toError converts the wire representation of an error to a
+// *globalregistry.ProviderError.
+func (e *Error) toError(provider, method string) error {
+	err := errors.New(e.Message)
+	for _, class := range errorClasses {
+		if e.Class == class.name {
+			err = globalregistry.WithClass(err, class.err)
+			break
+		}
+	}
+	return globalregistry.WrapError(provider, method, e.StatusCode, err)
+}
@@ -0,0 +1,436 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// registry is the globalregistry.Registry implementation of the plugin based
+// providers. It forwards the calls to the plugin process.
+type registry struct {
+	globalregistry.Registry
+	process *process
+	config  *RegistryConfig
+}
+
+var _ globalregistry.Registry = &registry{}
+var _ globalregistry.RegistryWithProjects = &registry{}
+var _ globalregistry.ProjectCreator = &registry{}
+var _ globalregistry.RegistryWithCapabilities = &registry{}
+
+// newRegistryConfig returns the wire representation of the registry
+// configuration.
+func newRegistryConfig(config globalregistry.Registry) (*RegistryConfig, error) {
+	rc := &RegistryConfig{
+		Name:                  config.GetName(),
+		Provider:              config.GetProvider(),
+		APIEndpoint:           config.GetAPIEndpoint(),
+		Username:              config.GetUsername(),
+		Password:              config.GetPassword(),
+		InsecureSkipTLSVerify: config.GetInsecureSkipTLSVerify(),
+		Annotations:           config.GetAnnotations(),
+	}
+	if opt, ok := config.GetOptions().(globalregistry.CanForceDelete); ok {
+		rc.ForceDeleteProjects = opt.ForceDeleteProjects()
+	}
+	if regWithTLS, ok := config.(globalregistry.RegistryWithTLS); ok {
+		var err error
+		rc.CABundle, err = regWithTLS.GetCABundle()
+		if err != nil {
+			return nil, err
+		}
+		rc.ClientCertificate, rc.ClientKey, err = regWithTLS.GetClientCertificate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func newRegistry(p *process, config globalregistry.Registry) (*registry, error) {
+	rc, err := newRegistryConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &registry{
+		Registry: config,
+		process:  p,
+		config:   rc,
+	}, nil
+}
+
+func (r *registry) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	return r.process.call(ctx, method, r.config, params, result)
+}
+
+// GetCapabilities implements the globalregistry.RegistryWithCapabilities
+// interface.
+func (r *registry) GetCapabilities() globalregistry.ProviderCapabilities {
+	return r.process.handshake.Capabilities
+}
+
+func (r *registry) canRead(feature globalregistry.Feature) bool {
+	return r.GetCapabilities().CanRead(feature, "")
+}
+
+func (r *registry) canWrite(feature globalregistry.Feature) bool {
+	return r.GetCapabilities().CanWrite(feature, "")
+}
+
+// notSupported returns the error of the operations that the plugin does not
+// support according to its capability descriptor.
+func (r *registry) notSupported(action string) error {
+	return globalregistry.WrapError(r.GetProvider(), action, 0, globalregistry.ErrNotImplemented)
+}
+
+func (r *registry) ListProjects(ctx context.Context) ([]globalregistry.Project, error) {
+	names := []string{}
+	if err := r.call(ctx, MethodListProjects, nil, &names); err != nil {
+		return nil, err
+	}
+	projects := make([]globalregistry.Project, len(names))
+	for i, name := range names {
+		projects[i] = &project{
+			name:     name,
+			registry: r,
+		}
+	}
+	return projects, nil
+}
+
+func (r *registry) GetProjectByName(ctx context.Context, name string) (globalregistry.Project, error) {
+	if name == "" {
+		return &project{
+			registry: r,
+		}, nil
+	}
+	projects, err := r.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		if project.GetName() == name {
+			return project, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *registry) CreateProject(ctx context.Context, name string) (globalregistry.Project, error) {
+	if !r.canWrite(globalregistry.FeatureProjects) {
+		return nil, r.notSupported(MethodCreateProject)
+	}
+	if err := r.call(ctx, MethodCreateProject, &ProjectParams{Project: name}, nil); err != nil {
+		return nil, err
+	}
+	return &project{
+		name:     name,
+		registry: r,
+	}, nil
+}
+
+// project is the globalregistry.Project implementation of the plugin based
+// providers. The capability descriptor of the plugin is checked before the
+// calls are forwarded: the unsupported read operations return empty results,
+// the unsupported write operations return ErrNotImplemented.
+type project struct {
+	name     string
+	registry *registry
+}
+
+var _ globalregistry.Project = &project{}
+var _ globalregistry.ProjectWithRepositories = &project{}
+var _ globalregistry.DestructibleProject = &project{}
+var _ globalregistry.ProjectWithMembers = &project{}
+var _ globalregistry.MemberManipulatorProject = &project{}
+var _ globalregistry.ProjectWithScanner = &project{}
+var _ globalregistry.ScannerManipulatorProject = &project{}
+var _ globalregistry.ProjectWithReplication = &project{}
+var _ globalregistry.ReplicationRuleManipulatorProject = &project{}
+var _ globalregistry.ProjectWithStorage = &project{}
+
+func (p *project) GetName() string {
+	return p.name
+}
+
+func (p *project) params() *ProjectParams {
+	return &ProjectParams{Project: p.name}
+}
+
+func (p *project) GetRepositories(ctx context.Context) ([]string, error) {
+	repositories := []string{}
+	err := p.registry.call(ctx, MethodGetRepositories, p.params(), &repositories)
+	return repositories, err
+}
+
+func (p *project) Delete(ctx context.Context) error {
+	if !p.registry.canWrite(globalregistry.FeatureProjectDeletion) {
+		return p.registry.notSupported(MethodDeleteProject)
+	}
+	return p.registry.call(ctx, MethodDeleteProject, p.params(), nil)
+}
+
+func (p *project) GetMembers(ctx context.Context) ([]globalregistry.ProjectMember, error) {
+	if !p.registry.canRead(globalregistry.FeatureProjectMembers) {
+		return []globalregistry.ProjectMember{}, nil
+	}
+	wireMembers := []Member{}
+	if err := p.registry.call(ctx, MethodGetMembers, p.params(), &wireMembers); err != nil {
+		return nil, err
+	}
+	members := make([]globalregistry.ProjectMember, len(wireMembers))
+	for i := range wireMembers {
+		members[i] = newProjectMember(wireMembers[i])
+	}
+	return members, nil
+}
+
+func (p *project) AssignMember(ctx context.Context, member globalregistry.ProjectMember) (*globalregistry.ProjectMemberCredentials, error) {
+	if !p.registry.canWrite(globalregistry.FeatureProjectMembers) {
+		return nil, p.registry.notSupported(MethodAssignMember)
+	}
+	var creds *globalregistry.ProjectMemberCredentials
+	err := p.registry.call(ctx, MethodAssignMember, &MemberParams{
+		Project: p.name,
+		Member:  toMember(member),
+	}, &creds)
+	return creds, err
+}
+
+func (p *project) UnassignMember(ctx context.Context, member globalregistry.ProjectMember) error {
+	if !p.registry.canWrite(globalregistry.FeatureProjectMembers) {
+		return p.registry.notSupported(MethodUnassignMember)
+	}
+	return p.registry.call(ctx, MethodUnassignMember, &MemberParams{
+		Project: p.name,
+		Member:  toMember(member),
+	}, nil)
+}
+
+func (p *project) GetScanner(ctx context.Context) (globalregistry.Scanner, error) {
+	if !p.registry.canRead(globalregistry.FeatureProjectScanners) {
+		return nil, nil
+	}
+	var s *Scanner
+	if err := p.registry.call(ctx, MethodGetScanner, p.params(), &s); err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, nil
+	}
+	return s, nil
+}
+
+func (p *project) AssignScanner(ctx context.Context, s globalregistry.Scanner) error {
+	if !p.registry.canWrite(globalregistry.FeatureProjectScanners) {
+		return p.registry.notSupported(MethodAssignScanner)
+	}
+	return p.registry.call(ctx, MethodAssignScanner, &ScannerParams{
+		Project: p.name,
+		Scanner: Scanner{Name: s.GetName(), URL: s.GetURL()},
+	}, nil)
+}
+
+func (p *project) UnassignScanner(ctx context.Context, s globalregistry.Scanner) error {
+	if !p.registry.canWrite(globalregistry.FeatureProjectScanners) {
+		return p.registry.notSupported(MethodUnassignScanner)
+	}
+	return p.registry.call(ctx, MethodUnassignScanner, &ScannerParams{
+		Project: p.name,
+		Scanner: Scanner{Name: s.GetName(), URL: s.GetURL()},
+	}, nil)
+}
+
+func (p *project) GetReplicationRules(ctx context.Context, trigger globalregistry.ReplicationTrigger, direction string) ([]globalregistry.ReplicationRule, error) {
+	if !p.registry.canRead(globalregistry.FeatureProjectReplicationRules) {
+		return []globalregistry.ReplicationRule{}, nil
+	}
+	wireRules := []ReplicationRule{}
+	if err := p.registry.call(ctx, MethodGetReplicationRules, p.params(), &wireRules); err != nil {
+		return nil, err
+	}
+	rules := make([]globalregistry.ReplicationRule, 0, len(wireRules))
+	for i := range wireRules {
+		rule := &replicationRule{
+			ReplicationRule: wireRules[i],
+			project:         p,
+		}
+		if trigger != nil && (rule.TriggerType != trigger.TriggerType() ||
+			rule.TriggerSchedule != trigger.TriggerSchedule()) {
+			continue
+		}
+		if direction != "" && rule.Direction() != direction {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (p *project) AssignReplicationRule(ctx context.Context, remote globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	return p.callReplicationRule(ctx, MethodAssignReplicationRule, remote, trigger, direction)
+}
+
+func (p *project) UpdateReplicationRule(ctx context.Context, remote globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	return p.callReplicationRule(ctx, MethodUpdateReplicationRule, remote, trigger, direction)
+}
+
+// callReplicationRule invokes the method which assigns or updates the
+// replication rule of the project.
+func (p *project) callReplicationRule(ctx context.Context, method string, remote globalregistry.Registry, trigger globalregistry.ReplicationTrigger, direction string) (globalregistry.ReplicationRule, error) {
+	if !p.registry.canWrite(globalregistry.FeatureProjectReplicationRules) {
+		return nil, p.registry.notSupported(method)
+	}
+	remoteConfig, err := newRegistryConfig(remote)
+	if err != nil {
+		return nil, err
+	}
+	params := &AssignReplicationRuleParams{
+		Project:   p.name,
+		Remote:    remoteConfig,
+		Direction: direction,
+	}
+	if trigger != nil {
+		params.TriggerType = trigger.TriggerType()
+		params.TriggerSchedule = trigger.TriggerSchedule()
+	}
+	wireRule := ReplicationRule{}
+	if err = p.registry.call(ctx, method, params, &wireRule); err != nil {
+		return nil, err
+	}
+	return &replicationRule{
+		ReplicationRule: wireRule,
+		project:         p,
+	}, nil
+}
+
+func (p *project) GetUsedStorage(ctx context.Context) (int, error) {
+	if !p.registry.canRead(globalregistry.FeatureProjectStorageReport) {
+		return 0, nil
+	}
+	var storage int
+	err := p.registry.call(ctx, MethodGetUsedStorage, p.params(), &storage)
+	return storage, err
+}
+
+// projectMember is the globalregistry.ProjectMember implementation of the
+// plugin based providers.
+type projectMember struct {
+	Member
+}
+
+var _ globalregistry.ProjectMember = &projectMember{}
+
+func (m *projectMember) GetName() string { return m.Name }
+func (m *projectMember) GetType() string { return m.Type }
+func (m *projectMember) GetRole() string { return m.Role }
+
+// ldapMember is a projectMember with a distinguished name.
+type ldapMember struct {
+	projectMember
+}
+
+var _ globalregistry.LdapMember = &ldapMember{}
+
+func (m *ldapMember) GetDN() string { return m.DN }
+
+// newProjectMember returns the globalregistry.ProjectMember corresponding to the
+// wire representation. If the DN field is set, a globalregistry.LdapMember is
+// returned.
+func newProjectMember(m Member) globalregistry.ProjectMember {
+	if m.DN != "" {
+		return &ldapMember{projectMember{m}}
+	}
+	return &projectMember{m}
+}
+
+// toMember returns the wire representation of the project member.
+func toMember(member globalregistry.ProjectMember) Member {
+	m := Member{
+		Name: member.GetName(),
+		Type: member.GetType(),
+		Role: member.GetRole(),
+	}
+	if ldapMember, ok := member.(globalregistry.LdapMember); ok {
+		m.DN = ldapMember.GetDN()
+	}
+	return m
+}
+
+var _ globalregistry.Scanner = &Scanner{}
+
+func (s *Scanner) GetName() string { return s.Name }
+func (s *Scanner) GetURL() string  { return s.URL }
+
+// replicationRule is the globalregistry.ReplicationRule implementation of the
+// plugin based providers.
+type replicationRule struct {
+	ReplicationRule
+	project *project
+}
+
+var _ globalregistry.ReplicationRule = &replicationRule{}
+var _ globalregistry.DestructibleReplicationRule = &replicationRule{}
+
+func (rr *replicationRule) GetProjectName() string { return rr.project.name }
+func (rr *replicationRule) GetName() string        { return rr.Name }
+func (rr *replicationRule) Direction() string      { return rr.ReplicationRule.Direction }
+
+func (rr *replicationRule) Trigger() globalregistry.ReplicationTrigger {
+	return replicationTrigger{
+		triggerType:     rr.TriggerType,
+		triggerSchedule: rr.TriggerSchedule,
+	}
+}
+
+func (rr *replicationRule) RemoteRegistry() globalregistry.Registry {
+	return &RegistryConfig{
+		Name:        rr.ReplicationRule.RemoteRegistry.Name,
+		Provider:    rr.ReplicationRule.RemoteRegistry.Provider,
+		APIEndpoint: rr.ReplicationRule.RemoteRegistry.APIEndpoint,
+	}
+}
+
+func (rr *replicationRule) Delete(ctx context.Context) error {
+	if !rr.project.registry.canWrite(globalregistry.FeatureProjectReplicationRules) {
+		return rr.project.registry.notSupported(MethodDeleteReplicationRule)
+	}
+	return rr.project.registry.call(ctx, MethodDeleteReplicationRule, &DeleteReplicationRuleParams{
+		Project: rr.project.name,
+		Rule:    rr.ReplicationRule,
+	}, nil)
+}
+
+type replicationTrigger struct {
+	triggerType     api.ReplicationTriggerType
+	triggerSchedule string
+}
+
+var _ globalregistry.ReplicationTrigger = replicationTrigger{}
+
+func (rt replicationTrigger) TriggerType() api.ReplicationTriggerType { return rt.triggerType }
+func (rt replicationTrigger) TriggerSchedule() string                 { return rt.triggerSchedule }
+
+// String implements fmt.Stringer for logging purposes.
+func (rt replicationTrigger) String() string {
+	return fmt.Sprintf("%s %s", rt.triggerType, rt.triggerSchedule)
+}
@@ -0,0 +1,356 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+)
+
+// server serves the requests of registryman in the plugin process.
+type server struct {
+	provider     string
+	capabilities globalregistry.ProviderCapabilities
+	creator      globalregistry.RegistryCreator
+	logger       logr.Logger
+}
+
+// Serve implements the plugin side of the protocol on the standard input and
+// output. The creator is called with the registry configuration of each
+// request. Serve returns when the standard input is closed; plugins shall
+// call it from their main function:
+//
+//	func main() {
+//		plugin.Serve("myprovider", capabilities, newRegistry)
+//	}
+func Serve(provider string, capabilities globalregistry.ProviderCapabilities, creator globalregistry.RegistryCreator) {
+	if err := ServeIO(os.Stdin, os.Stdout, provider, capabilities, creator); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// ServeIO is like Serve, but it reads the requests from r and writes the
+// responses to w. The log messages are written to the standard error.
+func ServeIO(r io.Reader, w io.Writer, provider string, capabilities globalregistry.ProviderCapabilities, creator globalregistry.RegistryCreator) error {
+	s := &server{
+		provider:     provider,
+		capabilities: capabilities,
+		creator:      creator,
+		logger: funcr.New(func(prefix, args string) {
+			fmt.Fprintln(os.Stderr, prefix, args)
+		}, funcr.Options{}).WithName(provider),
+	}
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	encoder := json.NewEncoder(w)
+	for {
+		req := &Request{}
+		if err := decoder.Decode(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("cannot decode request: %w", err)
+		}
+		resp := &Response{
+			ID: req.ID,
+		}
+		result, err := s.handle(context.Background(), req)
+		if err != nil {
+			resp.Error = newError(err)
+		} else if result != nil {
+			resp.Result, err = json.Marshal(result)
+			if err != nil {
+				resp.Error = newError(err)
+			}
+		}
+		if err = encoder.Encode(resp); err != nil {
+			return fmt.Errorf("cannot encode response: %w", err)
+		}
+	}
+}
+
+// handle serves a single request and returns its result.
+func (s *server) handle(ctx context.Context, req *Request) (interface{}, error) {
+	if req.Method == MethodHandshake {
+		return &HandshakeResult{
+			ProtocolVersion: ProtocolVersion,
+			Provider:        s.provider,
+			Capabilities:    s.capabilities,
+		}, nil
+	}
+	if req.Registry == nil {
+		return nil, fmt.Errorf("registry configuration is missing: %w", globalregistry.ErrValidation)
+	}
+	reg, err := s.creator(s.logger, req.Registry)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Method {
+	case MethodListProjects:
+		regWithProjects, ok := reg.(globalregistry.RegistryWithProjects)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		projects, err := regWithProjects.ListProjects(ctx)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(projects))
+		for i, project := range projects {
+			names[i] = project.GetName()
+		}
+		return names, nil
+	case MethodCreateProject:
+		params := &ProjectParams{}
+		if err = decodeParams(req, params); err != nil {
+			return nil, err
+		}
+		projectCreator, ok := reg.(globalregistry.ProjectCreator)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		_, err = projectCreator.CreateProject(ctx, params.Project)
+		return nil, err
+	}
+
+	params := &ProjectParams{}
+	if err = decodeParams(req, params); err != nil {
+		return nil, err
+	}
+	project, err := projectOf(ctx, reg, params.Project)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Method {
+	case MethodDeleteProject:
+		destructibleProject, ok := project.(globalregistry.DestructibleProject)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		return nil, destructibleProject.Delete(ctx)
+	case MethodGetRepositories:
+		projectWithRepositories, ok := project.(globalregistry.ProjectWithRepositories)
+		if !ok {
+			return []string{}, nil
+		}
+		return projectWithRepositories.GetRepositories(ctx)
+	case MethodGetMembers:
+		projectWithMembers, ok := project.(globalregistry.ProjectWithMembers)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		members, err := projectWithMembers.GetMembers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		wireMembers := make([]Member, len(members))
+		for i, member := range members {
+			wireMembers[i] = toMember(member)
+		}
+		return wireMembers, nil
+	case MethodAssignMember, MethodUnassignMember:
+		memberParams := &MemberParams{}
+		if err = decodeParams(req, memberParams); err != nil {
+			return nil, err
+		}
+		memberManipulator, ok := project.(globalregistry.MemberManipulatorProject)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		member := newProjectMember(memberParams.Member)
+		if req.Method == MethodUnassignMember {
+			return nil, memberManipulator.UnassignMember(ctx, member)
+		}
+		return memberManipulator.AssignMember(ctx, member)
+	case MethodGetScanner:
+		projectWithScanner, ok := project.(globalregistry.ProjectWithScanner)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		scanner, err := projectWithScanner.GetScanner(ctx)
+		if err != nil || scanner == nil {
+			return nil, err
+		}
+		return &Scanner{
+			Name: scanner.GetName(),
+			URL:  scanner.GetURL(),
+		}, nil
+	case MethodAssignScanner, MethodUnassignScanner:
+		scannerParams := &ScannerParams{}
+		if err = decodeParams(req, scannerParams); err != nil {
+			return nil, err
+		}
+		scannerManipulator, ok := project.(globalregistry.ScannerManipulatorProject)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		if req.Method == MethodUnassignScanner {
+			return nil, scannerManipulator.UnassignScanner(ctx, &scannerParams.Scanner)
+		}
+		return nil, scannerManipulator.AssignScanner(ctx, &scannerParams.Scanner)
+	case MethodGetReplicationRules:
+		rules, err := replicationRulesOf(ctx, project, req.Method)
+		if err != nil {
+			return nil, err
+		}
+		wireRules := make([]ReplicationRule, len(rules))
+		for i, rule := range rules {
+			wireRules[i] = toReplicationRule(rule)
+		}
+		return wireRules, nil
+	case MethodAssignReplicationRule, MethodUpdateReplicationRule:
+		assignParams := &AssignReplicationRuleParams{}
+		if err = decodeParams(req, assignParams); err != nil {
+			return nil, err
+		}
+		replicationManipulator, ok := project.(globalregistry.ReplicationRuleManipulatorProject)
+		if !ok {
+			return nil, notImplemented(req.Method)
+		}
+		if assignParams.Remote == nil {
+			return nil, fmt.Errorf("remote registry is missing: %w", globalregistry.ErrValidation)
+		}
+		trigger := replicationTrigger{
+			triggerType:     assignParams.TriggerType,
+			triggerSchedule: assignParams.TriggerSchedule,
+		}
+		var rule globalregistry.ReplicationRule
+		if req.Method == MethodUpdateReplicationRule {
+			rule, err = replicationManipulator.UpdateReplicationRule(ctx, assignParams.Remote,
+				trigger, assignParams.Direction)
+		} else {
+			rule, err = replicationManipulator.AssignReplicationRule(ctx, assignParams.Remote,
+				trigger, assignParams.Direction)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return toReplicationRule(rule), nil
+	case MethodDeleteReplicationRule:
+		deleteParams := &DeleteReplicationRuleParams{}
+		if err = decodeParams(req, deleteParams); err != nil {
+			return nil, err
+		}
+		rules, err := replicationRulesOf(ctx, project, req.Method)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			if !matchesReplicationRule(rule, deleteParams.Rule) {
+				continue
+			}
+			destructibleRule, ok := rule.(globalregistry.DestructibleReplicationRule)
+			if !ok {
+				return nil, notImplemented(req.Method)
+			}
+			return nil, destructibleRule.Delete(ctx)
+		}
+		return nil, fmt.Errorf("replication rule %s not found: %w", deleteParams.Rule.Name, globalregistry.ErrNotFound)
+	case MethodGetUsedStorage:
+		projectWithStorage, ok := project.(globalregistry.ProjectWithStorage)
+		if !ok {
+			return 0, nil
+		}
+		return projectWithStorage.GetUsedStorage(ctx)
+	default:
+		return nil, notImplemented(req.Method)
+	}
+}
+
+func notImplemented(method string) error {
+	return fmt.Errorf("method %s: %w", method, globalregistry.ErrNotImplemented)
+}
+
+func decodeParams(req *Request, params interface{}) error {
+	if len(req.Params) == 0 {
+		return fmt.Errorf("parameters of method %s are missing: %w", req.Method, globalregistry.ErrValidation)
+	}
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return globalregistry.WithClass(
+			fmt.Errorf("invalid parameters of method %s: %w", req.Method, err),
+			globalregistry.ErrValidation)
+	}
+	return nil
+}
+
+// projectOf returns the project of the registry with the given name. If the
+// project does not exist, an error wrapping globalregistry.ErrNotFound is
+// returned.
+func projectOf(ctx context.Context, reg globalregistry.Registry, name string) (globalregistry.Project, error) {
+	regWithProjects, ok := reg.(globalregistry.RegistryWithProjects)
+	if !ok {
+		return nil, notImplemented(MethodListProjects)
+	}
+	project, err := regWithProjects.GetProjectByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, fmt.Errorf("project %s not found: %w", name, globalregistry.ErrNotFound)
+	}
+	return project, nil
+}
+
+func replicationRulesOf(ctx context.Context, project globalregistry.Project, method string) ([]globalregistry.ReplicationRule, error) {
+	projectWithReplication, ok := project.(globalregistry.ProjectWithReplication)
+	if !ok {
+		return nil, notImplemented(method)
+	}
+	return projectWithReplication.GetReplicationRules(ctx, nil, "")
+}
+
+// toReplicationRule returns the wire representation of the replication rule.
+func toReplicationRule(rule globalregistry.ReplicationRule) ReplicationRule {
+	wireRule := ReplicationRule{
+		Name:      rule.GetName(),
+		Project:   rule.GetProjectName(),
+		Direction: rule.Direction(),
+	}
+	if trigger := rule.Trigger(); trigger != nil {
+		wireRule.TriggerType = trigger.TriggerType()
+		wireRule.TriggerSchedule = trigger.TriggerSchedule()
+	}
+	if remote := rule.RemoteRegistry(); remote != nil {
+		wireRule.RemoteRegistry = RemoteRegistry{
+			Name:        remote.GetName(),
+			Provider:    remote.GetProvider(),
+			APIEndpoint: remote.GetAPIEndpoint(),
+		}
+	}
+	return wireRule
+}
+
+// matchesReplicationRule checks whether the replication rule corresponds to
+// the wire representation. The rules are identified by their names; if the
+// name is not set, by the remote registry and the direction.
+func matchesReplicationRule(rule globalregistry.ReplicationRule, wireRule ReplicationRule) bool {
+	if wireRule.Name != "" {
+		return rule.GetName() == wireRule.Name
+	}
+	actual := toReplicationRule(rule)
+	return actual.RemoteRegistry == wireRule.RemoteRegistry &&
+		actual.Direction == wireRule.Direction
+}
@@ -18,16 +18,20 @@ package skopeo
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/containers/image/v5/directory"
 	"github.com/containers/image/v5/docker"
 	"github.com/containers/image/v5/types"
 	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
 )
 
 type transfer struct {
 	dockerCtx *types.SystemContext
 	dirCtx    *types.SystemContext
+	certDir   string
 }
 type transferData struct {
 	sourcePath           string
@@ -39,17 +43,64 @@ type transferData struct {
 	scoped               bool
 }
 
-// New creates a new transfer struct.
-func New(username, password string) *transfer {
-	return &transfer{
+// New creates a new transfer struct. The credentials and the TLS settings of
+// the Docker transport are taken from the registry. If the registry has a
+// custom CA bundle or client certificate, they are written into a temporary
+// certificate directory which is removed by Close.
+func New(reg globalregistry.Registry) (*transfer, error) {
+	t := &transfer{
 		dockerCtx: &types.SystemContext{
 			DockerAuthConfig: &types.DockerAuthConfig{
-				Username: username,
-				Password: password,
+				Username: reg.GetUsername(),
+				Password: reg.GetPassword(),
 			},
+			DockerInsecureSkipTLSVerify: types.NewOptionalBool(reg.GetInsecureSkipTLSVerify()),
 		},
 		dirCtx: &types.SystemContext{},
 	}
+	regWithTLS, ok := reg.(globalregistry.RegistryWithTLS)
+	if !ok {
+		return t, nil
+	}
+	caBundle, err := regWithTLS.GetCABundle()
+	if err != nil {
+		return nil, err
+	}
+	certPEM, keyPEM, err := regWithTLS.GetClientCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if caBundle == nil && certPEM == nil {
+		return t, nil
+	}
+	t.certDir, err = os.MkdirTemp("", "registryman-certs-")
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		"ca.crt":      caBundle,
+		"client.cert": certPEM,
+		"client.key":  keyPEM,
+	}
+	for name, content := range files {
+		if content == nil {
+			continue
+		}
+		if err = os.WriteFile(filepath.Join(t.certDir, name), content, 0600); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	t.dockerCtx.DockerCertPath = t.certDir
+	return t, nil
+}
+
+// Close removes the temporary files created by New.
+func (t *transfer) Close() error {
+	if t.certDir == "" {
+		return nil
+	}
+	return os.RemoveAll(t.certDir)
 }
 
 // Export exports Docker repositories from a source repository to a destination path.
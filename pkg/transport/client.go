@@ -0,0 +1,455 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// transport package implements the HTTP client layer shared by the registry
+// providers. It provides retries with backoff, Retry-After handling, request
+// timeouts, per-registry rate limiting, mapping of the HTTP status codes to the
+// globalregistry error values and request logging with the secrets redacted.
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// RequestTimeoutAnnotation overrides the timeout of a single HTTP
+	// request attempt, e.g. "45s".
+	RequestTimeoutAnnotation = "registryman.kubermatic.com/requestTimeout"
+
+	// MaxRetriesAnnotation overrides the number of retries of a failed
+	// HTTP request.
+	MaxRetriesAnnotation = "registryman.kubermatic.com/maxRetries"
+
+	// RateLimitAnnotation overrides the number of requests per second sent
+	// to the registry. The value 0 disables rate limiting.
+	RateLimitAnnotation = "registryman.kubermatic.com/rateLimit"
+
+	// RateLimitBurstAnnotation overrides the number of requests that can be
+	// sent to the registry in a single burst.
+	RateLimitBurstAnnotation = "registryman.kubermatic.com/rateLimitBurst"
+)
+
+// Options contains the settings of a Client.
+type Options struct {
+	// Timeout is the timeout of a single request attempt, including the
+	// reading of the response body.
+	Timeout time.Duration
+
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry. The delay is
+	// doubled for each subsequent retry.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the upper limit of the delay between retries. It also
+	// caps the delay requested by the Retry-After response header.
+	MaxBackoff time.Duration
+
+	// RateLimit is the number of requests per second sent to the
+	// registry. The value 0 disables rate limiting.
+	RateLimit float64
+
+	// RateLimitBurst is the number of requests that can be sent in a
+	// single burst.
+	RateLimitBurst int
+
+	// CheckRedirect is passed to the underlying http.Client.
+	CheckRedirect func(req *http.Request, via []*http.Request) error
+}
+
+// DefaultOptions contains the settings used by New when the registry does not
+// override them by annotations.
+var DefaultOptions = Options{
+	Timeout:        30 * time.Second,
+	MaxRetries:     3,
+	MinBackoff:     500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	RateLimit:      10,
+	RateLimitBurst: 20,
+}
+
+// Client is the HTTP client used by the providers to access the registry API.
+type Client struct {
+	provider string
+	logger   logr.Logger
+	client   *http.Client
+	limiter  *rate.Limiter
+	options  Options
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*rate.Limiter{}
+)
+
+// limiterOf returns the rate limiter of the registry. The limiters are shared
+// between the Client instances of the same registry, so the limit is honored
+// even if the provider is re-created by every reconciliation.
+func limiterOf(config globalregistry.Registry, limit rate.Limit, burst int) *rate.Limiter {
+	key := config.GetName() + "|" + config.GetAPIEndpoint()
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	limiter, found := limiters[key]
+	if !found {
+		limiter = rate.NewLimiter(limit, burst)
+		limiters[key] = limiter
+		return limiter
+	}
+	if limiter.Limit() != limit {
+		limiter.SetLimit(limit)
+	}
+	if limiter.Burst() != burst {
+		limiter.SetBurst(burst)
+	}
+	return limiter
+}
+
+// OptionsOf returns the default options overridden by the annotations of the
+// registry.
+func OptionsOf(config globalregistry.Registry) (Options, error) {
+	options := DefaultOptions
+	annotations := config.GetAnnotations()
+	if value, ok := annotations[RequestTimeoutAnnotation]; ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return options, fmt.Errorf("invalid %s annotation: %q", RequestTimeoutAnnotation, value)
+		}
+		options.Timeout = timeout
+	}
+	if value, ok := annotations[MaxRetriesAnnotation]; ok {
+		maxRetries, err := strconv.Atoi(value)
+		if err != nil || maxRetries < 0 {
+			return options, fmt.Errorf("invalid %s annotation: %q", MaxRetriesAnnotation, value)
+		}
+		options.MaxRetries = maxRetries
+	}
+	if value, ok := annotations[RateLimitAnnotation]; ok {
+		rateLimit, err := strconv.ParseFloat(value, 64)
+		if err != nil || rateLimit < 0 {
+			return options, fmt.Errorf("invalid %s annotation: %q", RateLimitAnnotation, value)
+		}
+		options.RateLimit = rateLimit
+	}
+	if value, ok := annotations[RateLimitBurstAnnotation]; ok {
+		burst, err := strconv.Atoi(value)
+		if err != nil || burst < 1 {
+			return options, fmt.Errorf("invalid %s annotation: %q", RateLimitBurstAnnotation, value)
+		}
+		options.RateLimitBurst = burst
+	}
+	return options, nil
+}
+
+// New creates a Client for the registry. The TLS settings are taken from
+// globalregistry.TLSConfig, the other settings are taken from DefaultOptions
+// and the annotations of the registry.
+func New(logger logr.Logger, config globalregistry.Registry) (*Client, error) {
+	options, err := OptionsOf(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithOptions(logger, config, options)
+}
+
+// NewWithOptions creates a Client for the registry using the given options.
+func NewWithOptions(logger logr.Logger, config globalregistry.Registry, options Options) (*Client, error) {
+	tlsConfig, err := globalregistry.TLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	limit := rate.Inf
+	if options.RateLimit > 0 {
+		limit = rate.Limit(options.RateLimit)
+	}
+	burst := options.RateLimitBurst
+	if burst < 1 {
+		burst = 1
+	}
+	return &Client{
+		provider: config.GetProvider(),
+		logger:   logger,
+		client: &http.Client{
+			Transport:     transport,
+			CheckRedirect: options.CheckRedirect,
+		},
+		limiter: limiterOf(config, limit, burst),
+		options: options,
+	}, nil
+}
+
+// BytesBody is the response body returned by Client.Do. The body is read
+// completely before Do returns, so BytesBody provides the bytes.Buffer methods
+// (e.g. String()) too.
+type BytesBody struct {
+	*bytes.Buffer
+}
+
+// Close implements the io.Closer interface.
+func (bb *BytesBody) Close() error { return nil }
+
+// isIdempotent returns true if the request can be safely retried after a
+// server error.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry returns true if the response (or the error) of the given request
+// indicates a transient failure.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return isIdempotent(req)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// The request has not been processed, so it can be resent
+		// regardless of the method.
+		return true
+	case http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return isIdempotent(req)
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header of the response. It returns 0 if
+// the header is missing or invalid.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// backoff returns the delay before the given retry attempt.
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	delay := retryAfter(resp)
+	if delay == 0 {
+		delay = c.options.MinBackoff << (attempt - 1)
+		if delay > 0 {
+			// add up to 20% jitter to avoid synchronized retries
+			delay += time.Duration(rand.Int63n(int64(delay)/5 + 1))
+		}
+	}
+	if c.options.MaxBackoff > 0 && (delay > c.options.MaxBackoff || delay < 0) {
+		delay = c.options.MaxBackoff
+	}
+	return delay
+}
+
+// attempt sends the request once and reads the response body.
+func (c *Client) attempt(ctx context.Context, req *http.Request, body []byte) (*http.Response, error) {
+	if c.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.Timeout)
+		defer cancel()
+	}
+	req = req.Clone(ctx)
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	buf := &BytesBody{
+		Buffer: new(bytes.Buffer),
+	}
+	if _, err = buf.ReadFrom(resp.Body); err != nil {
+		return nil, fmt.Errorf("cannot read HTTP response body: %w", err)
+	}
+	resp.Body = buf
+	return resp, nil
+}
+
+// Do sends the HTTP request to the registry. The request is delayed by the
+// rate limiter of the registry and it is retried on connection errors and on
+// the 429 and 5xx status codes. Non-idempotent requests are retried only on
+// 429, since the other failures might have happened after the registry
+// processed the request.
+//
+// The response body is read completely and replaced with a BytesBody. If the
+// response status code is not 2xx, both the response and a
+// *globalregistry.ProviderError are returned, so the caller can inspect the
+// response.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read HTTP request body: %w", err)
+		}
+	}
+	logger := c.logger.WithValues(
+		"method", req.Method,
+		"req-url", redactURL(req.URL),
+	)
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			delay := c.backoff(attempt, resp)
+			logger.V(1).Info("retrying HTTP request",
+				"attempt", attempt,
+				"delay", delay.String(),
+			)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if err = c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err = c.attempt(ctx, req, body)
+		c.observe(req, resp, time.Since(start))
+		if err != nil {
+			logger.V(1).Info("HTTP request failed",
+				"error", err.Error(),
+				"duration", time.Since(start).String(),
+			)
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		} else {
+			logger.V(1).Info("HTTP request completed",
+				"status-code", resp.StatusCode,
+				"duration", time.Since(start).String(),
+			)
+		}
+		if attempt >= c.options.MaxRetries || !shouldRetry(req, resp, err) {
+			break
+		}
+	}
+	if err != nil {
+		logger.Error(err, "http.Client cannot Do")
+		return nil, globalregistry.WrapError(c.provider, actionOf(req), 0,
+			globalregistry.WithClass(err, globalregistry.ErrTransient))
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	buf := resp.Body.(*BytesBody)
+	logger.V(-1).Info("HTTP response status code is not OK",
+		"status-code", resp.StatusCode,
+		"resp-body-size", buf.Len(),
+	)
+	logger.V(1).Info(redactBody(buf.String()))
+	return resp, globalregistry.WrapError(c.provider, actionOf(req), resp.StatusCode,
+		errorOfResponse(resp))
+}
+
+// observe records the duration and the status code of a request attempt in the
+// metrics.
+func (c *Client) observe(req *http.Request, resp *http.Response, duration time.Duration) {
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.ProviderRequestDuration.WithLabelValues(
+		c.provider,
+		req.Method,
+		metrics.EndpointOf(req.URL.Path),
+		code,
+	).Observe(duration.Seconds())
+}
+
+// actionOf returns the description of the request used as the action of the
+// returned *globalregistry.ProviderError values.
+func actionOf(req *http.Request) string {
+	return req.Method + " " + req.URL.Path
+}
+
+// maxErrorBodyLength is the maximal number of characters of the response body
+// included in the error messages.
+const maxErrorBodyLength = 256
+
+// errorOfResponse returns the error describing a non-2xx response. The
+// returned error is classified as the globalregistry error value corresponding
+// to the status code.
+func errorOfResponse(resp *http.Response) error {
+	msg := strings.TrimSpace(redactBody(resp.Body.(*BytesBody).String()))
+	if len(msg) > maxErrorBodyLength {
+		msg = msg[:maxErrorBodyLength] + "..."
+	}
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	err := errors.New(msg)
+	switch {
+	case resp.StatusCode == http.StatusBadRequest,
+		resp.StatusCode == http.StatusUnprocessableEntity:
+		return globalregistry.WithClass(err, globalregistry.ErrValidation)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return globalregistry.WithClass(err, globalregistry.ErrUnauthorized)
+	case resp.StatusCode == http.StatusForbidden:
+		return globalregistry.WithClass(err, globalregistry.ErrForbidden)
+	case resp.StatusCode == http.StatusNotFound:
+		return globalregistry.WithClass(err, globalregistry.ErrNotFound)
+	case resp.StatusCode == http.StatusConflict:
+		return globalregistry.WithClass(err, globalregistry.ErrConflict)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return globalregistry.WithClass(err, globalregistry.ErrRateLimited)
+	case resp.StatusCode >= 500:
+		return globalregistry.WithClass(err, globalregistry.ErrTransient)
+	}
+	return err
+}
@@ -0,0 +1,264 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubermatic-labs/registryman/pkg/globalregistry"
+	"github.com/kubermatic-labs/registryman/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type testRegistry struct {
+	name        string
+	endpoint    string
+	annotations map[string]string
+}
+
+var _ globalregistry.Registry = &testRegistry{}
+
+func (r *testRegistry) GetProvider() string                        { return "test" }
+func (r *testRegistry) GetUsername() string                        { return "admin" }
+func (r *testRegistry) GetPassword() string                        { return "secret" }
+func (r *testRegistry) GetAPIEndpoint() string                     { return r.endpoint }
+func (r *testRegistry) GetName() string                            { return r.name }
+func (r *testRegistry) GetOptions() globalregistry.RegistryOptions { return nil }
+func (r *testRegistry) GetAnnotations() map[string]string          { return r.annotations }
+func (r *testRegistry) GetInsecureSkipTLSVerify() bool             { return false }
+
+var testOptions = Options{
+	Timeout:        time.Second,
+	MaxRetries:     2,
+	MinBackoff:     time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+	RateLimitBurst: 1,
+}
+
+func newTestClient(t *testing.T, name string, handler http.HandlerFunc) (*Client, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewWithOptions(logr.Discard(), &testRegistry{
+		name:     name,
+		endpoint: server.URL,
+	}, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestClient_RetryOnServerError(t *testing.T) {
+	var calls int32
+	client, server := newTestClient(t, "retry", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if body := resp.Body.(*BytesBody).String(); body != "ok" {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestClient_NoRetryOfNonIdempotentRequest(t *testing.T) {
+	var calls int32
+	client, server := newTestClient(t, "post", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+	resp, err := client.Do(context.Background(), req)
+	if err == nil {
+		t.Fatal("error is expected")
+	}
+	if globalregistry.StatusCodeOf(err) != http.StatusInternalServerError || resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected status code: %d", globalregistry.StatusCodeOf(err))
+	}
+	if calls != 1 {
+		t.Errorf("POST request is retried: %d calls", calls)
+	}
+}
+
+func TestClient_RetryAfterReplaysBody(t *testing.T) {
+	var calls int32
+	var bodies []string
+	client, server := newTestClient(t, "ratelimited", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewBufferString(`{"name":"p"}`))
+	if _, err := client.Do(context.Background(), req); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] != `{"name":"p"}` {
+		t.Errorf("request body is not replayed: %q", bodies)
+	}
+}
+
+func TestClient_StatusMapping(t *testing.T) {
+	for statusCode, expected := range map[int]error{
+		http.StatusUnauthorized:    globalregistry.ErrUnauthorized,
+		http.StatusForbidden:       globalregistry.ErrForbidden,
+		http.StatusNotFound:        globalregistry.ErrNotFound,
+		http.StatusConflict:        globalregistry.ErrConflict,
+		http.StatusTooManyRequests: globalregistry.ErrRateLimited,
+		http.StatusBadRequest:      globalregistry.ErrValidation,
+		http.StatusBadGateway:      globalregistry.ErrTransient,
+	} {
+		statusCode := statusCode
+		client, server := newTestClient(t, "mapping", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(statusCode)
+		})
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		_, err := client.Do(context.Background(), req)
+		if !errors.Is(err, expected) {
+			t.Errorf("status code %d: expected %v, got %v", statusCode, expected, err)
+		}
+		var providerErr *globalregistry.ProviderError
+		if !errors.As(err, &providerErr) || providerErr.Provider != "test" || providerErr.StatusCode != statusCode {
+			t.Errorf("status code %d: provider error is not returned: %v", statusCode, err)
+		}
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	client, server := newTestClient(t, "timeout", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	client.options.Timeout = 20 * time.Millisecond
+	client.options.MaxRetries = 0
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err := client.Do(context.Background(), req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if !errors.Is(err, globalregistry.ErrTransient) {
+		t.Errorf("timeout is not a transient error: %v", err)
+	}
+}
+
+func TestLimiterIsSharedPerRegistry(t *testing.T) {
+	reg := &testRegistry{name: "shared", endpoint: "https://registry.example.com"}
+	options := testOptions
+	options.RateLimit = 1
+	c1, err := NewWithOptions(logr.Discard(), reg, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := NewWithOptions(logr.Discard(), reg, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1.limiter != c2.limiter {
+		t.Error("rate limiter is not shared between the clients of the same registry")
+	}
+}
+
+func TestOptionsOf(t *testing.T) {
+	options, err := OptionsOf(&testRegistry{annotations: map[string]string{
+		RequestTimeoutAnnotation: "45s",
+		MaxRetriesAnnotation:     "5",
+		RateLimitAnnotation:      "0",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Timeout != 45*time.Second || options.MaxRetries != 5 || options.RateLimit != 0 {
+		t.Errorf("annotations are not applied: %+v", options)
+	}
+	if _, err = OptionsOf(&testRegistry{annotations: map[string]string{
+		MaxRetriesAnnotation: "many",
+	}}); err == nil {
+		t.Error("invalid annotation is accepted")
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	u, _ := url.Parse("https://admin:pw@registry.example.com/api?q=x&access_token=abc")
+	redactedURL := redactURL(u)
+	if strings.Contains(redactedURL, "pw") || strings.Contains(redactedURL, "abc") {
+		t.Errorf("URL is not redacted: %s", redactedURL)
+	}
+	body := redactBody(`{"name":"robot$p","secret":"s3cr3t","Password": "pw\"x"}`)
+	if strings.Contains(body, "s3cr3t") || strings.Contains(body, "pw") {
+		t.Errorf("body is not redacted: %s", body)
+	}
+	if !strings.Contains(body, "robot$p") {
+		t.Errorf("non-sensitive field is redacted: %s", body)
+	}
+}
+
+func TestClient_Metrics(t *testing.T) {
+	var calls int32
+	client, server := newTestClient(t, "metrics", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v2.0/projects/42/members", nil)
+	if _, err := client.Do(context.Background(), req); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	for code, expected := range map[string]uint64{"502": 1, "200": 1} {
+		observer, err := metrics.ProviderRequestDuration.GetMetricWithLabelValues(
+			"test", http.MethodGet, "/api/v2.0/projects/{id}/members", code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &dto.Metric{}
+		if err = observer.(prometheus.Metric).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		if count := m.GetHistogram().GetSampleCount(); count != expected {
+			t.Errorf("%d requests with status code %s are observed, expected %d", count, code, expected)
+		}
+	}
+}
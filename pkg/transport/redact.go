@@ -0,0 +1,82 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package transport
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveNames contains the (lower case) name fragments of the query
+// parameters and JSON fields whose values must not be logged.
+var sensitiveNames = []string{
+	"password",
+	"secret",
+	"token",
+	"apikey",
+	"api_key",
+	"credential",
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL returns the URL as a string with the user info and the sensitive
+// query parameters redacted.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redactedURL := *u
+	if redactedURL.User != nil {
+		redactedURL.User = url.User(redacted)
+	}
+	if redactedURL.RawQuery != "" {
+		query := redactedURL.Query()
+		for name := range query {
+			if isSensitive(name) {
+				query[name] = []string{redacted}
+			}
+		}
+		redactedURL.RawQuery = query.Encode()
+	}
+	return redactedURL.String()
+}
+
+var jsonStringField = regexp.MustCompile(`"([^"]+)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+
+// redactBody returns the (JSON) body with the values of the sensitive string
+// fields redacted.
+func redactBody(body string) string {
+	return jsonStringField.ReplaceAllStringFunc(body, func(field string) string {
+		name := jsonStringField.FindStringSubmatch(field)[1]
+		if !isSensitive(name) {
+			return field
+		}
+		return `"` + name + `":"` + redacted + `"`
+	})
+}
@@ -0,0 +1,99 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CertificateLoader loads the TLS certificate of the webhook server from
+// files. The files are reloaded periodically, so that a rotated certificate
+// is picked up without restart. If a reload fails, the previously loaded
+// certificate remains in use.
+type CertificateLoader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	err  error
+}
+
+// NewCertificateLoader creates a CertificateLoader for the certificate and
+// key files.
+func NewCertificateLoader(certFile, keyFile string) *CertificateLoader {
+	return &CertificateLoader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		err:      errors.New("TLS certificate is not loaded yet"),
+	}
+}
+
+// Load loads the certificate and the key files.
+func (cl *CertificateLoader) Load() error {
+	cert, err := tls.LoadX509KeyPair(cl.certFile, cl.keyFile)
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if err != nil {
+		if cl.cert == nil {
+			cl.err = err
+		}
+		return err
+	}
+	cl.cert = &cert
+	cl.err = nil
+	return nil
+}
+
+// Run reloads the certificate in every interval until stop is closed.
+func (cl *CertificateLoader) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := cl.Load(); err != nil {
+				logger.Error(err, "cannot load TLS certificate",
+					"cert", cl.certFile,
+					"key", cl.keyFile,
+				)
+			}
+		}
+	}
+}
+
+// Ready returns an error until the certificate is loaded.
+func (cl *CertificateLoader) Ready() error {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.err
+}
+
+// GetCertificate can be used as the GetCertificate function of a tls.Config.
+func (cl *CertificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	if cl.cert == nil {
+		return nil, cl.err
+	}
+	return cl.cert, nil
+}
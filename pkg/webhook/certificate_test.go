@@ -0,0 +1,121 @@
+/*
+   Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate and its key to the
+// directory.
+func writeCertificate(t *testing.T, dir, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "tls.crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "tls.key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonNameOf(t *testing.T, cl *CertificateLoader) string {
+	t.Helper()
+	cert, err := cl.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestCertificateLoader(t *testing.T) {
+	dir := t.TempDir()
+	cl := NewCertificateLoader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+	if cl.Load() == nil || cl.Ready() == nil {
+		t.Fatal("missing certificate is loaded")
+	}
+	if _, err := cl.GetCertificate(nil); err == nil {
+		t.Error("certificate is returned before it is loaded")
+	}
+
+	writeCertificate(t, dir, "first")
+	if err := cl.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.Ready(); err != nil {
+		t.Errorf("loader is not ready: %v", err)
+	}
+	if cn := commonNameOf(t, cl); cn != "first" {
+		t.Errorf("unexpected certificate: %s", cn)
+	}
+
+	// a rotated certificate is picked up
+	writeCertificate(t, dir, "second")
+	if err := cl.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cn := commonNameOf(t, cl); cn != "second" {
+		t.Errorf("rotated certificate is not loaded: %s", cn)
+	}
+
+	// a broken certificate file does not replace the loaded certificate
+	if err := os.WriteFile(filepath.Join(dir, "tls.crt"), []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if cl.Load() == nil {
+		t.Error("broken certificate is loaded")
+	}
+	if err := cl.Ready(); err != nil {
+		t.Errorf("loader is not ready after a failed reload: %v", err)
+	}
+	if cn := commonNameOf(t, cl); cn != "second" {
+		t.Errorf("unexpected certificate after a failed reload: %s", cn)
+	}
+}
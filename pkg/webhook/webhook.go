@@ -21,9 +21,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	api "github.com/kubermatic-labs/registryman/pkg/apis/registryman/v1alpha1"
 	"github.com/kubermatic-labs/registryman/pkg/config"
+	"github.com/kubermatic-labs/registryman/pkg/metrics"
 	admissionV1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -258,6 +260,10 @@ func validateConsistency(w http.ResponseWriter, aos config.ApiObjectStore, admis
 	encoder := json.NewEncoder(w)
 
 	err := config.ValidateConsistency(aos)
+	metrics.AdmissionReviewsTotal.WithLabelValues(
+		string(admissionRev.Request.Operation),
+		strconv.FormatBool(err == nil),
+	).Inc()
 	if err != nil {
 		logger.Info("rejecting validation request",
 			"reason", err.Error(),